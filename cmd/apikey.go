@@ -0,0 +1,97 @@
+package main
+
+import (
+	"banner-rotation/internal/auth"
+	"banner-rotation/internal/storage/postgres"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// runAPIKey выполняет подкоманду apikey:
+//
+//	banner-rotation apikey create -name NAME -scopes serve[,admin]
+//	banner-rotation apikey list
+//	banner-rotation apikey revoke ID
+func runAPIKey(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: apikey create -name NAME -scopes serve,admin | list | revoke ID")
+	}
+
+	store, err := postgres.New(os.Getenv("DB_URL"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = store.Close()
+	}()
+	keys := postgres.NewKeyStore(store)
+
+	ctx := context.Background()
+	switch args[0] {
+	case "create":
+		flags := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+		name := flags.String("name", "", "key owner, for example a service name")
+		scopeList := flags.String("scopes", string(auth.ScopeServe), "comma-separated scopes: serve, admin")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *name == "" {
+			return errors.New("-name is required")
+		}
+
+		var scopes []auth.Scope
+		for _, s := range strings.Split(*scopeList, ",") {
+			scope, err := auth.ParseScope(strings.TrimSpace(s))
+			if err != nil {
+				return err
+			}
+			scopes = append(scopes, scope)
+		}
+
+		plain, hash, err := auth.GenerateKey()
+		if err != nil {
+			return err
+		}
+		key, err := keys.CreateKey(ctx, *name, hash, scopes)
+		if err != nil {
+			return err
+		}
+		// Ключ нигде не хранится, поэтому выводится только сейчас
+		fmt.Printf("id:     %d\nname:   %s\nscopes: %s\nkey:    %s\n", key.ID, key.Name, *scopeList, plain)
+		return nil
+	case "list":
+		list, err := keys.ListKeys(ctx)
+		if err != nil {
+			return err
+		}
+		for _, key := range list {
+			scopes := make([]string, len(key.Scopes))
+			for i, scope := range key.Scopes {
+				scopes[i] = string(scope)
+			}
+			state := "active"
+			if key.RevokedAt != nil {
+				state = "revoked " + key.RevokedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%d\t%s\t%s\tcreated %s\t%s\n", key.ID, key.Name, strings.Join(scopes, ","),
+				key.CreatedAt.Format("2006-01-02 15:04:05"), state)
+		}
+		return nil
+	case "revoke":
+		if len(args) < 2 {
+			return errors.New("usage: apikey revoke ID")
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid key id: %q", args[1])
+		}
+		return keys.RevokeKey(ctx, id)
+	default:
+		return fmt.Errorf("unknown apikey command: %q", args[0])
+	}
+}
@@ -3,10 +3,22 @@ package main
 import (
 	"banner-rotation/internal/api"
 	"banner-rotation/internal/app"
+	"banner-rotation/internal/auth"
 	"banner-rotation/internal/config"
+	"banner-rotation/internal/grpc"
+	"banner-rotation/internal/idempotency"
 	"banner-rotation/internal/kafka"
+	"banner-rotation/internal/ratelimit"
+	"banner-rotation/internal/storage"
+	"banner-rotation/internal/storage/bolt"
+	"banner-rotation/internal/storage/buffered"
+	"banner-rotation/internal/storage/memory"
 	"banner-rotation/internal/storage/postgres"
+	"banner-rotation/internal/storage/redis"
+	"banner-rotation/internal/tracking"
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -19,6 +31,20 @@ import (
 )
 
 func main() {
+	// Подкоманды
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "apikey" {
+		if err := runAPIKey(os.Args[2:]); err != nil {
+			log.Fatalf("API key command failed: %v", err)
+		}
+		return
+	}
+
 	// Загрузка конфигурации
 	cfg, err := config.Load()
 	if err != nil {
@@ -36,8 +62,8 @@ func main() {
 		cancel()
 	}()
 
-	// Подключение к PostgreSQL
-	store, err := postgres.New(os.Getenv("DB_URL"))
+	// Подключение к хранилищу
+	store, err := newStorage(cfg.Storage)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -49,6 +75,7 @@ func main() {
 
 	// Создание продюсера Kafka
 	var producer kafka.ProducerInterface
+	var kafkaProducer *kafka.Producer
 	if cfg.Kafka.Brokers != "" {
 		kafkaWriter := &segmentio_kafka.Writer{
 			Addr:         segmentio_kafka.TCP(strings.Split(cfg.Kafka.Brokers, ",")...),
@@ -56,7 +83,8 @@ func main() {
 			Balancer:     &segmentio_kafka.LeastBytes{},
 			BatchTimeout: 10 * time.Millisecond,
 		}
-		producer = kafka.NewProducer(kafkaWriter)
+		kafkaProducer = kafka.NewProducer(kafkaWriter)
+		producer = kafkaProducer
 		defer func() {
 			if err := producer.Close(); err != nil {
 				log.Printf("Error closing producer: %v", err)
@@ -67,11 +95,84 @@ func main() {
 		producer = nil
 	}
 
+	// Свертка часовой статистики в дневную
+	if cfg.Storage.HourlyRetention > 0 {
+		app.StartStatsRollup(ctx, store, cfg.Storage.HourlyRetention, time.Hour)
+	}
+
 	// Инициализация сервиса
 	bandit := app.NewBandit(store, producer)
+	bandit.StartWarm(ctx, app.DefaultWarmRetryInterval)
 
 	// Создание и запуск API сервера
-	apiServer := api.NewServer(bandit)
+	apiServer := api.NewServer(bandit, store)
+	idempotencyStore, err := newIdempotencyStore(cfg.Idempotency.Store, store)
+	if err != nil {
+		log.Fatalf("Failed to create idempotency store: %v", err)
+	}
+	ttl := cfg.Idempotency.TTL
+	if ttl <= 0 {
+		ttl = idempotency.DefaultTTL
+	}
+	apiServer.SetIdempotencyStore(idempotencyStore, ttl)
+
+	limiter, err := newRateLimiter(cfg.RateLimit.Store, store)
+	if err != nil {
+		log.Fatalf("Failed to create rate limiter: %v", err)
+	}
+	apiServer.SetRateLimiter(limiter,
+		ratelimit.Limit{Rate: cfg.RateLimit.Serve.Rate, Burst: cfg.RateLimit.Serve.Burst},
+		ratelimit.Limit{Rate: cfg.RateLimit.Admin.Rate, Burst: cfg.RateLimit.Admin.Burst},
+	)
+
+	// Подписанные ссылки переходов
+	if cfg.Tracking.Secret != "" {
+		clickTTL := cfg.Tracking.TTL
+		if clickTTL <= 0 {
+			clickTTL = tracking.DefaultClickTTL
+		}
+		signer := tracking.NewSigner([]byte(cfg.Tracking.Secret))
+		apiServer.SetTracking(signer, cfg.Tracking.BaseURL, cfg.Tracking.FallbackURL, clickTTL)
+
+		// Показы засчитываются по пикселю; подтвержденные пиксели хранятся
+		// там же, где ключи идемпотентности
+		if cfg.Tracking.ReserveShows {
+			impressionTTL := cfg.Tracking.ImpressionTTL
+			if impressionTTL <= 0 {
+				impressionTTL = tracking.DefaultImpressionTTL
+			}
+			apiServer.SetShowReservations(idempotencyStore, impressionTTL)
+			bandit.SetReservationTTL(impressionTTL)
+			log.Printf("Reserving shows until confirmed by pixel, ttl %s", impressionTTL)
+		}
+	} else {
+		if cfg.Tracking.ReserveShows {
+			log.Fatalf("Reserving shows requires tracking secret, set TRACKING_SECRET")
+		}
+		log.Println("Tracking secret not configured, click links disabled")
+	}
+
+	// Проверки готовности зависимостей
+	if pinger, ok := store.(storage.Pinger); ok {
+		apiServer.AddReadinessCheck("storage", pinger.Ping)
+	}
+	if kafkaProducer != nil {
+		apiServer.AddReadinessCheck("kafka", kafkaProducer.Ping)
+	}
+	apiServer.AddReadinessCheck("bandit_cache", bandit.Warmed)
+
+	// Проверка API-ключей
+	var authenticator *auth.Authenticator
+	if cfg.Auth.Enabled {
+		pg, ok := postgresBackend(store)
+		if !ok {
+			log.Fatalf("API key authentication requires storage driver postgres, set AUTH_ENABLED=false to run without it")
+		}
+		authenticator = auth.NewAuthenticator(postgres.NewKeyStore(pg), auth.DefaultCacheTTL)
+		apiServer.SetAuthenticator(authenticator)
+	} else {
+		log.Println("API key authentication disabled")
+	}
 	go func() {
 		log.Println("Starting API server on :8080")
 		if err := apiServer.Start(":8080"); err != nil && err != http.ErrServerClosed {
@@ -79,15 +180,148 @@ func main() {
 		}
 	}()
 
+	// Создание и запуск gRPC сервера
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Addr != "" {
+		grpcServer = grpc.NewServer(bandit, authenticator)
+		grpcServer.SetShowReservations(cfg.Tracking.ReserveShows)
+		go func() {
+			log.Printf("Starting gRPC server on %s", cfg.GRPC.Addr)
+			if err := grpcServer.Start(cfg.GRPC.Addr); err != nil {
+				log.Fatalf("gRPC server failed: %v", err)
+			}
+		}()
+	}
+
 	// Ожидание сигнала завершения
 	<-ctx.Done()
 	log.Println("Shutting down..")
 
+	// Балансировщик успевает увидеть, что /readyz больше не проходит
+	apiServer.Drain()
+	if cfg.Health.DrainDelay > 0 {
+		log.Printf("Draining for %s", cfg.Health.DrainDelay)
+		time.Sleep(cfg.Health.DrainDelay)
+	}
+
 	// Graceful shutdown
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer shutdownCancel()
 	if err := apiServer.Shutdown(shutdownCtx); err != nil {
 		log.Printf("API server shutdown error: %v", err)
 	}
+	if grpcServer != nil {
+		if err := grpcServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("gRPC server shutdown error: %v", err)
+		}
+	}
 	log.Println("Server exited")
 }
+
+// newStorage создает хранилище согласно конфигурации
+func newStorage(cfg config.StorageConfig) (storage.Storage, error) {
+	// Буфер подтверждает показ до записи и обошел бы атомарную проверку лимита
+	if cfg.ShowCap > 0 && cfg.BufferFlushInterval > 0 {
+		return nil, errors.New("storage.show_cap requires unbuffered writes, set storage.buffer_flush_interval to 0")
+	}
+
+	store, err := newBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.BufferFlushInterval > 0 {
+		log.Printf("Buffering shows and clicks, flush interval %v", cfg.BufferFlushInterval)
+		return buffered.New(store, cfg.BufferFlushInterval), nil
+	}
+	return store, nil
+}
+
+// newIdempotencyStore создает хранилище ключей идемпотентности. Ключи
+// в postgres используют пул соединений основного хранилища.
+func newIdempotencyStore(kind string, store storage.Storage) (idempotency.Store, error) {
+	switch kind {
+	case "", "memory":
+		return idempotency.NewMemoryStore(), nil
+	case "postgres":
+		pg, ok := postgresBackend(store)
+		if !ok {
+			return nil, errors.New("idempotency store postgres requires storage driver postgres")
+		}
+		return postgres.NewIdempotencyStore(pg), nil
+	default:
+		return nil, fmt.Errorf("unknown idempotency store: %q", kind)
+	}
+}
+
+// newRateLimiter создает хранилище лимитов запросов. Лимиты в памяти
+// считаются отдельно каждым экземпляром, postgres и redis - общие.
+func newRateLimiter(kind string, store storage.Storage) (ratelimit.Limiter, error) {
+	switch kind {
+	case "", "memory":
+		return ratelimit.NewMemoryLimiter(), nil
+	case "postgres":
+		pg, ok := postgresBackend(store)
+		if !ok {
+			return nil, errors.New("rate limit store postgres requires storage driver postgres")
+		}
+		return postgres.NewRateLimiter(pg), nil
+	case "redis":
+		rs, ok := redisBackend(store)
+		if !ok {
+			return nil, errors.New("rate limit store redis requires storage driver redis")
+		}
+		return redis.NewRateLimiter(rs), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store: %q", kind)
+	}
+}
+
+// postgresBackend возвращает хранилище PostgreSQL под буфером записи, если
+// используется драйвер postgres
+func postgresBackend(store storage.Storage) (*postgres.PostgresStorage, bool) {
+	if buffer, ok := store.(*buffered.BufferedStorage); ok {
+		store = buffer.Storage
+	}
+	pg, ok := store.(*postgres.PostgresStorage)
+	return pg, ok
+}
+
+// redisBackend возвращает хранилище Redis под буфером записи, если
+// используется драйвер redis
+func redisBackend(store storage.Storage) (*redis.RedisStorage, bool) {
+	if buffer, ok := store.(*buffered.BufferedStorage); ok {
+		store = buffer.Storage
+	}
+	rs, ok := store.(*redis.RedisStorage)
+	return rs, ok
+}
+
+// newBackend создает реализацию хранилища по имени драйвера
+func newBackend(cfg config.StorageConfig) (storage.Storage, error) {
+	switch cfg.Driver {
+	case "postgres":
+		store, err := postgres.New(os.Getenv("DB_URL"))
+		if err != nil {
+			return nil, err
+		}
+		if cfg.AutoMigrate {
+			if err := store.Migrate(context.Background()); err != nil {
+				_ = store.Close()
+				return nil, fmt.Errorf("failed to migrate database: %w", err)
+			}
+		}
+		return store, nil
+	case "memory":
+		if cfg.SnapshotPath == "" {
+			return memory.New(), nil
+		}
+		return memory.NewWithSnapshot(cfg.SnapshotPath)
+	case "bolt":
+		return bolt.New(cfg.BoltPath)
+	case "redis":
+		return redis.New(cfg.RedisAddr, cfg.ShowCap)
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %q", cfg.Driver)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"banner-rotation/internal/storage/postgres"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// runMigrate выполняет подкоманду migrate:
+//
+//	banner-rotation migrate up
+//	banner-rotation migrate down [N]
+//	banner-rotation migrate status
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up | down [N] | status")
+	}
+
+	store, err := postgres.New(os.Getenv("DB_URL"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = store.Close()
+	}()
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		return store.Migrate(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps: %q", args[1])
+			}
+		}
+		return store.MigrateDown(ctx, steps)
+	case "status":
+		status, err := store.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range status {
+			applied := "pending"
+			if st.AppliedAt != nil {
+				applied = "applied " + st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", st.Version, st.Name, applied)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command: %q", args[0])
+	}
+}
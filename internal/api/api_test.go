@@ -2,16 +2,21 @@ package api
 
 import (
 	"banner-rotation/internal/app"
+	"banner-rotation/internal/storage"
+	"banner-rotation/internal/storage/memory"
 	"bytes"
 	"context"
 	"encoding/json"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockBandit реализует app.BanditInterface для тестов
@@ -29,22 +34,82 @@ func (m *MockBandit) RemoveBannerFromSlot(ctx context.Context, slotID, bannerID
 	return args.Error(0)
 }
 
-func (m *MockBandit) ChooseBanner(ctx context.Context, slotID, groupID int) (int, error) {
-	args := m.Called(ctx, slotID, groupID)
+func (m *MockBandit) UpdateSlotRotation(ctx context.Context, slotID int, change storage.RotationChange) ([]int, error) {
+	args := m.Called(ctx, slotID, change)
+	ids, _ := args.Get(0).([]int)
+	return ids, args.Error(1)
+}
+
+func (m *MockBandit) ChooseBanner(ctx context.Context, slotID, groupID int, visitor storage.Visitor) (int, error) {
+	args := m.Called(ctx, slotID, groupID, visitor)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockBandit) ChooseBanners(ctx context.Context, choices []app.SlotChoice, visitor storage.Visitor) []app.SlotDecision {
+	args := m.Called(ctx, choices, visitor)
+	decisions, _ := args.Get(0).([]app.SlotDecision)
+	return decisions
+}
+
+func (m *MockBandit) ReserveBanner(ctx context.Context, slotID, groupID int, visitor storage.Visitor) (int, error) {
+	args := m.Called(ctx, slotID, groupID, visitor)
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockBandit) ReserveBanners(ctx context.Context, choices []app.SlotChoice, visitor storage.Visitor) []app.SlotDecision {
+	args := m.Called(ctx, choices, visitor)
+	decisions, _ := args.Get(0).([]app.SlotDecision)
+	return decisions
+}
+
+func (m *MockBandit) ConfirmShow(ctx context.Context, slotID, bannerID, groupID int) error {
+	args := m.Called(ctx, slotID, bannerID, groupID)
+	return args.Error(0)
+}
+
+func (m *MockBandit) SetBannerTargeting(ctx context.Context, slotID, bannerID int, targeting storage.Targeting) error {
+	args := m.Called(ctx, slotID, bannerID, targeting)
+	return args.Error(0)
+}
+
 func (m *MockBandit) RecordClick(ctx context.Context, slotID, bannerID, groupID int) error {
 	args := m.Called(ctx, slotID, bannerID, groupID)
 	return args.Error(0)
 }
 
+func (m *MockBandit) UpdateBanner(ctx context.Context, banner storage.Banner) error {
+	args := m.Called(ctx, banner)
+	return args.Error(0)
+}
+
+func (m *MockBandit) UpdateSlot(ctx context.Context, slot storage.Slot) error {
+	args := m.Called(ctx, slot)
+	return args.Error(0)
+}
+
+func (m *MockBandit) UpdateCampaign(ctx context.Context, campaign storage.Campaign) error {
+	args := m.Called(ctx, campaign)
+	return args.Error(0)
+}
+
+func (m *MockBandit) SetCampaignStatus(ctx context.Context, campaignID int, status storage.CampaignStatus) (storage.Campaign, error) {
+	args := m.Called(ctx, campaignID, status)
+	campaign, _ := args.Get(0).(storage.Campaign)
+	return campaign, args.Error(1)
+}
+
+func (m *MockBandit) GetSlotStats(ctx context.Context, slotID, groupID int, from, to time.Time) (*app.SlotReport, error) {
+	args := m.Called(ctx, slotID, groupID, from, to)
+	report, _ := args.Get(0).(*app.SlotReport)
+	return report, args.Error(1)
+}
+
 func TestAPIEndpoints(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	// Создаем мок, реализующий интерфейс BanditInterface
 	mockBandit := new(MockBandit)
-	server := NewServer(mockBandit)
+	server := NewServer(mockBandit, memory.New())
 
 	t.Run("AddBannerToSlot - success", func(t *testing.T) {
 		mockBandit.On("AddBannerToSlot", mock.Anything, 1, 100).Return(nil)
@@ -86,7 +151,7 @@ func TestAPIEndpoints(t *testing.T) {
 	})
 
 	t.Run("ChooseBanner - success", func(t *testing.T) {
-		mockBandit.On("ChooseBanner", mock.Anything, 1, 1).Return(100, nil)
+		mockBandit.On("ChooseBanner", mock.Anything, 1, 1, storage.Visitor{}).Return(100, nil)
 
 		w := httptest.NewRecorder()
 		req := createRequest(t, "POST", "/api/v1/choose_banner", ChooseBannerRequest{
@@ -105,7 +170,7 @@ func TestAPIEndpoints(t *testing.T) {
 	})
 
 	t.Run("ChooseBanner - no banners", func(t *testing.T) {
-		mockBandit.On("ChooseBanner", mock.Anything, 2, 1).Return(0, app.ErrNoBanners)
+		mockBandit.On("ChooseBanner", mock.Anything, 2, 1, storage.Visitor{}).Return(0, app.ErrNoBanners)
 
 		w := httptest.NewRecorder()
 		req := createRequest(t, "POST", "/api/v1/choose_banner", ChooseBannerRequest{
@@ -114,7 +179,11 @@ func TestAPIEndpoints(t *testing.T) {
 		})
 
 		server.router.ServeHTTP(w, req)
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		var resp ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "no_banners", resp.Code)
 		mockBandit.AssertExpectations(t)
 	})
 
@@ -134,6 +203,88 @@ func TestAPIEndpoints(t *testing.T) {
 	})
 }
 
+func TestSlotStatsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockBandit := new(MockBandit)
+	server := NewServer(mockBandit, memory.New())
+
+	t.Run("lifetime", func(t *testing.T) {
+		mockBandit.On("GetSlotStats", mock.Anything, 1, 2, time.Time{}, time.Time{}).Return(&app.SlotReport{
+			SlotID:     1,
+			GroupID:    2,
+			TotalShows: 10,
+			Banners: []app.BannerReport{
+				{BannerID: 100, Shows: 10, Clicks: 2, CTR: 0.2, CILow: 0.05, CIHigh: 0.5, Score: 1.1, Share: 1},
+				{BannerID: 101, Score: math.MaxFloat64},
+			},
+		}, nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/slots/1/stats?group_id=2", nil)
+		server.router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp SlotStatsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Nil(t, resp.From)
+		require.Len(t, resp.Banners, 2)
+		assert.Equal(t, 0.2, resp.Banners[0].CTR)
+		require.NotNil(t, resp.Banners[0].Score)
+		assert.Equal(t, 1.1, *resp.Banners[0].Score)
+		assert.Nil(t, resp.Banners[1].Score)
+	})
+
+	t.Run("range", func(t *testing.T) {
+		from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+		mockBandit.On("GetSlotStats", mock.Anything, 1, 2,
+			mock.MatchedBy(from.Equal), mock.MatchedBy(to.Equal),
+		).Return(&app.SlotReport{SlotID: 1, GroupID: 2, From: from, To: to}, nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET",
+			"/api/v1/slots/1/stats?group_id=2&from=2024-05-01T00:00:00Z&to=2024-05-02T00:00:00Z", nil)
+		server.router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp SlotStatsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.NotNil(t, resp.From)
+		assert.True(t, from.Equal(*resp.From))
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		for _, url := range []string{
+			"/api/v1/slots/abc/stats?group_id=1",
+			"/api/v1/slots/1/stats",
+			"/api/v1/slots/1/stats?group_id=1&from=yesterday",
+			"/api/v1/slots/1/stats?group_id=1&from=2024-05-02T00:00:00Z&to=2024-05-01T00:00:00Z",
+		} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", url, nil)
+			server.router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code, url)
+		}
+	})
+
+	mockBandit.AssertExpectations(t)
+}
+
+// createCatalog заводит в справочнике слоты и баннеры с перечисленными ID
+func createCatalog(t *testing.T, store storage.Storage, slotIDs, bannerIDs []int) {
+	t.Helper()
+	ctx := context.Background()
+	for _, id := range slotIDs {
+		_, err := store.CreateSlot(ctx, storage.Slot{ID: id})
+		require.NoError(t, err)
+	}
+	for _, id := range bannerIDs {
+		_, err := store.CreateBanner(ctx, storage.Banner{ID: id})
+		require.NoError(t, err)
+	}
+}
+
 func createRequest(t *testing.T, method, url string, body interface{}) *http.Request {
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"banner-rotation/internal/auth"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader - заголовок с API-ключом; ключ можно передать и как
+// Authorization: Bearer <ключ>
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyContextKey - ключ gin.Context, под которым authorize сохраняет auth.Key запроса
+const apiKeyContextKey = "apiKey"
+
+// authorize пропускает запрос, только если его ключ дает право scope.
+// Без Authenticator проверка выключена.
+func (s *Server) authorize(scope auth.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.auth == nil {
+			c.Next()
+			return
+		}
+
+		key, err := s.auth.Authorize(c.Request.Context(), requestKey(c), scope)
+		if err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Set(apiKeyContextKey, key)
+		c.Next()
+	}
+}
+
+// requestKey возвращает API-ключ запроса
+func requestKey(c *gin.Context) string {
+	if key := c.GetHeader(APIKeyHeader); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"banner-rotation/internal/auth"
+	"banner-rotation/internal/storage"
+	"banner-rotation/internal/storage/memory"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mapKeyStore - ключи по хешу
+type mapKeyStore map[string]auth.Key
+
+func (s mapKeyStore) LookupKey(ctx context.Context, hash string) (auth.Key, error) {
+	key, ok := s[hash]
+	if !ok {
+		return auth.Key{}, storage.ErrNotFound
+	}
+	return key, nil
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockBandit := new(MockBandit)
+	server := NewServer(mockBandit, memory.New())
+	server.SetAuthenticator(auth.NewAuthenticator(mapKeyStore{
+		auth.HashKey("serve-key"): {ID: 1, Name: "web", Scopes: []auth.Scope{auth.ScopeServe}},
+		auth.HashKey("admin-key"): {ID: 2, Name: "ops", Scopes: []auth.Scope{auth.ScopeAdmin}},
+	}, time.Minute))
+
+	mockBandit.On("ChooseBanner", mock.Anything, 1, 1, storage.Visitor{}).Return(100, nil)
+	mockBandit.On("RemoveBannerFromSlot", mock.Anything, 1, 100).Return(nil)
+
+	do := func(method, url string, body interface{}, header, key string) *httptest.ResponseRecorder {
+		req := createRequest(t, method, url, body)
+		if key != "" {
+			req.Header.Set(header, key)
+		}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	choose := ChooseBannerRequest{SlotID: 1, GroupID: 1}
+	remove := RemoveBannerFromSlotRequest{SlotID: 1, BannerID: 100}
+
+	t.Run("missing or unknown key", func(t *testing.T) {
+		w := do("POST", "/api/v1/choose_banner", choose, APIKeyHeader, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		var resp ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "unauthorized", resp.Code)
+
+		assert.Equal(t, http.StatusUnauthorized, do("POST", "/api/v1/choose_banner", choose, APIKeyHeader, "wrong").Code)
+	})
+
+	t.Run("serve scope", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do("POST", "/api/v1/choose_banner", choose, APIKeyHeader, "serve-key").Code)
+		assert.Equal(t, http.StatusOK, do("POST", "/api/v1/choose_banner", choose, "Authorization", "Bearer serve-key").Code)
+		assert.Equal(t, http.StatusForbidden, do("DELETE", "/api/v1/banner_slot", remove, APIKeyHeader, "serve-key").Code)
+		assert.Equal(t, http.StatusForbidden, do("GET", "/api/v1/banners", nil, APIKeyHeader, "serve-key").Code)
+	})
+
+	t.Run("admin scope", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do("DELETE", "/api/v1/banner_slot", remove, APIKeyHeader, "admin-key").Code)
+		assert.Equal(t, http.StatusOK, do("POST", "/api/v1/choose_banner", choose, APIKeyHeader, "admin-key").Code)
+	})
+
+	t.Run("spec is public", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do("GET", "/api/v1/openapi.json", nil, APIKeyHeader, "").Code)
+	})
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"banner-rotation/internal/app"
+	"banner-rotation/internal/storage"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChooseBannersRequest запрос на выбор баннеров для нескольких слотов одной
+// страницы, не больше 50. Атрибуты пользователя и контекст показа общие
+// для всех слотов.
+type ChooseBannersRequest struct {
+	Slots []SlotChoiceRequest `json:"slots" binding:"required,min=1,max=50,dive"`
+	// User - атрибуты для подбора группы слотам без group_id
+	User *UserAttributes `json:"user"`
+	// WithCreative - добавить в ответ креативы выбранных баннеров
+	WithCreative bool `json:"with_creative"`
+	// Контекст показа для таргетинга, пустые значения не заданы
+	Country string `json:"country"`
+	Device  string `json:"device" binding:"omitempty,oneof=desktop mobile tablet"`
+	OS      string `json:"os"`
+}
+
+// SlotChoiceRequest слот в запросе choose_banners. group_id обязателен,
+// если в запросе не переданы атрибуты пользователя.
+type SlotChoiceRequest struct {
+	SlotID  int `json:"slot_id" binding:"required"`
+	GroupID int `json:"group_id"`
+}
+
+// ChooseBannersResponse ответ с выбором для каждого слота в порядке запроса
+type ChooseBannersResponse struct {
+	Results []SlotChoiceResponse `json:"results"`
+}
+
+// SlotChoiceResponse выбор для одного слота: баннер или ошибка
+type SlotChoiceResponse struct {
+	SlotID        int            `json:"slot_id"`
+	BannerID      int            `json:"banner_id,omitempty"`
+	GroupID       int            `json:"group_id,omitempty"`
+	Creative      *Creative      `json:"creative,omitempty"`
+	ClickURL      string         `json:"click_url,omitempty"`
+	ImpressionURL string         `json:"impression_url,omitempty"`
+	Error         *ErrorResponse `json:"error,omitempty"`
+}
+
+// chooseBanners выбирает баннеры для нескольких слотов за один запрос.
+// Ответ всегда 200, если запрос корректен: ошибки слотов возвращаются
+// в их результатах.
+func (s *Server) chooseBanners(c *gin.Context) {
+	var req ChooseBannersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	results := make([]SlotChoiceResponse, len(req.Slots))
+	choices := make([]app.SlotChoice, 0, len(req.Slots))
+	indexes := make([]int, 0, len(req.Slots))
+
+	// Группа подбирается один раз на запрос и только если она нужна
+	var (
+		resolved   bool
+		resolvedID int
+		resolveErr error
+	)
+	for i, slot := range req.Slots {
+		results[i].SlotID = slot.SlotID
+		groupID := slot.GroupID
+		if groupID == 0 {
+			if req.User == nil {
+				badRequest(c, fmt.Errorf("slots[%d]: group_id is required without user", i))
+				return
+			}
+			if !resolved {
+				resolvedID, resolveErr = s.segments.ResolveGroup(ctx, storage.UserAttributes(*req.User))
+				resolved = true
+			}
+			if resolveErr != nil {
+				results[i].Error = slotError(resolveErr)
+				continue
+			}
+			groupID = resolvedID
+			results[i].GroupID = groupID
+		}
+
+		choices = append(choices, app.SlotChoice{SlotID: slot.SlotID, GroupID: groupID})
+		indexes = append(indexes, i)
+	}
+
+	choose := s.bandit.ChooseBanners
+	if s.reservations != nil {
+		choose = s.bandit.ReserveBanners
+	}
+	visitor := storage.Visitor{Country: req.Country, Device: req.Device, OS: req.OS}
+	for j, decision := range choose(ctx, choices, visitor) {
+		result := &results[indexes[j]]
+		if decision.Err != nil {
+			result.Error = slotError(decision.Err)
+			continue
+		}
+
+		// Креатив читается до заполнения результата, чтобы слот с ошибкой
+		// не выглядел выбранным
+		var creative *Creative
+		if req.WithCreative {
+			var err error
+			if creative, err = s.bannerCreative(ctx, decision.BannerID); err != nil {
+				result.Error = slotError(err)
+				continue
+			}
+		}
+		result.BannerID = decision.BannerID
+		result.ClickURL = s.clickURL(decision.SlotID, decision.BannerID, decision.GroupID)
+		result.ImpressionURL = s.impressionURL(decision.SlotID, decision.BannerID, decision.GroupID)
+		result.Creative = creative
+	}
+
+	c.JSON(http.StatusOK, ChooseBannersResponse{Results: results})
+}
+
+// slotError возвращает описание ошибки слота в том же виде, что и ответ с ошибкой
+func slotError(err error) *ErrorResponse {
+	_, resp := errorResponse(err)
+	return &resp
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"banner-rotation/internal/app"
+	"banner-rotation/internal/storage"
+	"banner-rotation/internal/storage/memory"
+	"banner-rotation/internal/tracking"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChooseBannersEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctx := context.Background()
+	store := memory.New()
+	server := NewServer(app.NewBandit(store, nil), store)
+
+	_, err := store.CreateGroup(ctx, storage.Group{ID: 5, Rule: &storage.GroupRule{}})
+	require.NoError(t, err)
+	createCatalog(t, store, []int{1, 2}, []int{10, 20})
+	require.NoError(t, store.AddBannerToSlot(ctx, 1, 10))
+	require.NoError(t, store.AddBannerToSlot(ctx, 2, 20))
+
+	do := func(body interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, createRequest(t, "POST", "/api/v1/choose_banners", body))
+		return w
+	}
+
+	t.Run("errors are reported per slot", func(t *testing.T) {
+		w := do(ChooseBannersRequest{Slots: []SlotChoiceRequest{
+			{SlotID: 1, GroupID: 1},
+			{SlotID: 9, GroupID: 1},
+			{SlotID: 2},
+		}, User: &UserAttributes{Age: 30}})
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp ChooseBannersResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Results, 3)
+
+		assert.Equal(t, SlotChoiceResponse{SlotID: 1, BannerID: 10}, resp.Results[0])
+
+		assert.Equal(t, 9, resp.Results[1].SlotID)
+		assert.Zero(t, resp.Results[1].BannerID)
+		require.NotNil(t, resp.Results[1].Error)
+		assert.Equal(t, "no_banners", resp.Results[1].Error.Code)
+
+		assert.Equal(t, SlotChoiceResponse{SlotID: 2, BannerID: 20, GroupID: 5}, resp.Results[2])
+
+		stats, err := store.GetBannerStats(ctx, 2, 5)
+		require.NoError(t, err)
+		require.Len(t, stats, 1)
+		assert.Equal(t, 1, stats[0].Shows)
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		w := do(ChooseBannersRequest{})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = do(ChooseBannersRequest{Slots: []SlotChoiceRequest{{SlotID: 1}}})
+		assert.Equal(t, http.StatusBadRequest, w.Code, "group_id is required without user")
+
+		w = do(ChooseBannersRequest{Slots: make([]SlotChoiceRequest, 51)})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+// brokenCatalog - справочник, из которого не читаются баннеры
+type brokenCatalog struct {
+	*memory.MemoryStorage
+}
+
+func (c brokenCatalog) GetBanner(ctx context.Context, id int) (storage.Banner, error) {
+	return storage.Banner{}, storage.ErrUnavailable
+}
+
+func TestChooseWithCreative_CatalogError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctx := context.Background()
+	store := memory.New()
+	createCatalog(t, store, []int{1}, []int{10})
+	require.NoError(t, store.AddBannerToSlot(ctx, 1, 10))
+	server := NewServer(app.NewBandit(store, nil), brokenCatalog{store})
+	server.SetTracking(tracking.NewSigner([]byte("secret")), "https://ads.example.com", "", time.Hour)
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, createRequest(t, "POST", "/api/v1/choose_banners", ChooseBannersRequest{
+		Slots:        []SlotChoiceRequest{{SlotID: 1, GroupID: 1}},
+		WithCreative: true,
+	}))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp ChooseBannersResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Results, 1)
+	require.NotNil(t, resp.Results[0].Error)
+	assert.Equal(t, "unavailable", resp.Results[0].Error.Code)
+	assert.Equal(t, SlotChoiceResponse{SlotID: 1, Error: resp.Results[0].Error}, resp.Results[0], "failed slot carries no banner")
+
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, createRequest(t, "POST", "/api/v1/choose_banner", ChooseBannerRequest{
+		SlotID: 1, GroupID: 1, WithCreative: true,
+	}))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.NotContains(t, w.Body.String(), "banner_id")
+}
@@ -0,0 +1,287 @@
+package api
+
+import (
+	"banner-rotation/internal/storage"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateAdvertiserRequest запрос на создание рекламодателя.
+// Если ID не указан, он выдается автоматически.
+type CreateAdvertiserRequest struct {
+	ID   int    `json:"id" binding:"omitempty,min=1"`
+	Name string `json:"name" binding:"required"`
+}
+
+// UpdateAdvertiserRequest запрос на изменение рекламодателя
+type UpdateAdvertiserRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// AdvertiserResponse рекламодатель
+type AdvertiserResponse struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// CreateCampaignRequest запрос на создание кампании. Статус по умолчанию -
+// active; start_at и end_at ограничивают период показа и необязательны.
+type CreateCampaignRequest struct {
+	ID int `json:"id" binding:"omitempty,min=1"`
+	UpdateCampaignRequest
+}
+
+// UpdateCampaignRequest запрос на изменение кампании
+type UpdateCampaignRequest struct {
+	AdvertiserID int        `json:"advertiser_id" binding:"required,min=1"`
+	Name         string     `json:"name" binding:"required"`
+	Status       string     `json:"status" binding:"omitempty,oneof=active paused stopped"`
+	Budget       int64      `json:"budget" binding:"min=0"`
+	StartAt      *time.Time `json:"start_at"`
+	EndAt        *time.Time `json:"end_at"`
+}
+
+// CampaignResponse кампания
+type CampaignResponse struct {
+	ID           int        `json:"id"`
+	AdvertiserID int        `json:"advertiser_id"`
+	Name         string     `json:"name"`
+	Status       string     `json:"status"`
+	Budget       int64      `json:"budget"`
+	StartAt      *time.Time `json:"start_at,omitempty"`
+	EndAt        *time.Time `json:"end_at,omitempty"`
+}
+
+func newCampaignResponse(campaign storage.Campaign) CampaignResponse {
+	resp := CampaignResponse{
+		ID:           campaign.ID,
+		AdvertiserID: campaign.AdvertiserID,
+		Name:         campaign.Name,
+		Status:       string(campaign.Status),
+		Budget:       campaign.Budget,
+	}
+	if !campaign.StartAt.IsZero() {
+		resp.StartAt = &campaign.StartAt
+	}
+	if !campaign.EndAt.IsZero() {
+		resp.EndAt = &campaign.EndAt
+	}
+	return resp
+}
+
+// toStorage проверяет период показа и собирает кампанию с указанным ID
+func (r UpdateCampaignRequest) toStorage(id int) (storage.Campaign, error) {
+	campaign := storage.Campaign{
+		ID:           id,
+		AdvertiserID: r.AdvertiserID,
+		Name:         r.Name,
+		Status:       storage.CampaignStatus(r.Status),
+		Budget:       r.Budget,
+	}
+	if campaign.Status == "" {
+		campaign.Status = storage.CampaignActive
+	}
+	if r.StartAt != nil {
+		campaign.StartAt = r.StartAt.UTC()
+	}
+	if r.EndAt != nil {
+		campaign.EndAt = r.EndAt.UTC()
+	}
+	if !campaign.StartAt.IsZero() && !campaign.EndAt.IsZero() && !campaign.StartAt.Before(campaign.EndAt) {
+		return storage.Campaign{}, errors.New("start_at must be before end_at")
+	}
+	return campaign, nil
+}
+
+func (s *Server) createAdvertiser(c *gin.Context) {
+	var req CreateAdvertiserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	advertiser, err := s.catalog.CreateAdvertiser(c.Request.Context(), storage.Advertiser{ID: req.ID, Name: req.Name})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, AdvertiserResponse(advertiser))
+}
+
+func (s *Server) getAdvertiser(c *gin.Context) {
+	id, ok := entityID(c)
+	if !ok {
+		return
+	}
+
+	advertiser, err := s.catalog.GetAdvertiser(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, AdvertiserResponse(advertiser))
+}
+
+func (s *Server) listAdvertisers(c *gin.Context) {
+	advertisers, err := s.catalog.ListAdvertisers(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	resp := make([]AdvertiserResponse, 0, len(advertisers))
+	for _, advertiser := range advertisers {
+		resp = append(resp, AdvertiserResponse(advertiser))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) updateAdvertiser(c *gin.Context) {
+	id, ok := entityID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateAdvertiserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	advertiser := storage.Advertiser{ID: id, Name: req.Name}
+	if err := s.catalog.UpdateAdvertiser(c.Request.Context(), advertiser); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, AdvertiserResponse(advertiser))
+}
+
+func (s *Server) deleteAdvertiser(c *gin.Context) {
+	id, ok := entityID(c)
+	if !ok {
+		return
+	}
+
+	if err := s.catalog.DeleteAdvertiser(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) createCampaign(c *gin.Context) {
+	var req CreateCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	campaign, err := req.toStorage(req.ID)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	campaign, err = s.catalog.CreateCampaign(c.Request.Context(), campaign)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newCampaignResponse(campaign))
+}
+
+func (s *Server) getCampaign(c *gin.Context) {
+	id, ok := entityID(c)
+	if !ok {
+		return
+	}
+
+	campaign, err := s.catalog.GetCampaign(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newCampaignResponse(campaign))
+}
+
+func (s *Server) listCampaigns(c *gin.Context) {
+	campaigns, err := s.catalog.ListCampaigns(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	resp := make([]CampaignResponse, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		resp = append(resp, newCampaignResponse(campaign))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// updateCampaign изменяет кампанию через бандита, чтобы сбросить кеш слотов
+func (s *Server) updateCampaign(c *gin.Context) {
+	id, ok := entityID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	campaign, err := req.toStorage(id)
+	if err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	if err := s.bandit.UpdateCampaign(c.Request.Context(), campaign); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newCampaignResponse(campaign))
+}
+
+func (s *Server) deleteCampaign(c *gin.Context) {
+	id, ok := entityID(c)
+	if !ok {
+		return
+	}
+
+	if err := s.catalog.DeleteCampaign(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// setCampaignStatus возвращает обработчик, переводящий кампанию в status
+func (s *Server) setCampaignStatus(status storage.CampaignStatus) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := entityID(c)
+		if !ok {
+			return
+		}
+
+		campaign, err := s.bandit.SetCampaignStatus(c.Request.Context(), id, status)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, newCampaignResponse(campaign))
+	}
+}
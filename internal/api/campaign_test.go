@@ -0,0 +1,109 @@
+package api
+
+import (
+	"banner-rotation/internal/app"
+	"banner-rotation/internal/storage/memory"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCampaignEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := memory.New()
+	server := NewServer(app.NewBandit(store, nil), store)
+	createCatalog(t, store, []int{1}, nil)
+
+	do := func(method, url string, body interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, createRequest(t, method, url, body))
+		return w
+	}
+	choose := func() *httptest.ResponseRecorder {
+		return do("POST", "/api/v1/choose_banner", ChooseBannerRequest{SlotID: 1, GroupID: 1})
+	}
+
+	t.Run("create", func(t *testing.T) {
+		w := do("POST", "/api/v1/advertisers", CreateAdvertiserRequest{Name: "acme"})
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		w = do("POST", "/api/v1/campaigns", CreateCampaignRequest{UpdateCampaignRequest: UpdateCampaignRequest{
+			AdvertiserID: 1,
+			Name:         "winter",
+			Budget:       100000,
+			StartAt:      &start,
+		}})
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var campaign CampaignResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &campaign))
+		assert.Equal(t, CampaignResponse{
+			ID:           1,
+			AdvertiserID: 1,
+			Name:         "winter",
+			Status:       "active",
+			Budget:       100000,
+			StartAt:      &start,
+		}, campaign)
+
+		w = do("POST", "/api/v1/banners", CreateBannerRequest{ID: 10, Description: "snow", CampaignID: 1})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, http.StatusOK, do("POST", "/api/v1/banner_slot", AddBannerToSlotRequest{SlotID: 1, BannerID: 10}).Code)
+		assert.Equal(t, http.StatusOK, choose().Code)
+	})
+
+	t.Run("create - invalid", func(t *testing.T) {
+		start := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+		end := start.Add(-time.Hour)
+		w := do("POST", "/api/v1/campaigns", CreateCampaignRequest{UpdateCampaignRequest: UpdateCampaignRequest{
+			AdvertiserID: 1, Name: "backwards", StartAt: &start, EndAt: &end,
+		}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = do("POST", "/api/v1/campaigns", map[string]interface{}{"advertiser_id": 1, "name": "x", "status": "deleted"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = do("POST", "/api/v1/campaigns", CreateCampaignRequest{UpdateCampaignRequest: UpdateCampaignRequest{
+			AdvertiserID: 100, Name: "orphan",
+		}})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = do("POST", "/api/v1/banners", CreateBannerRequest{Description: "orphan", CampaignID: 100})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("pause and resume", func(t *testing.T) {
+		w := do("POST", "/api/v1/campaigns/1/pause", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var campaign CampaignResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &campaign))
+		assert.Equal(t, "paused", campaign.Status)
+
+		// Единственный баннер слота приостановлен
+		assert.Equal(t, http.StatusNotFound, choose().Code)
+
+		assert.Equal(t, http.StatusOK, do("POST", "/api/v1/campaigns/1/resume", nil).Code)
+		assert.Equal(t, http.StatusOK, choose().Code)
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do("POST", "/api/v1/campaigns/1/stop", nil).Code)
+		assert.Equal(t, http.StatusConflict, do("POST", "/api/v1/campaigns/1/resume", nil).Code)
+		assert.Equal(t, http.StatusNotFound, choose().Code)
+		assert.Equal(t, http.StatusNotFound, do("POST", "/api/v1/campaigns/100/pause", nil).Code)
+	})
+
+	t.Run("delete in use", func(t *testing.T) {
+		assert.Equal(t, http.StatusConflict, do("DELETE", "/api/v1/campaigns/1", nil).Code)
+		assert.Equal(t, http.StatusConflict, do("DELETE", "/api/v1/advertisers/1", nil).Code)
+	})
+}
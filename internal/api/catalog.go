@@ -0,0 +1,440 @@
+package api
+
+import (
+	"banner-rotation/internal/storage"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateBannerRequest запрос на создание баннера.
+// Если ID не указан, он выдается автоматически.
+type CreateBannerRequest struct {
+	ID          int       `json:"id" binding:"omitempty,min=1"`
+	Description string    `json:"description" binding:"required"`
+	CampaignID  int       `json:"campaign_id" binding:"min=0"`
+	Creative    *Creative `json:"creative"`
+}
+
+// UpdateBannerRequest запрос на изменение баннера. Креатив заменяется
+// целиком: если он не передан, у баннера не остается креатива.
+type UpdateBannerRequest struct {
+	Description string    `json:"description" binding:"required"`
+	CampaignID  int       `json:"campaign_id" binding:"min=0"`
+	Creative    *Creative `json:"creative"`
+}
+
+// Creative креатив баннера: картинка со ссылкой или готовый HTML-фрагмент.
+// Ссылки принимаются только http и https: click_url становится адресом
+// перехода, а image_url отдается площадкам.
+type Creative struct {
+	ImageURL string `json:"image_url,omitempty" binding:"omitempty,http_url"`
+	HTML     string `json:"html,omitempty"`
+	ClickURL string `json:"click_url,omitempty" binding:"omitempty,http_url"`
+	Width    int    `json:"width,omitempty" binding:"min=0"`
+	Height   int    `json:"height,omitempty" binding:"min=0"`
+	AltText  string `json:"alt_text,omitempty"`
+}
+
+// BannerResponse баннер
+type BannerResponse struct {
+	ID          int       `json:"id"`
+	Description string    `json:"description"`
+	CampaignID  int       `json:"campaign_id,omitempty"`
+	Creative    *Creative `json:"creative,omitempty"`
+}
+
+func newBannerResponse(banner storage.Banner) BannerResponse {
+	return BannerResponse{
+		ID:          banner.ID,
+		Description: banner.Description,
+		CampaignID:  banner.CampaignID,
+		Creative:    newCreative(banner.Creative),
+	}
+}
+
+// newCreative возвращает nil для баннера без креатива
+func newCreative(creative storage.Creative) *Creative {
+	if creative == (storage.Creative{}) {
+		return nil
+	}
+	resp := Creative(creative)
+	return &resp
+}
+
+func (c *Creative) toStorage() storage.Creative {
+	if c == nil {
+		return storage.Creative{}
+	}
+	return storage.Creative(*c)
+}
+
+// CreateSlotRequest запрос на создание слота.
+// Если ID не указан, он выдается автоматически.
+type CreateSlotRequest struct {
+	ID          int    `json:"id" binding:"omitempty,min=1"`
+	Description string `json:"description" binding:"required"`
+	Sizes       []Size `json:"sizes" binding:"dive"`
+}
+
+// UpdateSlotRequest запрос на изменение слота. Список размеров
+// заменяется целиком; пустой список снимает ограничения.
+type UpdateSlotRequest struct {
+	Description string `json:"description" binding:"required"`
+	Sizes       []Size `json:"sizes" binding:"dive"`
+}
+
+// Size размер креатива в пикселях
+type Size struct {
+	Width  int `json:"width" binding:"min=1"`
+	Height int `json:"height" binding:"min=1"`
+}
+
+// SlotResponse слот. Sizes - допустимые размеры баннеров,
+// пустой список - слот принимает баннеры любого размера
+type SlotResponse struct {
+	ID          int    `json:"id"`
+	Description string `json:"description"`
+	Sizes       []Size `json:"sizes"`
+}
+
+func newSlotResponse(slot storage.Slot) SlotResponse {
+	sizes := make([]Size, len(slot.Sizes))
+	for i, size := range slot.Sizes {
+		sizes[i] = Size(size)
+	}
+	return SlotResponse{ID: slot.ID, Description: slot.Description, Sizes: sizes}
+}
+
+func toStorageSizes(sizes []Size) []storage.Size {
+	if len(sizes) == 0 {
+		return nil
+	}
+	result := make([]storage.Size, len(sizes))
+	for i, size := range sizes {
+		result[i] = storage.Size(size)
+	}
+	return result
+}
+
+// CreateGroupRequest запрос на создание группы.
+// Если ID не указан, он выдается автоматически.
+type CreateGroupRequest struct {
+	ID          int        `json:"id" binding:"omitempty,min=1"`
+	Description string     `json:"description" binding:"required"`
+	Rule        *GroupRule `json:"rule"`
+}
+
+// UpdateGroupRequest запрос на изменение группы. Правило заменяется
+// целиком: если оно не передано, группа не подбирается автоматически.
+type UpdateGroupRequest struct {
+	Description string     `json:"description" binding:"required"`
+	Rule        *GroupRule `json:"rule"`
+}
+
+// GroupRule правило подбора группы по атрибутам пользователя
+type GroupRule struct {
+	Priority  int      `json:"priority"`
+	MinAge    int      `json:"min_age,omitempty" binding:"min=0"`
+	MaxAge    int      `json:"max_age,omitempty" binding:"omitempty,gtefield=MinAge"`
+	Genders   []string `json:"genders,omitempty"`
+	Interests []string `json:"interests,omitempty"`
+}
+
+// GroupResponse социально-демографическая группа
+type GroupResponse struct {
+	ID          int        `json:"id"`
+	Description string     `json:"description"`
+	Rule        *GroupRule `json:"rule,omitempty"`
+}
+
+func newGroupResponse(group storage.Group) GroupResponse {
+	resp := GroupResponse{ID: group.ID, Description: group.Description}
+	if group.Rule != nil {
+		rule := GroupRule(*group.Rule)
+		resp.Rule = &rule
+	}
+	return resp
+}
+
+func (r *GroupRule) toStorage() *storage.GroupRule {
+	if r == nil {
+		return nil
+	}
+	rule := storage.GroupRule(*r)
+	return &rule
+}
+
+// entityID разбирает идентификатор из пути, при ошибке отвечает 400
+func entityID(c *gin.Context) (int, bool) {
+	return pathID(c, "id")
+}
+
+// pathID разбирает идентификатор из параметра пути name, при ошибке отвечает 400
+func pathID(c *gin.Context, name string) (int, bool) {
+	id, err := strconv.Atoi(c.Param(name))
+	if err != nil || id < 1 {
+		badRequest(c, errors.New("invalid "+name))
+		return 0, false
+	}
+	return id, true
+}
+
+func (s *Server) createBanner(c *gin.Context) {
+	var req CreateBannerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	banner, err := s.catalog.CreateBanner(c.Request.Context(), storage.Banner{
+		ID:          req.ID,
+		Description: req.Description,
+		CampaignID:  req.CampaignID,
+		Creative:    req.Creative.toStorage(),
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newBannerResponse(banner))
+}
+
+func (s *Server) getBanner(c *gin.Context) {
+	id, ok := entityID(c)
+	if !ok {
+		return
+	}
+
+	banner, err := s.catalog.GetBanner(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newBannerResponse(banner))
+}
+
+func (s *Server) listBanners(c *gin.Context) {
+	banners, err := s.catalog.ListBanners(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	resp := make([]BannerResponse, 0, len(banners))
+	for _, banner := range banners {
+		resp = append(resp, newBannerResponse(banner))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) updateBanner(c *gin.Context) {
+	id, ok := entityID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateBannerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	banner := storage.Banner{
+		ID:          id,
+		Description: req.Description,
+		CampaignID:  req.CampaignID,
+		Creative:    req.Creative.toStorage(),
+	}
+	if err := s.bandit.UpdateBanner(c.Request.Context(), banner); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newBannerResponse(banner))
+}
+
+func (s *Server) deleteBanner(c *gin.Context) {
+	id, ok := entityID(c)
+	if !ok {
+		return
+	}
+
+	if err := s.catalog.DeleteBanner(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) createSlot(c *gin.Context) {
+	var req CreateSlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	slot, err := s.catalog.CreateSlot(c.Request.Context(), storage.Slot{
+		ID:          req.ID,
+		Description: req.Description,
+		Sizes:       toStorageSizes(req.Sizes),
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newSlotResponse(slot))
+}
+
+func (s *Server) getSlot(c *gin.Context) {
+	id, ok := entityID(c)
+	if !ok {
+		return
+	}
+
+	slot, err := s.catalog.GetSlot(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newSlotResponse(slot))
+}
+
+func (s *Server) listSlots(c *gin.Context) {
+	slots, err := s.catalog.ListSlots(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	resp := make([]SlotResponse, 0, len(slots))
+	for _, slot := range slots {
+		resp = append(resp, newSlotResponse(slot))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) updateSlot(c *gin.Context) {
+	id, ok := entityID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateSlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	slot := storage.Slot{ID: id, Description: req.Description, Sizes: toStorageSizes(req.Sizes)}
+	if err := s.bandit.UpdateSlot(c.Request.Context(), slot); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newSlotResponse(slot))
+}
+
+func (s *Server) deleteSlot(c *gin.Context) {
+	id, ok := entityID(c)
+	if !ok {
+		return
+	}
+
+	if err := s.catalog.DeleteSlot(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) createGroup(c *gin.Context) {
+	var req CreateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	group, err := s.catalog.CreateGroup(c.Request.Context(), storage.Group{ID: req.ID, Description: req.Description, Rule: req.Rule.toStorage()})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	s.segments.Invalidate()
+
+	c.JSON(http.StatusCreated, newGroupResponse(group))
+}
+
+func (s *Server) getGroup(c *gin.Context) {
+	id, ok := entityID(c)
+	if !ok {
+		return
+	}
+
+	group, err := s.catalog.GetGroup(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newGroupResponse(group))
+}
+
+func (s *Server) listGroups(c *gin.Context) {
+	groups, err := s.catalog.ListGroups(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	resp := make([]GroupResponse, 0, len(groups))
+	for _, group := range groups {
+		resp = append(resp, newGroupResponse(group))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) updateGroup(c *gin.Context) {
+	id, ok := entityID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	group := storage.Group{ID: id, Description: req.Description, Rule: req.Rule.toStorage()}
+	if err := s.catalog.UpdateGroup(c.Request.Context(), group); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	s.segments.Invalidate()
+
+	c.JSON(http.StatusOK, newGroupResponse(group))
+}
+
+func (s *Server) deleteGroup(c *gin.Context) {
+	id, ok := entityID(c)
+	if !ok {
+		return
+	}
+
+	if err := s.catalog.DeleteGroup(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	s.segments.Invalidate()
+
+	c.Status(http.StatusNoContent)
+}
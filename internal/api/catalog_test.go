@@ -0,0 +1,97 @@
+package api
+
+import (
+	"banner-rotation/internal/app"
+	"banner-rotation/internal/storage/memory"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctx := context.Background()
+	store := memory.New()
+	server := NewServer(app.NewBandit(store, nil), store)
+
+	do := func(method, url string, body interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, createRequest(t, method, url, body))
+		return w
+	}
+
+	t.Run("create", func(t *testing.T) {
+		w := do("POST", "/api/v1/banners", CreateBannerRequest{Description: "summer sale"})
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var banner BannerResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &banner))
+		assert.Equal(t, BannerResponse{ID: 1, Description: "summer sale"}, banner)
+
+		w = do("POST", "/api/v1/slots", CreateSlotRequest{ID: 5, Description: "header"})
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		w = do("POST", "/api/v1/groups", CreateGroupRequest{Description: "students"})
+		assert.Equal(t, http.StatusCreated, w.Code)
+	})
+
+	t.Run("create - invalid request", func(t *testing.T) {
+		w := do("POST", "/api/v1/banners", map[string]interface{}{"id": 2})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("create - duplicate id", func(t *testing.T) {
+		w := do("POST", "/api/v1/slots", CreateSlotRequest{ID: 5, Description: "footer"})
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("get and list", func(t *testing.T) {
+		w := do("GET", "/api/v1/slots/5", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var slot SlotResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &slot))
+		assert.Equal(t, "header", slot.Description)
+
+		w = do("GET", "/api/v1/groups", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var groups []GroupResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &groups))
+		assert.Len(t, groups, 1)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, do("GET", "/api/v1/banners/100", nil).Code)
+		assert.Equal(t, http.StatusNotFound, do("PUT", "/api/v1/slots/100", UpdateSlotRequest{Description: "x"}).Code)
+		assert.Equal(t, http.StatusNotFound, do("DELETE", "/api/v1/groups/100", nil).Code)
+		assert.Equal(t, http.StatusBadRequest, do("GET", "/api/v1/banners/abc", nil).Code)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		w := do("PUT", "/api/v1/banners/1", UpdateBannerRequest{Description: "autumn sale"})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		banner, err := store.GetBanner(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "autumn sale", banner.Description)
+	})
+
+	t.Run("delete - in rotation", func(t *testing.T) {
+		require.NoError(t, store.AddBannerToSlot(ctx, 5, 1))
+
+		assert.Equal(t, http.StatusConflict, do("DELETE", "/api/v1/banners/1", nil).Code)
+		assert.Equal(t, http.StatusConflict, do("DELETE", "/api/v1/slots/5", nil).Code)
+
+		require.NoError(t, store.RemoveBannerFromSlot(ctx, 5, 1))
+		assert.Equal(t, http.StatusNoContent, do("DELETE", "/api/v1/banners/1", nil).Code)
+		assert.Equal(t, http.StatusNoContent, do("DELETE", "/api/v1/slots/5", nil).Code)
+	})
+}
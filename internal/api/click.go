@@ -0,0 +1,67 @@
+package api
+
+import (
+	"banner-rotation/internal/storage"
+	"banner-rotation/internal/tracking"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// clickURL возвращает подписанную ссылку перехода по баннеру или пустую
+// строку, если подпись ссылок не настроена
+func (s *Server) clickURL(slotID, bannerID, groupID int) string {
+	if s.tracker == nil {
+		return ""
+	}
+	return strings.TrimSuffix(s.trackingBaseURL, "/") + "/c/" +
+		s.tracker.Sign(tracking.KindClick, s.clickTTL, slotID, bannerID, groupID)
+}
+
+// click засчитывает клик по подписанной ссылке и перенаправляет на посадочную
+// страницу баннера. Клик по поврежденной или устаревшей ссылке не засчитывается,
+// но переход все равно выполняется; без посадочной страницы баннера - на
+// запасной адрес.
+func (s *Server) click(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	claims, err := tracking.Claims{}, tracking.ErrInvalidToken
+	if s.tracker != nil {
+		claims, err = s.tracker.Parse(tracking.KindClick, c.Param("token"))
+	}
+	if err == nil {
+		if err := s.bandit.RecordClick(ctx, claims.SlotID, claims.BannerID, claims.GroupID); err != nil {
+			log.Printf("Failed to record click on banner %d in slot %d: %v", claims.BannerID, claims.SlotID, err)
+		}
+	}
+
+	target := s.fallbackURL
+	if claims.BannerID != 0 {
+		banner, err := s.catalog.GetBanner(ctx, claims.BannerID)
+		switch {
+		case err == nil && isHTTPURL(banner.ClickURL):
+			target = banner.ClickURL
+		case err != nil && !errors.Is(err, storage.ErrNotFound):
+			log.Printf("Failed to load landing url of banner %d: %v", claims.BannerID, err)
+		}
+	}
+	if target == "" {
+		_ = c.Error(fmt.Errorf("no landing url for click: %w", storage.ErrNotFound))
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, target)
+}
+
+// isHTTPURL проверяет, что по ссылке можно перейти: схема http или https.
+// Посадочные страницы, сохраненные до проверки схемы при записи, могут быть любыми.
+func isHTTPURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"banner-rotation/internal/storage"
+	"banner-rotation/internal/storage/memory"
+	"banner-rotation/internal/tracking"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickRedirect(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	catalog := memory.New()
+	_, err := catalog.CreateBanner(ctx, storage.Banner{ID: 100, Creative: storage.Creative{
+		ImageURL: "https://cdn.example.com/100.png",
+		ClickURL: "https://shop.example.com/sale",
+	}})
+	require.NoError(t, err)
+
+	secret := []byte("secret")
+	newServer := func() (*Server, *MockBandit) {
+		mockBandit := new(MockBandit)
+		server := NewServer(mockBandit, catalog)
+		server.SetTracking(tracking.NewSigner(secret), "https://ads.example.com/", "https://example.com/", time.Hour)
+		return server, mockBandit
+	}
+	get := func(server *Server, url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w
+	}
+
+	t.Run("link from choose_banner counts the click", func(t *testing.T) {
+		server, mockBandit := newServer()
+		mockBandit.On("ChooseBanner", mock.Anything, 1, 2, storage.Visitor{}).Return(100, nil)
+		mockBandit.On("RecordClick", mock.Anything, 1, 100, 2).Return(nil).Once()
+
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, createRequest(t, "POST", "/api/v1/choose_banner", ChooseBannerRequest{SlotID: 1, GroupID: 2}))
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp ChooseBannerResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+		path, ok := strings.CutPrefix(resp.ClickURL, "https://ads.example.com/c/")
+		require.True(t, ok, resp.ClickURL)
+
+		w = get(server, "/c/"+path)
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://shop.example.com/sale", w.Header().Get("Location"))
+		mockBandit.AssertExpectations(t)
+	})
+
+	t.Run("expired token redirects without counting", func(t *testing.T) {
+		server, mockBandit := newServer()
+		token := tracking.NewSigner(secret).Sign(tracking.KindClick, -time.Minute, 1, 100, 2)
+
+		w := get(server, "/c/"+token)
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://shop.example.com/sale", w.Header().Get("Location"))
+		mockBandit.AssertNotCalled(t, "RecordClick", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("forged token redirects without counting", func(t *testing.T) {
+		server, mockBandit := newServer()
+		token := tracking.NewSigner([]byte("other")).Sign(tracking.KindClick, time.Hour, 1, 100, 2)
+
+		w := get(server, "/c/"+token)
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://shop.example.com/sale", w.Header().Get("Location"))
+		mockBandit.AssertNotCalled(t, "RecordClick", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("garbage token goes to fallback", func(t *testing.T) {
+		server, _ := newServer()
+
+		w := get(server, "/c/garbage")
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://example.com/", w.Header().Get("Location"))
+	})
+
+	t.Run("failed click is still redirected", func(t *testing.T) {
+		server, mockBandit := newServer()
+		mockBandit.On("RecordClick", mock.Anything, 1, 100, 2).Return(storage.ErrUnavailable)
+
+		w := get(server, "/c/"+tracking.NewSigner(secret).Sign(tracking.KindClick, time.Hour, 1, 100, 2))
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://shop.example.com/sale", w.Header().Get("Location"))
+	})
+
+	t.Run("impression token is not a click", func(t *testing.T) {
+		server, mockBandit := newServer()
+
+		w := get(server, "/c/"+tracking.NewSigner(secret).Sign(tracking.KindImpression, time.Hour, 1, 100, 2))
+		assert.Equal(t, http.StatusFound, w.Code)
+		mockBandit.AssertNotCalled(t, "RecordClick", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("unsafe stored landing goes to fallback", func(t *testing.T) {
+		_, err := catalog.CreateBanner(ctx, storage.Banner{ID: 101, Creative: storage.Creative{ClickURL: "javascript:alert(1)"}})
+		require.NoError(t, err)
+		server, mockBandit := newServer()
+		mockBandit.On("RecordClick", mock.Anything, 1, 101, 2).Return(nil)
+
+		w := get(server, "/c/"+tracking.NewSigner(secret).Sign(tracking.KindClick, time.Hour, 1, 101, 2))
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://example.com/", w.Header().Get("Location"))
+	})
+
+	t.Run("no landing and no fallback", func(t *testing.T) {
+		server := NewServer(new(MockBandit), catalog)
+
+		w := get(server, "/c/garbage")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
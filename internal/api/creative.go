@@ -0,0 +1,52 @@
+package api
+
+import (
+	"banner-rotation/internal/storage"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// creativeTemplate отрисовывает креатив: HTML-фрагмент вставляется как есть,
+// картинка оборачивается ссылкой, если у креатива есть click_url
+var creativeTemplate = template.Must(template.New("creative").Parse(
+	`{{if .HTML}}{{.HTML}}{{else}}` +
+		`{{if .ClickURL}}<a href="{{.ClickURL}}" target="_blank" rel="noopener">{{end}}` +
+		`<img src="{{.ImageURL}}" alt="{{.AltText}}"` +
+		`{{if .Width}} width="{{.Width}}"{{end}}{{if .Height}} height="{{.Height}}"{{end}}>` +
+		`{{if .ClickURL}}</a>{{end}}{{end}}`,
+))
+
+// renderData - креатив для шаблона; HTML помечен как доверенный,
+// так как его заводят через административное API
+type renderData struct {
+	Creative
+	HTML template.HTML
+}
+
+// renderBanner отдает креатив баннера готовой HTML-разметкой
+func (s *Server) renderBanner(c *gin.Context) {
+	id, ok := entityID(c)
+	if !ok {
+		return
+	}
+
+	banner, err := s.catalog.GetBanner(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if banner.HTML == "" && banner.ImageURL == "" {
+		_ = c.Error(fmt.Errorf("banner %d has no creative: %w", id, storage.ErrNotFound))
+		return
+	}
+
+	data := renderData{Creative: Creative(banner.Creative), HTML: template.HTML(banner.HTML)}
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := creativeTemplate.Execute(c.Writer, data); err != nil {
+		_ = c.Error(err)
+	}
+}
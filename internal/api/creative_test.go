@@ -0,0 +1,146 @@
+package api
+
+import (
+	"banner-rotation/internal/app"
+	"banner-rotation/internal/storage"
+	"banner-rotation/internal/storage/memory"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreativeEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockBandit := new(MockBandit)
+	store := memory.New()
+	server := NewServer(mockBandit, store)
+
+	do := func(method, url string, body interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, createRequest(t, method, url, body))
+		return w
+	}
+
+	creative := &Creative{
+		ImageURL: "https://cdn.example.com/728x90.png",
+		ClickURL: "https://example.com/landing?a=1&b=2",
+		Width:    728,
+		Height:   90,
+		AltText:  `"sale"`,
+	}
+
+	t.Run("create with creative", func(t *testing.T) {
+		w := do("POST", "/api/v1/banners", CreateBannerRequest{ID: 1, Description: "leaderboard", Creative: creative})
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		w = do("GET", "/api/v1/banners/1", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var banner BannerResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &banner))
+		assert.Equal(t, creative, banner.Creative)
+	})
+
+	t.Run("create - invalid url", func(t *testing.T) {
+		w := do("POST", "/api/v1/banners", CreateBannerRequest{
+			Description: "broken",
+			Creative:    &Creative{ImageURL: "not a url"},
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		for _, link := range []string{"javascript:alert(1)", "data:text/html,<script>alert(1)</script>", "ftp://example.com/a"} {
+			w = do("POST", "/api/v1/banners", CreateBannerRequest{
+				Description: "unsafe",
+				Creative:    &Creative{ClickURL: link},
+			})
+			assert.Equal(t, http.StatusBadRequest, w.Code, link)
+
+			w = do("POST", "/api/v1/banners", CreateBannerRequest{
+				Description: "unsafe",
+				Creative:    &Creative{ImageURL: link},
+			})
+			assert.Equal(t, http.StatusBadRequest, w.Code, link)
+		}
+	})
+
+	t.Run("choose with creative", func(t *testing.T) {
+		mockBandit.On("ChooseBanner", mock.Anything, 1, 1, storage.Visitor{}).Return(1, nil)
+
+		w := do("POST", "/api/v1/choose_banner", ChooseBannerRequest{SlotID: 1, GroupID: 1, WithCreative: true})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp ChooseBannerResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, ChooseBannerResponse{BannerID: 1, Creative: creative}, resp)
+
+		// Без флага креатив не запрашивается
+		w = do("POST", "/api/v1/choose_banner", ChooseBannerRequest{SlotID: 1, GroupID: 1})
+		assert.JSONEq(t, `{"banner_id": 1}`, w.Body.String())
+	})
+
+	t.Run("render image", func(t *testing.T) {
+		w := do("GET", "/api/v1/banners/1/render", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t,
+			`<a href="https://example.com/landing?a=1&amp;b=2" target="_blank" rel="noopener">`+
+				`<img src="https://cdn.example.com/728x90.png" alt="&#34;sale&#34;" width="728" height="90"></a>`,
+			w.Body.String())
+	})
+
+	t.Run("render html", func(t *testing.T) {
+		require.NoError(t, store.UpdateBanner(context.Background(), storage.Banner{
+			ID:       1,
+			Creative: storage.Creative{HTML: "<div>sale</div>"},
+		}))
+
+		w := do("GET", "/api/v1/banners/1/render", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "<div>sale</div>", w.Body.String())
+	})
+
+	t.Run("slot size mismatch", func(t *testing.T) {
+		w := do("POST", "/api/v1/slots", CreateSlotRequest{ID: 1, Description: "sidebar", Sizes: []Size{{Width: 300, Height: 250}}})
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var slot SlotResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &slot))
+		assert.Equal(t, []Size{{Width: 300, Height: 250}}, slot.Sizes)
+
+		mockBandit.On("AddBannerToSlot", mock.Anything, 1, 1).
+			Return(&app.SizeMismatchError{SlotID: 1, BannerID: 1, Size: storage.Size{Width: 728, Height: 90}})
+
+		w = do("POST", "/api/v1/banner_slot", AddBannerToSlotRequest{SlotID: 1, BannerID: 1})
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("slot resize - size mismatch", func(t *testing.T) {
+		mockBandit.On("UpdateSlot", mock.Anything, storage.Slot{ID: 1, Description: "sidebar", Sizes: []storage.Size{{Width: 160, Height: 600}}}).
+			Return(&app.SizeMismatchError{SlotID: 1, BannerID: 1, Size: storage.Size{Width: 300, Height: 250}})
+
+		w := do("PUT", "/api/v1/slots/1", UpdateSlotRequest{Description: "sidebar", Sizes: []Size{{Width: 160, Height: 600}}})
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), `"code":"size_mismatch"`)
+	})
+
+	t.Run("slot - invalid size", func(t *testing.T) {
+		w := do("POST", "/api/v1/slots", CreateSlotRequest{Description: "broken", Sizes: []Size{{Width: 0, Height: 90}}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("render without creative", func(t *testing.T) {
+		w := do("POST", "/api/v1/banners", CreateBannerRequest{ID: 2, Description: "plain"})
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		assert.Equal(t, http.StatusNotFound, do("GET", "/api/v1/banners/2/render", nil).Code)
+		assert.Equal(t, http.StatusNotFound, do("GET", "/api/v1/banners/100/render", nil).Code)
+	})
+}
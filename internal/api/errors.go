@@ -0,0 +1,93 @@
+package api
+
+import (
+	"banner-rotation/internal/auth"
+	"banner-rotation/internal/ratelimit"
+	"banner-rotation/internal/storage"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Машиночитаемые коды ошибок по видам. Ошибки предметной области
+// могут уточнять код, например no_banners или size_mismatch.
+const (
+	codeInvalidRequest = "invalid_request"
+	codeNotFound       = "not_found"
+	codeConflict       = "conflict"
+	codeInvalid        = "invalid"
+	codeUnavailable    = "unavailable"
+	codeUnauthorized   = "unauthorized"
+	codeForbidden      = "forbidden"
+	codeRateLimited    = "rate_limited"
+	codeInternal       = "internal"
+)
+
+// ErrorResponse тело ответа с ошибкой. Code стабилен и предназначен
+// для обработки клиентом, Error - описание для человека.
+type ErrorResponse struct {
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+// coder - ошибка, уточняющая машиночитаемый код
+type coder interface {
+	Code() string
+}
+
+// errorHandler отвечает клиенту по последней ошибке, которую обработчик
+// передал через c.Error, если сам обработчик ответ не записал
+func errorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		writeError(c)
+	}
+}
+
+// writeError отвечает по последней ошибке обработчика, если ответ еще не записан
+func writeError(c *gin.Context) {
+	last := c.Errors.Last()
+	if last == nil || c.Writer.Written() {
+		return
+	}
+
+	if last.IsType(gin.ErrorTypeBind) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, ErrorResponse{Code: codeInvalidRequest, Error: last.Error()})
+		return
+	}
+	status, resp := errorResponse(last.Err)
+	c.AbortWithStatusJSON(status, resp)
+}
+
+// errorResponse сопоставляет ошибке HTTP-статус и тело ответа
+func errorResponse(err error) (int, ErrorResponse) {
+	status, code := http.StatusInternalServerError, codeInternal
+	switch {
+	case errors.Is(err, auth.ErrUnauthenticated):
+		status, code = http.StatusUnauthorized, codeUnauthorized
+	case errors.Is(err, auth.ErrForbidden):
+		status, code = http.StatusForbidden, codeForbidden
+	case errors.Is(err, ratelimit.ErrLimited):
+		status, code = http.StatusTooManyRequests, codeRateLimited
+	case errors.Is(err, storage.ErrNotFound):
+		status, code = http.StatusNotFound, codeNotFound
+	case errors.Is(err, storage.ErrConflict):
+		status, code = http.StatusConflict, codeConflict
+	case errors.Is(err, storage.ErrInvalid):
+		status, code = http.StatusUnprocessableEntity, codeInvalid
+	case storage.IsUnavailable(err):
+		status, code = http.StatusServiceUnavailable, codeUnavailable
+	}
+
+	var domain coder
+	if status != http.StatusInternalServerError && errors.As(err, &domain) {
+		code = domain.Code()
+	}
+	return status, ErrorResponse{Code: code, Error: err.Error()}
+}
+
+// badRequest передает обработчику ошибок ошибку разбора запроса
+func badRequest(c *gin.Context, err error) {
+	_ = c.Error(err).SetType(gin.ErrorTypeBind)
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"banner-rotation/internal/app"
+	"banner-rotation/internal/ratelimit"
+	"banner-rotation/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"not found", fmt.Errorf("banner 1: %w", storage.ErrNotFound), http.StatusNotFound, "not_found"},
+		{"no banners", fmt.Errorf("%w %d", app.ErrNoBanners, 1), http.StatusNotFound, "no_banners"},
+		{"conflict", storage.ErrConflict, http.StatusConflict, "conflict"},
+		{"campaign stopped", fmt.Errorf("campaign 1: %w", app.ErrCampaignStopped), http.StatusConflict, "campaign_stopped"},
+		{"show cap reached", fmt.Errorf("%w 1: banner 2", app.ErrShowCapReached), http.StatusConflict, "show_cap_reached"},
+		{"no matching group", app.ErrNoMatchingGroup, http.StatusUnprocessableEntity, "no_matching_group"},
+		{"size mismatch", fmt.Errorf("wrapped: %w", &app.SizeMismatchError{SlotID: 1, BannerID: 2}), http.StatusUnprocessableEntity, "size_mismatch"},
+		{"unavailable", fmt.Errorf("query: %w", context.DeadlineExceeded), http.StatusServiceUnavailable, "unavailable"},
+		{"rate limited", ratelimit.ErrLimited, http.StatusTooManyRequests, "rate_limited"},
+		{"internal", errors.New("boom"), http.StatusInternalServerError, "internal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := errorResponse(tt.err)
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.code, resp.Code)
+			assert.Equal(t, tt.err.Error(), resp.Error)
+		})
+	}
+}
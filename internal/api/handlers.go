@@ -1,7 +1,13 @@
 package api
 
 import (
+	"banner-rotation/internal/storage"
+	"context"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,33 +24,80 @@ type RemoveBannerFromSlotRequest struct {
 	BannerID int `json:"banner_id" binding:"required"`
 }
 
-// ChooseBannerRequest запрос на выбор баннера
+// ChooseBannerRequest запрос на выбор баннера. Вместо group_id можно
+// передать атрибуты пользователя, тогда группа подбирается по правилам.
 type ChooseBannerRequest struct {
-	SlotID  int `json:"slot_id" binding:"required"`
-	GroupID int `json:"group_id" binding:"required"`
+	SlotID  int             `json:"slot_id" binding:"required"`
+	GroupID int             `json:"group_id" binding:"required_without=User"`
+	User    *UserAttributes `json:"user"`
+	// WithCreative - вернуть вместе с ID креатив баннера
+	WithCreative bool `json:"with_creative"`
+	// Country, Device и OS - контекст показа для ограничений баннеров
+	Country string `json:"country"`
+	Device  string `json:"device" binding:"omitempty,oneof=desktop mobile tablet"`
+	OS      string `json:"os"`
 }
 
-// ChooseBannerResponse ответ с выбранным баннером
+// ChooseBannerResponse ответ с выбранным баннером. GroupID заполняется,
+// если группа подобрана по атрибутам пользователя.
 type ChooseBannerResponse struct {
-	BannerID int `json:"banner_id"`
+	BannerID int       `json:"banner_id"`
+	GroupID  int       `json:"group_id,omitempty"`
+	Creative *Creative `json:"creative,omitempty"`
+	// ClickURL - подписанная ссылка перехода, которая засчитывает клик
+	ClickURL string `json:"click_url,omitempty"`
+	// ImpressionURL - пиксель, который засчитывает показ в режиме резервирования
+	ImpressionURL string `json:"impression_url,omitempty"`
 }
 
-// RegisterClickRequest запрос на регистрацию клика
+// RegisterClickRequest запрос на регистрацию клика. Как и при выборе
+// баннера, вместо group_id можно передать атрибуты пользователя.
 type RegisterClickRequest struct {
-	SlotID   int `json:"slot_id" binding:"required"`
-	BannerID int `json:"banner_id" binding:"required"`
-	GroupID  int `json:"group_id" binding:"required"`
+	SlotID   int             `json:"slot_id" binding:"required"`
+	BannerID int             `json:"banner_id" binding:"required"`
+	GroupID  int             `json:"group_id" binding:"required_without=User"`
+	User     *UserAttributes `json:"user"`
+}
+
+// SlotStatsRequest параметры запроса статистики слота
+type SlotStatsRequest struct {
+	GroupID int       `form:"group_id" binding:"required"`
+	From    time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To      time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// BannerStatsResponse статистика одного баннера
+type BannerStatsResponse struct {
+	BannerID int     `json:"banner_id"`
+	Shows    int     `json:"shows"`
+	Clicks   int     `json:"clicks"`
+	CTR      float64 `json:"ctr"`
+	CILow    float64 `json:"ci_low"`
+	CIHigh   float64 `json:"ci_high"`
+	// Score - null, если баннер еще не показывали и он выбирается вне очереди
+	Score *float64 `json:"score"`
+	Share float64  `json:"share"`
+}
+
+// SlotStatsResponse ответ со статистикой слота
+type SlotStatsResponse struct {
+	SlotID     int                   `json:"slot_id"`
+	GroupID    int                   `json:"group_id"`
+	From       *time.Time            `json:"from,omitempty"`
+	To         *time.Time            `json:"to,omitempty"`
+	TotalShows int                   `json:"total_shows"`
+	Banners    []BannerStatsResponse `json:"banners"`
 }
 
 func (s *Server) addBannerToSlot(c *gin.Context) {
 	var req AddBannerToSlotRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(c, err)
 		return
 	}
 
 	if err := s.bandit.AddBannerToSlot(c.Request.Context(), req.SlotID, req.BannerID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
@@ -54,12 +107,12 @@ func (s *Server) addBannerToSlot(c *gin.Context) {
 func (s *Server) removeBannerFromSlot(c *gin.Context) {
 	var req RemoveBannerFromSlotRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(c, err)
 		return
 	}
 
 	if err := s.bandit.RemoveBannerFromSlot(c.Request.Context(), req.SlotID, req.BannerID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
@@ -69,30 +122,137 @@ func (s *Server) removeBannerFromSlot(c *gin.Context) {
 func (s *Server) chooseBanner(c *gin.Context) {
 	var req ChooseBannerRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(c, err)
+		return
+	}
+
+	groupID, ok := s.resolveGroup(c, req.GroupID, req.User)
+	if !ok {
 		return
 	}
 
-	bannerID, err := s.bandit.ChooseBanner(c.Request.Context(), req.SlotID, req.GroupID)
+	visitor := storage.Visitor{Country: req.Country, Device: req.Device, OS: req.OS}
+	choose := s.bandit.ChooseBanner
+	if s.reservations != nil {
+		choose = s.bandit.ReserveBanner
+	}
+	bannerID, err := choose(c.Request.Context(), req.SlotID, groupID, visitor)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, ChooseBannerResponse{BannerID: bannerID})
+	var creative *Creative
+	if req.WithCreative {
+		if creative, err = s.bannerCreative(c.Request.Context(), bannerID); err != nil {
+			_ = c.Error(err)
+			return
+		}
+	}
+
+	resp := ChooseBannerResponse{
+		BannerID:      bannerID,
+		ClickURL:      s.clickURL(req.SlotID, bannerID, groupID),
+		ImpressionURL: s.impressionURL(req.SlotID, bannerID, groupID),
+		Creative:      creative,
+	}
+	if groupID != req.GroupID {
+		resp.GroupID = groupID
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// bannerCreative читает креатив выбранного баннера для with_creative.
+// Баннер может быть в ротации, не будучи заведен в справочнике (хранилище
+// redis), - тогда креатива нет.
+func (s *Server) bannerCreative(ctx context.Context, bannerID int) (*Creative, error) {
+	banner, err := s.catalog.GetBanner(ctx, bannerID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	return newCreative(banner.Creative), nil
 }
 
 func (s *Server) registerClick(c *gin.Context) {
 	var req RegisterClickRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(c, err)
+		return
+	}
+
+	groupID, ok := s.resolveGroup(c, req.GroupID, req.User)
+	if !ok {
 		return
 	}
 
-	if err := s.bandit.RecordClick(c.Request.Context(), req.SlotID, req.BannerID, req.GroupID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := s.bandit.RecordClick(c.Request.Context(), req.SlotID, req.BannerID, groupID); err != nil {
+		_ = c.Error(err)
 		return
 	}
 
 	c.Status(http.StatusOK)
 }
+
+func (s *Server) getSlotStats(c *gin.Context) {
+	slotID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		badRequest(c, errors.New("invalid slot id"))
+		return
+	}
+
+	var req SlotStatsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	// Если задана только одна граница, вторая открыта
+	from, to := req.From, req.To
+	if !from.IsZero() || !to.IsZero() {
+		if from.IsZero() {
+			from = time.Unix(0, 0)
+		}
+		if to.IsZero() {
+			to = time.Now()
+		}
+		if !from.Before(to) {
+			badRequest(c, errors.New("from must be before to"))
+			return
+		}
+	}
+
+	report, err := s.bandit.GetSlotStats(c.Request.Context(), slotID, req.GroupID, from, to)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	resp := SlotStatsResponse{
+		SlotID:     report.SlotID,
+		GroupID:    report.GroupID,
+		TotalShows: report.TotalShows,
+		Banners:    make([]BannerStatsResponse, 0, len(report.Banners)),
+	}
+	if !report.From.IsZero() || !report.To.IsZero() {
+		resp.From, resp.To = &report.From, &report.To
+	}
+	for _, b := range report.Banners {
+		banner := BannerStatsResponse{
+			BannerID: b.BannerID,
+			Shows:    b.Shows,
+			Clicks:   b.Clicks,
+			CTR:      b.CTR,
+			CILow:    b.CILow,
+			CIHigh:   b.CIHigh,
+			Share:    b.Share,
+		}
+		if b.Score != math.MaxFloat64 {
+			score := b.Score
+			banner.Score = &score
+		}
+		resp.Banners = append(resp.Banners, banner)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readinessTimeout ограничивает время одной проверки готовности
+const readinessTimeout = 2 * time.Second
+
+// Статусы ответа /readyz
+const (
+	statusOK          = "ok"
+	statusUnavailable = "unavailable"
+	statusDraining    = "draining"
+)
+
+// readinessCheck - проверка зависимости, от которой зависит готовность сервиса
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// HealthResponse - ответ /healthz и /readyz. Checks содержит результат
+// каждой проверки готовности по имени зависимости.
+type HealthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckStatus `json:"checks,omitempty"`
+}
+
+// CheckStatus - результат проверки одной зависимости
+type CheckStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// AddReadinessCheck добавляет зависимость, без которой сервис не готов
+// принимать запросы. Вызывается до Start.
+func (s *Server) AddReadinessCheck(name string, check func(ctx context.Context) error) {
+	s.checks = append(s.checks, readinessCheck{name: name, check: check})
+}
+
+// Drain переводит /readyz в состояние draining, чтобы балансировщик
+// перестал направлять запросы до остановки сервера
+func (s *Server) Drain() {
+	s.draining.Store(true)
+}
+
+// healthz отвечает, пока процесс жив
+func (s *Server) healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthResponse{Status: statusOK})
+}
+
+// readyz выполняет проверки готовности параллельно и отвечает 503,
+// если хотя бы одна не прошла или сервер останавливается
+func (s *Server) readyz(c *gin.Context) {
+	if s.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, HealthResponse{Status: statusDraining})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	results := make([]CheckStatus, len(s.checks))
+	var wg sync.WaitGroup
+	for i, rc := range s.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = CheckStatus{Status: statusOK}
+			if err := rc.check(ctx); err != nil {
+				results[i] = CheckStatus{Status: statusUnavailable, Error: err.Error()}
+			}
+		}()
+	}
+	wg.Wait()
+
+	status, resp := http.StatusOK, HealthResponse{Status: statusOK, Checks: make(map[string]CheckStatus, len(results))}
+	for i, result := range results {
+		resp.Checks[s.checks[i].name] = result
+		if result.Status != statusOK {
+			status, resp.Status = http.StatusServiceUnavailable, statusUnavailable
+		}
+	}
+	c.JSON(status, resp)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"banner-rotation/internal/storage/memory"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	get := func(server *Server, url string) (*httptest.ResponseRecorder, HealthResponse) {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		var resp HealthResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w, resp
+	}
+
+	t.Run("healthz", func(t *testing.T) {
+		server := NewServer(new(MockBandit), memory.New())
+		server.AddReadinessCheck("storage", func(ctx context.Context) error { return errors.New("down") })
+
+		w, resp := get(server, "/healthz")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "ok", resp.Status)
+	})
+
+	t.Run("ready", func(t *testing.T) {
+		server := NewServer(new(MockBandit), memory.New())
+		server.AddReadinessCheck("storage", func(ctx context.Context) error { return nil })
+
+		w, resp := get(server, "/readyz")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, HealthResponse{Status: "ok", Checks: map[string]CheckStatus{
+			"storage":     {Status: "ok"},
+			"group_rules": {Status: "ok"},
+		}}, resp)
+	})
+
+	t.Run("dependency down", func(t *testing.T) {
+		server := NewServer(new(MockBandit), memory.New())
+		server.AddReadinessCheck("storage", func(ctx context.Context) error { return nil })
+		server.AddReadinessCheck("kafka", func(ctx context.Context) error { return errors.New("no brokers") })
+
+		w, resp := get(server, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "unavailable", resp.Status)
+		assert.Equal(t, CheckStatus{Status: "ok"}, resp.Checks["storage"])
+		assert.Equal(t, CheckStatus{Status: "unavailable", Error: "no brokers"}, resp.Checks["kafka"])
+	})
+
+	t.Run("draining", func(t *testing.T) {
+		server := NewServer(new(MockBandit), memory.New())
+		server.Drain()
+
+		w, resp := get(server, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "draining", resp.Status)
+
+		w, _ = get(server, "/healthz")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"banner-rotation/internal/app"
+	"banner-rotation/internal/idempotency"
+	"banner-rotation/internal/storage"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdempotencyKeyHeader - заголовок с ключом идемпотентности запроса
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader выставляется в ответах, повторенных по ключу
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLen = 255
+	// idempotencyLockTTL - сколько ключ занят выполняющимся запросом. Если
+	// экземпляр упадет, не дописав ответ, ключ освободится через это время.
+	idempotencyLockTTL = time.Minute
+)
+
+var (
+	errIdempotencyInProgress = app.NewError("idempotency_key_in_progress", storage.ErrConflict,
+		"request with this idempotency key is in progress")
+	errIdempotencyKeyReused = app.NewError("idempotency_key_reused", storage.ErrInvalid,
+		"idempotency key was used with a different request")
+)
+
+// bodyRecorder сохраняет копию тела ответа
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotent повторяет первый ответ на запрос с тем же Idempotency-Key, не
+// выполняя обработчик снова. Ответы 5xx не сохраняются: такой запрос можно
+// повторить с тем же ключом. Запросы без ключа обрабатываются как обычно.
+func (s *Server) idempotent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			badRequest(c, errors.New("idempotency key is too long"))
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			badRequest(c, err)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Ключ действует в пределах клиента и маршрута: другой клиент с тем же
+		// ключом не получит чужой ответ. Отпечаток ловит повтор ключа с другим телом.
+		ctx := c.Request.Context()
+		key = clientID(c) + " " + c.Request.Method + " " + c.FullPath() + " " + key
+		fingerprint := fingerprintOf(body)
+
+		rec, created, err := s.idempotency.Begin(ctx, key, fingerprint, idempotencyLockTTL)
+		if err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		if !created {
+			switch {
+			case rec.Fingerprint != fingerprint:
+				_ = c.Error(errIdempotencyKeyReused)
+			case rec.Response == nil:
+				_ = c.Error(errIdempotencyInProgress)
+			default:
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(rec.Response.Status, rec.Response.ContentType, rec.Response.Body)
+			}
+			c.Abort()
+			return
+		}
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		writeError(c)
+
+		// Клиент мог отключиться, не дождавшись ответа, но его повтор
+		// должен получить сохраненный ответ
+		ctx = context.WithoutCancel(ctx)
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			err = s.idempotency.Release(ctx, key)
+		} else {
+			resp := idempotency.Response{
+				Status:      status,
+				ContentType: recorder.Header().Get("Content-Type"),
+				Body:        recorder.body.Bytes(),
+			}
+			err = s.idempotency.Complete(ctx, key, resp, s.idempotencyTTL)
+		}
+		if err != nil {
+			log.Printf("Failed to save idempotent response: %v", err)
+		}
+	}
+}
+
+// fingerprintOf возвращает отпечаток тела запроса
+func fingerprintOf(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
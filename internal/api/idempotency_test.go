@@ -0,0 +1,116 @@
+package api
+
+import (
+	"banner-rotation/internal/idempotency"
+	"banner-rotation/internal/storage"
+	"banner-rotation/internal/storage/memory"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockBandit := new(MockBandit)
+	server := NewServer(mockBandit, memory.New())
+
+	do := func(url, key string, body interface{}) *httptest.ResponseRecorder {
+		req := createRequest(t, "POST", url, body)
+		req.RemoteAddr = "192.0.2.1:1234"
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("choose_banner is replayed", func(t *testing.T) {
+		mockBandit.On("ChooseBanner", mock.Anything, 1, 1, storage.Visitor{}).Return(100, nil).Once()
+
+		first := do("/api/v1/choose_banner", "k1", ChooseBannerRequest{SlotID: 1, GroupID: 1})
+		require.Equal(t, http.StatusOK, first.Code)
+		assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+
+		second := do("/api/v1/choose_banner", "k1", ChooseBannerRequest{SlotID: 1, GroupID: 1})
+		require.Equal(t, http.StatusOK, second.Code)
+		assert.Equal(t, "true", second.Header().Get(IdempotentReplayedHeader))
+		assert.Equal(t, first.Body.String(), second.Body.String())
+		assert.Equal(t, first.Header().Get("Content-Type"), second.Header().Get("Content-Type"))
+
+		mockBandit.AssertNumberOfCalls(t, "ChooseBanner", 1)
+	})
+
+	t.Run("register_click is counted once", func(t *testing.T) {
+		mockBandit.On("RecordClick", mock.Anything, 1, 100, 1).Return(nil).Once()
+
+		req := RegisterClickRequest{SlotID: 1, BannerID: 100, GroupID: 1}
+		assert.Equal(t, http.StatusOK, do("/api/v1/register_click", "k1", req).Code)
+		assert.Equal(t, http.StatusOK, do("/api/v1/register_click", "k1", req).Code)
+		mockBandit.AssertNumberOfCalls(t, "RecordClick", 1)
+	})
+
+	t.Run("key reused with another request", func(t *testing.T) {
+		w := do("/api/v1/choose_banner", "k1", ChooseBannerRequest{SlotID: 2, GroupID: 1})
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+		var resp ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "idempotency_key_reused", resp.Code)
+	})
+
+	t.Run("client errors are replayed, server errors are not", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, do("/api/v1/choose_banner", "k2", ChooseBannerRequest{}).Code)
+		w := do("/api/v1/choose_banner", "k2", ChooseBannerRequest{})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "true", w.Header().Get(IdempotentReplayedHeader))
+
+		mockBandit.On("ChooseBanner", mock.Anything, 3, 1, storage.Visitor{}).Return(0, errors.New("boom")).Once()
+		mockBandit.On("ChooseBanner", mock.Anything, 3, 1, storage.Visitor{}).Return(300, nil).Once()
+		assert.Equal(t, http.StatusInternalServerError, do("/api/v1/choose_banner", "k3", ChooseBannerRequest{SlotID: 3, GroupID: 1}).Code)
+		assert.Equal(t, http.StatusOK, do("/api/v1/choose_banner", "k3", ChooseBannerRequest{SlotID: 3, GroupID: 1}).Code)
+	})
+
+	t.Run("request in progress", func(t *testing.T) {
+		store := idempotency.NewMemoryStore()
+		server.SetIdempotencyStore(store, time.Hour)
+
+		body := []byte(`{"slot_id":1,"group_id":1}`)
+		_, _, err := store.Begin(context.Background(), "ip:192.0.2.1 POST /api/v1/choose_banner k4", fingerprintOf(body), time.Hour)
+		require.NoError(t, err)
+
+		w := do("/api/v1/choose_banner", "k4", json.RawMessage(body))
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("key is scoped to the client", func(t *testing.T) {
+		mockBandit.On("ChooseBanner", mock.Anything, 5, 1, storage.Visitor{}).Return(500, nil).Twice()
+
+		req := ChooseBannerRequest{SlotID: 5, GroupID: 1}
+		assert.Equal(t, http.StatusOK, do("/api/v1/choose_banner", "k5", req).Code)
+
+		other := createRequest(t, "POST", "/api/v1/choose_banner", req)
+		other.RemoteAddr = "198.51.100.7:1234"
+		other.Header.Set(IdempotencyKeyHeader, "k5")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, other)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+	})
+
+	t.Run("without key every request is executed", func(t *testing.T) {
+		mockBandit.On("ChooseBanner", mock.Anything, 4, 1, storage.Visitor{}).Return(400, nil).Twice()
+		assert.Equal(t, http.StatusOK, do("/api/v1/choose_banner", "", ChooseBannerRequest{SlotID: 4, GroupID: 1}).Code)
+		assert.Equal(t, http.StatusOK, do("/api/v1/choose_banner", "", ChooseBannerRequest{SlotID: 4, GroupID: 1}).Code)
+	})
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"banner-rotation/internal/storage"
+	"banner-rotation/internal/tracking"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// transparentGIF - прозрачная картинка 1x1, которую отдает пиксель показа
+var transparentGIF = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// impressionURL возвращает пиксель подтверждения показа или пустую строку,
+// если показы не резервируются
+func (s *Server) impressionURL(slotID, bannerID, groupID int) string {
+	if s.tracker == nil || s.reservations == nil {
+		return ""
+	}
+	return strings.TrimSuffix(s.trackingBaseURL, "/") + "/i/" +
+		s.tracker.Sign(tracking.KindImpression, s.reservationTTL, slotID, bannerID, groupID) + ".gif"
+}
+
+// impression подтверждает зарезервированный показ и всегда отдает
+// прозрачный GIF, чтобы страница не показывала битую картинку
+func (s *Server) impression(c *gin.Context) {
+	token, ok := strings.CutSuffix(c.Param("token"), ".gif")
+	if !ok {
+		_ = c.Error(fmt.Errorf("impression pixel %q: %w", c.Param("token"), storage.ErrNotFound))
+		return
+	}
+	if s.tracker != nil && s.reservations != nil {
+		s.confirmShow(c.Request.Context(), token)
+	}
+
+	c.Header("Cache-Control", "no-store, no-cache, must-revalidate")
+	c.Data(http.StatusOK, "image/gif", transparentGIF)
+}
+
+// confirmShow записывает показ по токену пикселя один раз. Поврежденный,
+// устаревший и повторно загруженный пиксель показ не засчитывает.
+func (s *Server) confirmShow(ctx context.Context, token string) {
+	claims, err := s.tracker.Parse(tracking.KindImpression, token)
+	if err != nil {
+		return
+	}
+
+	key := fmt.Sprintf("impression %d.%d.%d.%d.%s",
+		claims.SlotID, claims.BannerID, claims.GroupID, claims.ExpiresAt.Unix(), claims.Nonce)
+	_, created, err := s.reservations.Begin(ctx, key, "", time.Until(claims.ExpiresAt))
+	if err != nil {
+		log.Printf("Failed to confirm show of banner %d in slot %d: %v", claims.BannerID, claims.SlotID, err)
+		return
+	}
+	if !created {
+		return
+	}
+
+	if err := s.bandit.ConfirmShow(ctx, claims.SlotID, claims.BannerID, claims.GroupID); err != nil {
+		log.Printf("Failed to confirm show of banner %d in slot %d: %v", claims.BannerID, claims.SlotID, err)
+		// Пиксель можно будет загрузить повторно
+		if err := s.reservations.Release(context.WithoutCancel(ctx), key); err != nil {
+			log.Printf("Failed to release show reservation: %v", err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"banner-rotation/internal/app"
+	"banner-rotation/internal/idempotency"
+	"banner-rotation/internal/storage"
+	"banner-rotation/internal/storage/memory"
+	"banner-rotation/internal/tracking"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImpressionPixel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	secret := []byte("secret")
+	signer := tracking.NewSigner(secret)
+	newServer := func() (*Server, *MockBandit) {
+		mockBandit := new(MockBandit)
+		server := NewServer(mockBandit, memory.New())
+		server.SetTracking(signer, "https://ads.example.com", "", time.Hour)
+		server.SetShowReservations(idempotency.NewMemoryStore(), time.Hour)
+		return server, mockBandit
+	}
+	pixel := func(server *Server, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+	assertGIF := func(t *testing.T, w *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/gif", w.Header().Get("Content-Type"))
+		assert.Equal(t, transparentGIF, w.Body.Bytes())
+	}
+
+	t.Run("choose reserves and pixel confirms once", func(t *testing.T) {
+		server, mockBandit := newServer()
+		mockBandit.On("ReserveBanner", mock.Anything, 1, 2, storage.Visitor{}).Return(100, nil)
+		mockBandit.On("ConfirmShow", mock.Anything, 1, 100, 2).Return(nil).Once()
+
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, createRequest(t, "POST", "/api/v1/choose_banner", ChooseBannerRequest{SlotID: 1, GroupID: 2}))
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp ChooseBannerResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		mockBandit.AssertNotCalled(t, "ChooseBanner", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+		path, ok := strings.CutPrefix(resp.ImpressionURL, "https://ads.example.com")
+		require.True(t, ok, resp.ImpressionURL)
+		assertGIF(t, pixel(server, path))
+		assertGIF(t, pixel(server, path))
+		mockBandit.AssertExpectations(t)
+	})
+
+	t.Run("batch reserves", func(t *testing.T) {
+		server, mockBandit := newServer()
+		mockBandit.On("ReserveBanners", mock.Anything, []app.SlotChoice{{SlotID: 1, GroupID: 2}}, storage.Visitor{}).
+			Return([]app.SlotDecision{{SlotID: 1, GroupID: 2, BannerID: 100}})
+
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, createRequest(t, "POST", "/api/v1/choose_banners", ChooseBannersRequest{
+			Slots: []SlotChoiceRequest{{SlotID: 1, GroupID: 2}},
+		}))
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp ChooseBannersResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.True(t, strings.HasPrefix(resp.Results[0].ImpressionURL, "https://ads.example.com/i/"))
+	})
+
+	t.Run("invalid tokens are not counted", func(t *testing.T) {
+		server, mockBandit := newServer()
+
+		for _, token := range []string{
+			"garbage",
+			signer.Sign(tracking.KindImpression, -time.Minute, 1, 100, 2),
+			signer.Sign(tracking.KindClick, time.Hour, 1, 100, 2),
+			tracking.NewSigner([]byte("other")).Sign(tracking.KindImpression, time.Hour, 1, 100, 2),
+		} {
+			assertGIF(t, pixel(server, "/i/"+token+".gif"))
+		}
+		mockBandit.AssertNotCalled(t, "ConfirmShow", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("failed confirmation can be retried", func(t *testing.T) {
+		server, mockBandit := newServer()
+		mockBandit.On("ConfirmShow", mock.Anything, 1, 100, 2).Return(storage.ErrUnavailable).Once()
+		mockBandit.On("ConfirmShow", mock.Anything, 1, 100, 2).Return(nil).Once()
+
+		path := "/i/" + signer.Sign(tracking.KindImpression, time.Hour, 1, 100, 2) + ".gif"
+		assertGIF(t, pixel(server, path))
+		assertGIF(t, pixel(server, path))
+		assertGIF(t, pixel(server, path))
+		mockBandit.AssertNumberOfCalls(t, "ConfirmShow", 2)
+	})
+
+	t.Run("without gif extension", func(t *testing.T) {
+		server, _ := newServer()
+		assert.Equal(t, http.StatusNotFound, pixel(server, "/i/"+signer.Sign(tracking.KindImpression, time.Hour, 1, 100, 2)).Code)
+	})
+
+	t.Run("reservations disabled", func(t *testing.T) {
+		mockBandit := new(MockBandit)
+		mockBandit.On("ChooseBanner", mock.Anything, 1, 2, storage.Visitor{}).Return(100, nil)
+		server := NewServer(mockBandit, memory.New())
+		server.SetTracking(signer, "https://ads.example.com", "", time.Hour)
+
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, createRequest(t, "POST", "/api/v1/choose_banner", ChooseBannerRequest{SlotID: 1, GroupID: 2}))
+		var resp ChooseBannerResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Empty(t, resp.ImpressionURL)
+		assert.NotEmpty(t, resp.ClickURL)
+
+		assertGIF(t, pixel(server, "/i/"+signer.Sign(tracking.KindImpression, time.Hour, 1, 100, 2)+".gif"))
+		mockBandit.AssertNotCalled(t, "ConfirmShow", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,18 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPISpec - спецификация OpenAPI 3 всех маршрутов /api/v1. При изменении
+// маршрутов или типов запросов ее нужно обновить, иначе упадет TestOpenAPISpec.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+func (s *Server) openAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", openAPISpec)
+}
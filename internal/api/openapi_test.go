@@ -0,0 +1,155 @@
+package api
+
+import (
+	"banner-rotation/internal/storage/memory"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openAPISchemas - типы API, описанные в components.schemas спецификации
+var openAPISchemas = map[string]interface{}{
+	"ErrorResponse":               ErrorResponse{},
+	"AddBannerToSlotRequest":      AddBannerToSlotRequest{},
+	"RemoveBannerFromSlotRequest": RemoveBannerFromSlotRequest{},
+	"UserAttributes":              UserAttributes{},
+	"ChooseBannerRequest":         ChooseBannerRequest{},
+	"ChooseBannerResponse":        ChooseBannerResponse{},
+	"ChooseBannersRequest":        ChooseBannersRequest{},
+	"SlotChoiceRequest":           SlotChoiceRequest{},
+	"ChooseBannersResponse":       ChooseBannersResponse{},
+	"SlotChoiceResponse":          SlotChoiceResponse{},
+	"RegisterClickRequest":        RegisterClickRequest{},
+	"BannerStatsResponse":         BannerStatsResponse{},
+	"SlotStatsResponse":           SlotStatsResponse{},
+	"Creative":                    Creative{},
+	"CreateBannerRequest":         CreateBannerRequest{},
+	"UpdateBannerRequest":         UpdateBannerRequest{},
+	"BannerResponse":              BannerResponse{},
+	"Size":                        Size{},
+	"CreateSlotRequest":           CreateSlotRequest{},
+	"UpdateSlotRequest":           UpdateSlotRequest{},
+	"SlotResponse":                SlotResponse{},
+	"ReplaceSlotBannersRequest":   ReplaceSlotBannersRequest{},
+	"UpdateSlotBannersRequest":    UpdateSlotBannersRequest{},
+	"SlotBannersResponse":         SlotBannersResponse{},
+	"Targeting":                   Targeting{},
+	"GroupRule":                   GroupRule{},
+	"CreateGroupRequest":          CreateGroupRequest{},
+	"UpdateGroupRequest":          UpdateGroupRequest{},
+	"GroupResponse":               GroupResponse{},
+	"CreateAdvertiserRequest":     CreateAdvertiserRequest{},
+	"UpdateAdvertiserRequest":     UpdateAdvertiserRequest{},
+	"AdvertiserResponse":          AdvertiserResponse{},
+	"CreateCampaignRequest":       CreateCampaignRequest{},
+	"UpdateCampaignRequest":       UpdateCampaignRequest{},
+	"CampaignResponse":            CampaignResponse{},
+}
+
+type openAPIDoc struct {
+	OpenAPI    string                                `json:"openapi"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+			Required   []string                   `json:"required"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := NewServer(new(MockBandit), memory.New())
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var doc openAPIDoc
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.True(t, strings.HasPrefix(doc.OpenAPI, "3."))
+
+	t.Run("every route is described", func(t *testing.T) {
+		param := regexp.MustCompile(`:(\w+)`)
+		routes := 0
+		for _, route := range server.router.Routes() {
+			path, ok := strings.CutPrefix(route.Path, "/api/v1")
+			if !ok {
+				continue
+			}
+			routes++
+			path = param.ReplaceAllString(path, "{$1}")
+			assert.Contains(t, doc.Paths[path], strings.ToLower(route.Method), "%s %s", route.Method, route.Path)
+		}
+
+		operations := 0
+		for _, methods := range doc.Paths {
+			operations += len(methods)
+		}
+		assert.Equal(t, routes, operations, "spec describes routes that do not exist")
+	})
+
+	t.Run("schemas match types", func(t *testing.T) {
+		for name, schema := range doc.Components.Schemas {
+			value, ok := openAPISchemas[name]
+			if !assert.True(t, ok, "unknown schema %s", name) {
+				continue
+			}
+
+			fields, required := jsonFields(reflect.TypeOf(value))
+			properties := make([]string, 0, len(schema.Properties))
+			for property := range schema.Properties {
+				properties = append(properties, property)
+			}
+			assert.ElementsMatch(t, fields, properties, name)
+			if strings.HasSuffix(name, "Request") {
+				assert.ElementsMatch(t, required, schema.Required, name)
+			}
+		}
+		for name := range openAPISchemas {
+			assert.Contains(t, doc.Components.Schemas, name)
+		}
+	})
+
+	t.Run("references resolve", func(t *testing.T) {
+		refs := regexp.MustCompile(`"\$ref":\s*"#/components/schemas/(\w+)"`).FindAllSubmatch(w.Body.Bytes(), -1)
+		require.NotEmpty(t, refs)
+		for _, ref := range refs {
+			assert.Contains(t, doc.Components.Schemas, string(ref[1]))
+		}
+	})
+}
+
+// jsonFields возвращает имена полей типа в JSON, раскрывая встроенные
+// структуры, и поля с binding:"required"
+func jsonFields(typ reflect.Type) (fields, required []string) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous {
+			embedded, embeddedRequired := jsonFields(field.Type)
+			fields = append(fields, embedded...)
+			required = append(required, embeddedRequired...)
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields = append(fields, name)
+		if slices.Contains(strings.Split(field.Tag.Get("binding"), ","), "required") {
+			required = append(required, name)
+		}
+	}
+	return fields, required
+}
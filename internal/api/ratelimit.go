@@ -0,0 +1,47 @@
+package api
+
+import (
+	"banner-rotation/internal/auth"
+	"banner-rotation/internal/ratelimit"
+	"log"
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimit ограничивает частоту запросов клиента лимитом области scope.
+// Клиент - API-ключ запроса, а без проверки ключей - IP-адрес. Если
+// хранилище лимитов недоступно, запрос пропускается.
+func (s *Server) rateLimit(scope auth.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := s.rateLimits[scope]
+		if s.limiter == nil || !limit.Enabled() {
+			c.Next()
+			return
+		}
+
+		allowed, retryAfter, err := s.limiter.Allow(c.Request.Context(), string(scope)+":"+clientID(c), limit)
+		if err != nil {
+			log.Printf("Failed to check rate limit: %v", err)
+			c.Next()
+			return
+		}
+		if !allowed {
+			seconds := int(math.Max(1, math.Ceil(retryAfter.Seconds())))
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			_ = c.Error(ratelimit.ErrLimited)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// clientID возвращает ключ корзины клиента: ID API-ключа или IP-адрес
+func clientID(c *gin.Context) string {
+	if v, ok := c.Get(apiKeyContextKey); ok {
+		return "key:" + strconv.Itoa(v.(auth.Key).ID)
+	}
+	return "ip:" + c.ClientIP()
+}
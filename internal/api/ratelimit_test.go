@@ -0,0 +1,103 @@
+package api
+
+import (
+	"banner-rotation/internal/auth"
+	"banner-rotation/internal/ratelimit"
+	"banner-rotation/internal/storage"
+	"banner-rotation/internal/storage/memory"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// failingLimiter - хранилище лимитов недоступно
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(ctx context.Context, key string, limit ratelimit.Limit) (bool, time.Duration, error) {
+	return false, 0, errors.New("connection refused")
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newServer := func(limiter ratelimit.Limiter) *Server {
+		mockBandit := new(MockBandit)
+		mockBandit.On("ChooseBanner", mock.Anything, 1, 1, storage.Visitor{}).Return(100, nil)
+		server := NewServer(mockBandit, memory.New())
+		server.SetRateLimiter(limiter, ratelimit.Limit{Rate: 1, Burst: 2}, ratelimit.Limit{Rate: 1, Burst: 1})
+		return server
+	}
+	do := func(server *Server, method, url string, body interface{}, setup func(*http.Request)) *httptest.ResponseRecorder {
+		req := createRequest(t, method, url, body)
+		if setup != nil {
+			setup(req)
+		}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	choose := ChooseBannerRequest{SlotID: 1, GroupID: 1}
+	fromIP := func(ip string) func(*http.Request) {
+		return func(r *http.Request) { r.RemoteAddr = ip + ":12345" }
+	}
+
+	t.Run("per IP with Retry-After", func(t *testing.T) {
+		server := newServer(ratelimit.NewMemoryLimiter())
+		for i := 0; i < 2; i++ {
+			assert.Equal(t, http.StatusOK, do(server, "POST", "/api/v1/choose_banner", choose, fromIP("10.0.0.1")).Code)
+		}
+
+		w := do(server, "POST", "/api/v1/choose_banner", choose, fromIP("10.0.0.1"))
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "1", w.Header().Get("Retry-After"))
+		var resp ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "rate_limited", resp.Code)
+
+		assert.Equal(t, http.StatusOK, do(server, "POST", "/api/v1/choose_banner", choose, fromIP("10.0.0.2")).Code)
+	})
+
+	t.Run("serve and admin limits are separate", func(t *testing.T) {
+		server := newServer(ratelimit.NewMemoryLimiter())
+		assert.Equal(t, http.StatusOK, do(server, "GET", "/api/v1/banners", nil, fromIP("10.0.0.1")).Code)
+		assert.Equal(t, http.StatusTooManyRequests, do(server, "GET", "/api/v1/slots", nil, fromIP("10.0.0.1")).Code)
+		assert.Equal(t, http.StatusOK, do(server, "POST", "/api/v1/choose_banner", choose, fromIP("10.0.0.1")).Code)
+	})
+
+	t.Run("per API key", func(t *testing.T) {
+		server := newServer(ratelimit.NewMemoryLimiter())
+		server.SetAuthenticator(auth.NewAuthenticator(mapKeyStore{
+			auth.HashKey("key-1"): {ID: 1, Scopes: []auth.Scope{auth.ScopeServe}},
+			auth.HashKey("key-2"): {ID: 2, Scopes: []auth.Scope{auth.ScopeServe}},
+		}, time.Minute))
+		withKey := func(key, ip string) func(*http.Request) {
+			return func(r *http.Request) {
+				r.Header.Set(APIKeyHeader, key)
+				r.RemoteAddr = ip + ":12345"
+			}
+		}
+
+		// Один ключ с разных адресов расходует общую корзину
+		assert.Equal(t, http.StatusOK, do(server, "POST", "/api/v1/choose_banner", choose, withKey("key-1", "10.0.0.1")).Code)
+		assert.Equal(t, http.StatusOK, do(server, "POST", "/api/v1/choose_banner", choose, withKey("key-1", "10.0.0.2")).Code)
+		assert.Equal(t, http.StatusTooManyRequests, do(server, "POST", "/api/v1/choose_banner", choose, withKey("key-1", "10.0.0.3")).Code)
+
+		assert.Equal(t, http.StatusOK, do(server, "POST", "/api/v1/choose_banner", choose, withKey("key-2", "10.0.0.1")).Code)
+	})
+
+	t.Run("limiter failure lets requests through", func(t *testing.T) {
+		server := newServer(failingLimiter{})
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusOK, do(server, "POST", "/api/v1/choose_banner", choose, nil).Code)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"banner-rotation/internal/storage"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReplaceSlotBannersRequest запрос на замену ротации слота целиком.
+// Пустой список убирает из ротации все баннеры.
+type ReplaceSlotBannersRequest struct {
+	BannerIDs []int `json:"banner_ids" binding:"required,dive,min=1"`
+}
+
+// UpdateSlotBannersRequest запрос на добавление и удаление баннеров слота
+type UpdateSlotBannersRequest struct {
+	Add    []int `json:"add" binding:"dive,min=1"`
+	Remove []int `json:"remove" binding:"dive,min=1"`
+}
+
+// SlotBannersResponse ротация слота после изменения
+type SlotBannersResponse struct {
+	SlotID    int   `json:"slot_id"`
+	BannerIDs []int `json:"banner_ids"`
+}
+
+func (s *Server) replaceSlotBanners(c *gin.Context) {
+	id, ok := entityID(c)
+	if !ok {
+		return
+	}
+
+	var req ReplaceSlotBannersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	s.updateSlotRotation(c, id, storage.RotationChange{Add: req.BannerIDs, Replace: true})
+}
+
+func (s *Server) updateSlotBanners(c *gin.Context) {
+	id, ok := entityID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateSlotBannersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+	for _, bannerID := range req.Add {
+		if slices.Contains(req.Remove, bannerID) {
+			badRequest(c, fmt.Errorf("banner %d is both added and removed", bannerID))
+			return
+		}
+	}
+
+	s.updateSlotRotation(c, id, storage.RotationChange{Add: req.Add, Remove: req.Remove})
+}
+
+// updateSlotRotation применяет изменение и отвечает итоговой ротацией слота
+func (s *Server) updateSlotRotation(c *gin.Context, slotID int, change storage.RotationChange) {
+	bannerIDs, err := s.bandit.UpdateSlotRotation(c.Request.Context(), slotID, change)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if bannerIDs == nil {
+		bannerIDs = []int{}
+	}
+
+	c.JSON(http.StatusOK, SlotBannersResponse{SlotID: slotID, BannerIDs: bannerIDs})
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"banner-rotation/internal/app"
+	"banner-rotation/internal/storage"
+	"banner-rotation/internal/storage/memory"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlotRotationEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctx := context.Background()
+	store := memory.New()
+	server := NewServer(app.NewBandit(store, nil), store)
+
+	_, err := store.CreateSlot(ctx, storage.Slot{ID: 1, Sizes: []storage.Size{{Width: 300, Height: 250}}})
+	require.NoError(t, err)
+	for id := 1; id <= 3; id++ {
+		_, err = store.CreateBanner(ctx, storage.Banner{ID: id, Creative: storage.Creative{Width: 300, Height: 250}})
+		require.NoError(t, err)
+	}
+	_, err = store.CreateBanner(ctx, storage.Banner{ID: 4, Creative: storage.Creative{Width: 728, Height: 90}})
+	require.NoError(t, err)
+
+	do := func(method, url string, body interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, createRequest(t, method, url, body))
+		return w
+	}
+	rotation := func(w *httptest.ResponseRecorder) []int {
+		var resp SlotBannersResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, 1, resp.SlotID)
+		return resp.BannerIDs
+	}
+
+	t.Run("replace", func(t *testing.T) {
+		w := do("PUT", "/api/v1/slots/1/banners", ReplaceSlotBannersRequest{BannerIDs: []int{2, 1}})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []int{1, 2}, rotation(w))
+	})
+
+	t.Run("add and remove", func(t *testing.T) {
+		w := do("PATCH", "/api/v1/slots/1/banners", UpdateSlotBannersRequest{Add: []int{3}, Remove: []int{1}})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []int{2, 3}, rotation(w))
+	})
+
+	t.Run("size mismatch applies nothing", func(t *testing.T) {
+		w := do("PATCH", "/api/v1/slots/1/banners", UpdateSlotBannersRequest{Add: []int{1, 4}, Remove: []int{2}})
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+		banners, err := store.GetBannersForSlot(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []int{2, 3}, banners)
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		w := do("PATCH", "/api/v1/slots/1/banners", UpdateSlotBannersRequest{Add: []int{2}, Remove: []int{2}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = do("PUT", "/api/v1/slots/1/banners", map[string]interface{}{})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = do("PUT", "/api/v1/slots/1/banners", ReplaceSlotBannersRequest{BannerIDs: []int{0}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("replace with empty list clears rotation", func(t *testing.T) {
+		w := do("PUT", "/api/v1/slots/1/banners", ReplaceSlotBannersRequest{BannerIDs: []int{}})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []int{}, rotation(w))
+	})
+}
@@ -1,11 +1,73 @@
 package api
 
+import (
+	"banner-rotation/internal/auth"
+	"banner-rotation/internal/storage"
+)
+
 func (s *Server) setupRoutes() {
+	// Проверки живости и готовности без ключа и лимитов
+	s.router.GET("/healthz", s.healthz)
+	s.router.GET("/readyz", s.readyz)
+
+	// Переход по ссылке из креатива: токен подписан, ключ не нужен
+	s.router.GET("/c/:token", s.click)
+	// Пиксель подтверждения показа в режиме резервирования
+	s.router.GET("/i/:token", s.impression)
+
 	api := s.router.Group("/api/v1")
+	api.GET("/openapi.json", s.openAPI)
+
+	// Показ баннеров: достаточно права serve
+	serve := api.Group("", s.authorize(auth.ScopeServe), s.rateLimit(auth.ScopeServe))
+	{
+		serve.POST("/choose_banner", s.idempotent(), s.chooseBanner)
+		serve.POST("/choose_banners", s.idempotent(), s.chooseBanners)
+		serve.POST("/register_click", s.idempotent(), s.registerClick)
+		serve.GET("/banners/:id/render", s.renderBanner)
+	}
+
+	// Управление и статистика: только admin
+	admin := api.Group("", s.authorize(auth.ScopeAdmin), s.rateLimit(auth.ScopeAdmin))
 	{
-		api.POST("/banner_slot", s.addBannerToSlot)
-		api.DELETE("/banner_slot", s.removeBannerFromSlot)
-		api.POST("/choose_banner", s.chooseBanner)
-		api.POST("/register_click", s.registerClick)
+		admin.POST("/banner_slot", s.addBannerToSlot)
+		admin.DELETE("/banner_slot", s.removeBannerFromSlot)
+		admin.GET("/slots/:id/stats", s.getSlotStats)
+
+		admin.POST("/banners", s.createBanner)
+		admin.GET("/banners", s.listBanners)
+		admin.GET("/banners/:id", s.getBanner)
+		admin.PUT("/banners/:id", s.updateBanner)
+		admin.DELETE("/banners/:id", s.deleteBanner)
+
+		admin.POST("/slots", s.createSlot)
+		admin.GET("/slots", s.listSlots)
+		admin.GET("/slots/:id", s.getSlot)
+		admin.PUT("/slots/:id", s.updateSlot)
+		admin.DELETE("/slots/:id", s.deleteSlot)
+		admin.PUT("/slots/:id/banners", s.replaceSlotBanners)
+		admin.PATCH("/slots/:id/banners", s.updateSlotBanners)
+		admin.PUT("/slots/:id/banners/:banner_id/targeting", s.setBannerTargeting)
+
+		admin.POST("/groups", s.createGroup)
+		admin.GET("/groups", s.listGroups)
+		admin.GET("/groups/:id", s.getGroup)
+		admin.PUT("/groups/:id", s.updateGroup)
+		admin.DELETE("/groups/:id", s.deleteGroup)
+
+		admin.POST("/advertisers", s.createAdvertiser)
+		admin.GET("/advertisers", s.listAdvertisers)
+		admin.GET("/advertisers/:id", s.getAdvertiser)
+		admin.PUT("/advertisers/:id", s.updateAdvertiser)
+		admin.DELETE("/advertisers/:id", s.deleteAdvertiser)
+
+		admin.POST("/campaigns", s.createCampaign)
+		admin.GET("/campaigns", s.listCampaigns)
+		admin.GET("/campaigns/:id", s.getCampaign)
+		admin.PUT("/campaigns/:id", s.updateCampaign)
+		admin.DELETE("/campaigns/:id", s.deleteCampaign)
+		admin.POST("/campaigns/:id/pause", s.setCampaignStatus(storage.CampaignPaused))
+		admin.POST("/campaigns/:id/resume", s.setCampaignStatus(storage.CampaignActive))
+		admin.POST("/campaigns/:id/stop", s.setCampaignStatus(storage.CampaignStopped))
 	}
 }
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"banner-rotation/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserAttributes атрибуты пользователя для подбора группы на сервере
+type UserAttributes struct {
+	Age       int      `json:"age" binding:"min=0"`
+	Gender    string   `json:"gender"`
+	Interests []string `json:"interests"`
+}
+
+// resolveGroup возвращает группу из запроса, а если она не указана - подбирает
+// ее по атрибутам пользователя. При ошибке передает ее обработчику ошибок
+// и возвращает false.
+func (s *Server) resolveGroup(c *gin.Context, groupID int, user *UserAttributes) (int, bool) {
+	if groupID != 0 || user == nil {
+		return groupID, true
+	}
+
+	groupID, err := s.segments.ResolveGroup(c.Request.Context(), storage.UserAttributes(*user))
+	if err != nil {
+		_ = c.Error(err)
+		return 0, false
+	}
+	return groupID, true
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"banner-rotation/internal/app"
+	"banner-rotation/internal/storage/memory"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupResolution(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctx := context.Background()
+	store := memory.New()
+	server := NewServer(app.NewBandit(store, nil), store)
+	createCatalog(t, store, []int{1}, []int{1})
+	require.NoError(t, store.AddBannerToSlot(ctx, 1, 1))
+
+	do := func(method, url string, body interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, createRequest(t, method, url, body))
+		return w
+	}
+
+	t.Run("group with rule", func(t *testing.T) {
+		w := do("POST", "/api/v1/groups", CreateGroupRequest{
+			ID:          5,
+			Description: "students",
+			Rule:        &GroupRule{Priority: 1, MinAge: 18, MaxAge: 23},
+		})
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var group GroupResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &group))
+		assert.Equal(t, &GroupRule{Priority: 1, MinAge: 18, MaxAge: 23}, group.Rule)
+
+		w = do("POST", "/api/v1/groups", CreateGroupRequest{Description: "invalid", Rule: &GroupRule{MinAge: 30, MaxAge: 20}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("choose_banner resolves group", func(t *testing.T) {
+		w := do("POST", "/api/v1/choose_banner", ChooseBannerRequest{SlotID: 1, User: &UserAttributes{Age: 20}})
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp ChooseBannerResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, ChooseBannerResponse{BannerID: 1, GroupID: 5}, resp)
+
+		w = do("POST", "/api/v1/register_click", RegisterClickRequest{SlotID: 1, BannerID: 1, User: &UserAttributes{Age: 20}})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		stats, err := store.GetBannerStats(ctx, 1, 5)
+		require.NoError(t, err)
+		require.Len(t, stats, 1)
+		assert.Equal(t, 1, stats[0].Shows)
+		assert.Equal(t, 1, stats[0].Clicks)
+	})
+
+	t.Run("no matching group", func(t *testing.T) {
+		w := do("POST", "/api/v1/choose_banner", ChooseBannerRequest{SlotID: 1, User: &UserAttributes{Age: 40}})
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("group changes apply immediately", func(t *testing.T) {
+		w := do("PUT", "/api/v1/groups/5", UpdateGroupRequest{Description: "adults", Rule: &GroupRule{MinAge: 18}})
+		require.Equal(t, http.StatusOK, w.Code)
+
+		w = do("POST", "/api/v1/choose_banner", ChooseBannerRequest{SlotID: 1, User: &UserAttributes{Age: 40}})
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("group_id or user is required", func(t *testing.T) {
+		w := do("POST", "/api/v1/choose_banner", ChooseBannerRequest{SlotID: 1})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = do("POST", "/api/v1/register_click", RegisterClickRequest{SlotID: 1, BannerID: 1})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
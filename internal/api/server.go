@@ -2,30 +2,109 @@ package api
 
 import (
 	"banner-rotation/internal/app"
+	"banner-rotation/internal/auth"
+	"banner-rotation/internal/idempotency"
+	"banner-rotation/internal/ratelimit"
+	"banner-rotation/internal/tracking"
 	"context"
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Server struct {
-	router *gin.Engine
-	bandit app.BanditInterface
-	server *http.Server
+	router   *gin.Engine
+	bandit   app.BanditInterface
+	catalog  app.CatalogInterface
+	segments *app.Segmenter
+	server   *http.Server
+
+	idempotency    idempotency.Store
+	idempotencyTTL time.Duration
+
+	// auth - проверка API-ключей, nil - проверка выключена
+	auth *auth.Authenticator
+
+	// limiter и rateLimits - лимиты запросов по областям, nil - без лимитов
+	limiter    ratelimit.Limiter
+	rateLimits map[auth.Scope]ratelimit.Limit
+
+	// tracker подписывает ссылки переходов и пиксели, nil - ссылки не выдаются
+	tracker         *tracking.Signer
+	trackingBaseURL string
+	fallbackURL     string
+	clickTTL        time.Duration
+
+	// reservations - подтвержденные показы, не nil - показ записывается
+	// только по пикселю /i/{token}.gif
+	reservations   idempotency.Store
+	reservationTTL time.Duration
+
+	// checks - проверки готовности, draining - сервер останавливается
+	checks   []readinessCheck
+	draining atomic.Bool
 }
 
-func NewServer(bandit app.BanditInterface) *Server {
+func NewServer(bandit app.BanditInterface, catalog app.CatalogInterface) *Server {
 	router := gin.Default()
+	router.Use(errorHandler())
 
 	server := &Server{
-		router: router,
-		bandit: bandit,
+		router:   router,
+		bandit:   bandit,
+		catalog:  catalog,
+		segments: app.NewSegmenter(catalog, app.DefaultRulesReloadInterval),
+
+		idempotency:    idempotency.NewMemoryStore(),
+		idempotencyTTL: idempotency.DefaultTTL,
 	}
 
+	server.AddReadinessCheck("group_rules", server.segments.Warm)
+
 	server.setupRoutes()
 	return server
 }
 
+// SetIdempotencyStore заменяет хранилище ключей идемпотентности, по умолчанию
+// ключи хранятся в памяти DefaultTTL. Вызывается до Start.
+func (s *Server) SetIdempotencyStore(store idempotency.Store, ttl time.Duration) {
+	s.idempotency = store
+	s.idempotencyTTL = ttl
+}
+
+// SetAuthenticator включает проверку API-ключей. Вызывается до Start.
+func (s *Server) SetAuthenticator(authenticator *auth.Authenticator) {
+	s.auth = authenticator
+}
+
+// SetRateLimiter включает лимиты запросов к маршрутам областей serve и admin.
+// Нулевой Limit оставляет область без лимита. Вызывается до Start.
+func (s *Server) SetRateLimiter(limiter ratelimit.Limiter, serve, admin ratelimit.Limit) {
+	s.limiter = limiter
+	s.rateLimits = map[auth.Scope]ratelimit.Limit{auth.ScopeServe: serve, auth.ScopeAdmin: admin}
+}
+
+// SetTracking включает подписанные ссылки переходов /c/{token}, действующие
+// clickTTL. baseURL - внешний адрес сервиса для ссылок, fallbackURL - куда
+// вести переход, если посадочная страница баннера неизвестна. Вызывается до Start.
+func (s *Server) SetTracking(signer *tracking.Signer, baseURL, fallbackURL string, clickTTL time.Duration) {
+	s.tracker = signer
+	s.trackingBaseURL = baseURL
+	s.fallbackURL = fallbackURL
+	s.clickTTL = clickTTL
+}
+
+// SetShowReservations включает резервирование показов: выбор баннера не
+// записывает показ, а возвращает пиксель, который подтверждает его в течение
+// ttl. store помнит подтвержденные пиксели, чтобы показ не засчитался дважды.
+// Требует SetTracking, вызывается до Start.
+func (s *Server) SetShowReservations(store idempotency.Store, ttl time.Duration) {
+	s.reservations = store
+	s.reservationTTL = ttl
+}
+
 func (s *Server) Start(address string) error {
 	s.server = &http.Server{
 		Addr:    address,
@@ -36,5 +115,6 @@ func (s *Server) Start(address string) error {
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
+	s.Drain()
 	return s.server.Shutdown(ctx)
 }
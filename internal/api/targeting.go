@@ -0,0 +1,40 @@
+package api
+
+import (
+	"banner-rotation/internal/storage"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Targeting ограничения показа баннера в слоте. Пустой список не ограничивает.
+type Targeting struct {
+	Countries []string `json:"countries,omitempty" binding:"dive,len=2,alpha"`
+	Devices   []string `json:"devices,omitempty" binding:"dive,oneof=desktop mobile tablet"`
+	OS        []string `json:"os,omitempty" binding:"dive,required"`
+}
+
+func (s *Server) setBannerTargeting(c *gin.Context) {
+	slotID, ok := entityID(c)
+	if !ok {
+		return
+	}
+	bannerID, ok := pathID(c, "banner_id")
+	if !ok {
+		return
+	}
+
+	var req Targeting
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(c, err)
+		return
+	}
+
+	targeting := storage.Targeting(req)
+	if err := s.bandit.SetBannerTargeting(c.Request.Context(), slotID, bannerID, targeting); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, req)
+}
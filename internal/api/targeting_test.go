@@ -0,0 +1,73 @@
+package api
+
+import (
+	"banner-rotation/internal/app"
+	"banner-rotation/internal/storage"
+	"banner-rotation/internal/storage/memory"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetingEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctx := context.Background()
+	store := memory.New()
+	server := NewServer(app.NewBandit(store, nil), store)
+	createCatalog(t, store, []int{1}, []int{1})
+	require.NoError(t, store.AddBannerToSlot(ctx, 1, 1))
+
+	do := func(method, url string, body interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, createRequest(t, method, url, body))
+		return w
+	}
+
+	t.Run("set targeting", func(t *testing.T) {
+		w := do("PUT", "/api/v1/slots/1/banners/1/targeting", Targeting{Countries: []string{"RU"}, Devices: []string{"mobile"}})
+		require.Equal(t, http.StatusOK, w.Code)
+
+		got, err := store.GetSlotTargeting(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, map[int]storage.Targeting{1: {Countries: []string{"RU"}, Devices: []string{"mobile"}}}, got)
+	})
+
+	t.Run("invalid targeting", func(t *testing.T) {
+		w := do("PUT", "/api/v1/slots/1/banners/1/targeting", Targeting{Devices: []string{"watch"}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = do("PUT", "/api/v1/slots/1/banners/x/targeting", Targeting{})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("banner not in slot", func(t *testing.T) {
+		w := do("PUT", "/api/v1/slots/1/banners/2/targeting", Targeting{OS: []string{"ios"}})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("choose_banner passes visitor", func(t *testing.T) {
+		w := do("POST", "/api/v1/choose_banner", ChooseBannerRequest{SlotID: 1, GroupID: 1, Country: "ru", Device: "mobile"})
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp ChooseBannerResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, 1, resp.BannerID)
+
+		var errResp ErrorResponse
+
+		w = do("POST", "/api/v1/choose_banner", ChooseBannerRequest{SlotID: 1, GroupID: 1, Country: "DE", Device: "mobile"})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+		assert.Equal(t, "no_eligible_banners", errResp.Code)
+
+		w = do("POST", "/api/v1/choose_banner", ChooseBannerRequest{SlotID: 1, GroupID: 1, Device: "watch"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
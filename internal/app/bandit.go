@@ -7,26 +7,59 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"math"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // BanditInterface определяет контракт для работы с ротацией баннеров
 type BanditInterface interface {
 	AddBannerToSlot(ctx context.Context, slotID, bannerID int) error
 	RemoveBannerFromSlot(ctx context.Context, slotID, bannerID int) error
-	ChooseBanner(ctx context.Context, slotID, groupID int) (int, error)
+	UpdateSlotRotation(ctx context.Context, slotID int, change storage.RotationChange) ([]int, error)
+	SetBannerTargeting(ctx context.Context, slotID, bannerID int, targeting storage.Targeting) error
+	ChooseBanner(ctx context.Context, slotID, groupID int, visitor storage.Visitor) (int, error)
+	ChooseBanners(ctx context.Context, choices []SlotChoice, visitor storage.Visitor) []SlotDecision
+	ReserveBanner(ctx context.Context, slotID, groupID int, visitor storage.Visitor) (int, error)
+	ReserveBanners(ctx context.Context, choices []SlotChoice, visitor storage.Visitor) []SlotDecision
+	ConfirmShow(ctx context.Context, slotID, bannerID, groupID int) error
 	RecordClick(ctx context.Context, slotID, bannerID, groupID int) error
+	GetSlotStats(ctx context.Context, slotID, groupID int, from, to time.Time) (*SlotReport, error)
+	UpdateBanner(ctx context.Context, banner storage.Banner) error
+	UpdateSlot(ctx context.Context, slot storage.Slot) error
+	UpdateCampaign(ctx context.Context, campaign storage.Campaign) error
+	SetCampaignStatus(ctx context.Context, campaignID int, status storage.CampaignStatus) (storage.Campaign, error)
 }
 
 var _ BanditInterface = (*Bandit)(nil)
 
+// DefaultReservationTTL - сколько выбор ReserveBanner ждет подтверждения
+// ConfirmShow, совпадает со сроком действия ссылки на пиксель показа
+const DefaultReservationTTL = time.Hour
+
+// DefaultWarmRetryInterval - пауза между попытками прогрева кеша в StartWarm
+const DefaultWarmRetryInterval = 5 * time.Second
+
 // Bandit - основной объект для управления ротацией баннеров
 type Bandit struct {
 	mu       sync.RWMutex
 	store    storage.Storage
 	cache    map[string]*banditCache
 	producer kafka.ProducerInterface
+	now      func() time.Time
+
+	// capped - slotID -> баннеры, исчерпавшие лимит показов в слоте.
+	// Множества не меняются после записи: markCapped заменяет их копией.
+	capped map[int]map[int]struct{}
+
+	// warmed - кеш статистики заполнен вызовом Warm
+	warmed atomic.Bool
+
+	reservationTTL time.Duration
 }
 
 // banditCache - кешированная статистика для комбинации слот+группа
@@ -34,6 +67,75 @@ type banditCache struct {
 	mu         sync.RWMutex
 	totalShows int
 	banners    map[int]BannerStat
+	// reserved - выборы ReserveBanner, еще не подтвержденные ConfirmShow:
+	// bannerID -> минута истечения (unix) -> число выборов
+	reserved map[int]map[int64]int
+
+	// Поля ниже не меняются после загрузки кеша.
+	// inactive - баннеры, кампании которых на паузе, остановлены
+	// или вне периода показа: их статистика учитывается, но они не выбираются
+	inactive map[int]struct{}
+	// campaigns - bannerID -> campaignID для баннеров, входящих в кампании
+	campaigns map[int]int
+	// targeting - ограничения показа баннеров в слоте
+	targeting map[int]storage.Targeting
+	// expiresAt - ближайшая граница периода показа кампаний, после которой
+	// кеш перечитывается; нулевое значение - кеш не устаревает
+	expiresAt time.Time
+}
+
+// fresh проверяет, что кеш не устарел к моменту now
+func (c *banditCache) fresh(now time.Time) bool {
+	return c.expiresAt.IsZero() || now.Before(c.expiresAt)
+}
+
+// reserve учитывает выбор баннера до подтверждения. Выборы группируются
+// по минуте истечения, чтобы память не росла с числом запросов.
+// Вызывается под c.mu.
+func (c *banditCache) reserve(bannerID int, expiresAt time.Time) {
+	if c.reserved == nil {
+		c.reserved = make(map[int]map[int64]int)
+	}
+	buckets, ok := c.reserved[bannerID]
+	if !ok {
+		buckets = make(map[int64]int)
+		c.reserved[bannerID] = buckets
+	}
+	buckets[expiresAt.Truncate(time.Minute).Add(time.Minute).Unix()]++
+}
+
+// release снимает один неподтвержденный выбор баннера, истекающий раньше
+// остальных. Вызывается под c.mu.
+func (c *banditCache) release(bannerID int) {
+	buckets := c.reserved[bannerID]
+	var oldest int64
+	for expiresAt := range buckets {
+		if oldest == 0 || expiresAt < oldest {
+			oldest = expiresAt
+		}
+	}
+	if oldest == 0 {
+		return
+	}
+
+	buckets[oldest]--
+	if buckets[oldest] == 0 {
+		delete(buckets, oldest)
+	}
+}
+
+// reservedShows возвращает число неподтвержденных выборов баннера,
+// забывая истекшие. Вызывается под c.mu.
+func (c *banditCache) reservedShows(bannerID int, now time.Time) int {
+	total := 0
+	for expiresAt, n := range c.reserved[bannerID] {
+		if expiresAt <= now.Unix() {
+			delete(c.reserved[bannerID], expiresAt)
+			continue
+		}
+		total += n
+	}
+	return total
 }
 
 // BannerStat - статистика для одного баннера
@@ -48,25 +150,59 @@ func NewBandit(store storage.Storage, producer kafka.ProducerInterface) *Bandit
 		store:    store,
 		cache:    make(map[string]*banditCache),
 		producer: producer,
+		now:      time.Now,
+		capped:   make(map[int]map[int]struct{}),
+
+		reservationTTL: DefaultReservationTTL,
 	}
 }
 
+// SetReservationTTL задает, сколько выбор ReserveBanner влияет на выбор
+// следующих баннеров, если показ так и не подтвержден
+func (b *Bandit) SetReservationTTL(ttl time.Duration) {
+	b.reservationTTL = ttl
+}
+
 // getCacheKey генерирует ключ кеша для комбинации слот+группа
 func (b *Bandit) getCacheKey(slotID, groupID int) string {
 	return fmt.Sprintf("%d_%d", slotID, groupID)
 }
 
-var (
-	ErrNoBanners = errors.New("no banners in rotation for slot")
-)
+// SizeMismatchError - размер креатива баннера не входит в допустимые размеры слота
+type SizeMismatchError struct {
+	SlotID   int
+	BannerID int
+	Size     storage.Size
+	Allowed  []storage.Size
+}
+
+func (e *SizeMismatchError) Error() string {
+	allowed := make([]string, len(e.Allowed))
+	for i, size := range e.Allowed {
+		allowed[i] = size.String()
+	}
+	return fmt.Sprintf("banner %d of size %s does not fit slot %d (allowed: %s)",
+		e.BannerID, e.Size, e.SlotID, strings.Join(allowed, ", "))
+}
+
+// Code возвращает машиночитаемый код ошибки
+func (e *SizeMismatchError) Code() string {
+	return "size_mismatch"
+}
+
+// Unwrap относит несовпадение размера к ошибкам валидации
+func (e *SizeMismatchError) Unwrap() error {
+	return storage.ErrInvalid
+}
 
 // loadStats загружает статистику из хранилища или кеша
 func (b *Bandit) loadStats(ctx context.Context, slotID, groupID int) (*banditCache, error) {
 	key := b.getCacheKey(slotID, groupID)
+	now := b.now()
 
 	// Проверка кеша под блокировкой чтения
 	b.mu.RLock()
-	if cache, ok := b.cache[key]; ok {
+	if cache, ok := b.cache[key]; ok && cache.fresh(now) {
 		b.mu.RUnlock()
 		return cache, nil
 	}
@@ -87,6 +223,12 @@ func (b *Bandit) loadStats(ctx context.Context, slotID, groupID int) (*banditCac
 		banners:    make(map[int]BannerStat, len(bannerIDs)),
 		totalShows: 0,
 	}
+	if err := b.loadCampaigns(ctx, newCache, bannerIDs, now); err != nil {
+		return nil, err
+	}
+	if newCache.targeting, err = b.store.GetSlotTargeting(ctx, slotID); err != nil {
+		return nil, fmt.Errorf("failed to get targeting for slot %d: %w", slotID, err)
+	}
 
 	// Инициализация баннеров
 	for _, id := range bannerIDs {
@@ -116,7 +258,7 @@ func (b *Bandit) loadStats(ctx context.Context, slotID, groupID int) (*banditCac
 	defer b.mu.Unlock()
 
 	// Проверка на случай, если кеш уже добавили параллельно
-	if existingCache, ok := b.cache[key]; ok {
+	if existingCache, ok := b.cache[key]; ok && existingCache.fresh(now) {
 		return existingCache, nil
 	}
 
@@ -124,6 +266,69 @@ func (b *Bandit) loadStats(ctx context.Context, slotID, groupID int) (*banditCac
 	return newCache, nil
 }
 
+// Warm заполняет кеш статистики для всех слотов и групп справочника, чтобы
+// первые запросы после запуска не ждали хранилище. Записи, загруженные
+// до ошибки, остаются в кеше, и следующий вызов продолжает с них.
+// После успешного прогрева возвращает nil без обращения к хранилищу.
+func (b *Bandit) Warm(ctx context.Context) error {
+	if b.warmed.Load() {
+		return nil
+	}
+
+	slots, err := b.store.ListSlots(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list slots: %w", err)
+	}
+	groups, err := b.store.ListGroups(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list groups: %w", err)
+	}
+
+	for _, slot := range slots {
+		for _, group := range groups {
+			if _, err := b.loadStats(ctx, slot.ID, group.ID); err != nil && !errors.Is(err, ErrNoBanners) {
+				return fmt.Errorf("failed to warm stats for slot %d group %d: %w", slot.ID, group.ID, err)
+			}
+		}
+	}
+
+	b.warmed.Store(true)
+	return nil
+}
+
+// StartWarm прогревает кеш статистики в фоне, повторяя Warm через interval,
+// пока прогрев не завершится или не отменится ctx
+func (b *Bandit) StartWarm(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			err := b.Warm(ctx)
+			if err == nil {
+				log.Println("Bandit stats cache warmed")
+				return
+			}
+			log.Printf("Failed to warm bandit stats cache: %v", err)
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Warmed возвращает ErrCacheWarming, пока кеш статистики не прогрет.
+// Подходит для проверки готовности: к хранилищу не обращается.
+func (b *Bandit) Warmed(ctx context.Context) error {
+	if !b.warmed.Load() {
+		return ErrCacheWarming
+	}
+	return nil
+}
+
 func (b *Bandit) sendEvent(eventType events.EventType, slotID, bannerID, groupID int) {
 	if b.producer == nil {
 		return
@@ -134,39 +339,216 @@ func (b *Bandit) sendEvent(eventType events.EventType, slotID, bannerID, groupID
 	}()
 }
 
-// chooseBannerSafe безопасно выбирает баннер под блокировкой
-func (b *Bandit) chooseBannerSafe(cache *banditCache) int {
-	bestID := 0
+// chooseBannerSafe безопасно выбирает баннер под блокировкой, пропуская
+// баннеры из capped. Неподтвержденные выборы считаются показами, чтобы
+// параллельные резервы не доставались одному баннеру. Возвращает 0, если
+// подходящих баннеров нет; filtered сообщает, что активные баннеры были,
+// но не подошли посетителю по ограничениям показа.
+func (b *Bandit) chooseBannerSafe(cache *banditCache, visitor storage.Visitor, capped map[int]struct{}, now time.Time) (bestID int, filtered bool) {
 	bestValue := -1.0
 
+	reserved := make(map[int]int, len(cache.reserved))
+	totalShows := cache.totalShows
+	for bannerID := range cache.reserved {
+		reserved[bannerID] = cache.reservedShows(bannerID, now)
+		totalShows += reserved[bannerID]
+	}
+
 	for bannerID, stat := range cache.banners {
-		value := b.calculateUCB(stat, cache.totalShows)
+		if _, ok := cache.inactive[bannerID]; ok {
+			continue
+		}
+		if _, ok := capped[bannerID]; ok {
+			continue
+		}
+		if targeting, ok := cache.targeting[bannerID]; ok && !targeting.Allows(visitor) {
+			filtered = true
+			continue
+		}
+		stat.Shows += reserved[bannerID]
+		value := b.calculateUCB(stat, totalShows)
 		if value > bestValue {
 			bestValue = value
 			bestID = bannerID
 		}
 	}
 
-	return bestID
+	return bestID, filtered
 }
 
 // ChooseBanner выбирает баннер для показа в указанном слоте для группы
-func (b *Bandit) ChooseBanner(ctx context.Context, slotID, groupID int) (int, error) {
+// среди баннеров, ограничения показа которых допускают посетителя.
+// Возвращает ErrNoEligibleBanners, если ни один активный баннер не подошел.
+func (b *Bandit) ChooseBanner(ctx context.Context, slotID, groupID int, visitor storage.Visitor) (int, error) {
+	bannerID, err := b.show(ctx, slotID, groupID, visitor)
+	if err != nil {
+		return 0, err
+	}
+
+	b.sendEvent(events.EventShow, slotID, bannerID, groupID)
+	return bannerID, nil
+}
+
+// show выбирает баннер и записывает показ. Если баннер исчерпал лимит
+// показов, он исключается из выбора и баннер выбирается заново.
+func (b *Bandit) show(ctx context.Context, slotID, groupID int, visitor storage.Visitor) (int, error) {
+	for {
+		bannerID, err := b.pick(ctx, slotID, groupID, visitor, false)
+		if err != nil {
+			return 0, err
+		}
+
+		err = b.recordShow(ctx, slotID, bannerID, groupID)
+		if err == nil {
+			return bannerID, nil
+		}
+		if !errors.Is(err, ErrShowCapReached) {
+			return 0, err
+		}
+	}
+}
+
+// ReserveBanner выбирает баннер как ChooseBanner, но не записывает показ:
+// его подтверждает ConfirmShow, когда баннер действительно увидели.
+// До подтверждения выбор не считается показом, но в течение reservationTTL
+// учитывается этим экземпляром при выборе следующих баннеров.
+func (b *Bandit) ReserveBanner(ctx context.Context, slotID, groupID int, visitor storage.Visitor) (int, error) {
+	return b.pick(ctx, slotID, groupID, visitor, true)
+}
+
+// ConfirmShow записывает показ баннера, выбранного ReserveBanner, и учитывает
+// его в кеше вместо резерва. Возвращает ErrShowCapReached, если баннер
+// уже исчерпал лимит показов.
+func (b *Bandit) ConfirmShow(ctx context.Context, slotID, bannerID, groupID int) error {
+	err := b.storeShow(ctx, slotID, bannerID, groupID)
+	b.settleReservation(slotID, bannerID, groupID, err == nil)
+	if err != nil {
+		return err
+	}
+
+	b.sendEvent(events.EventShow, slotID, bannerID, groupID)
+	return nil
+}
+
+// recordShow записывает показ, уже учтенный в кеше при выборе,
+// и откатывает его в кеше при ошибке
+func (b *Bandit) recordShow(ctx context.Context, slotID, bannerID, groupID int) error {
+	err := b.storeShow(ctx, slotID, bannerID, groupID)
+	if err != nil {
+		b.unrecordShow(slotID, bannerID, groupID)
+	}
+	return err
+}
+
+// storeShow записывает показ в хранилище. Баннер, исчерпавший лимит,
+// исключается из выбора.
+func (b *Bandit) storeShow(ctx context.Context, slotID, bannerID, groupID int) error {
+	err := b.store.RecordShow(ctx, slotID, bannerID, groupID)
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, storage.ErrShowCapReached) {
+		b.markCapped(slotID, bannerID)
+		return fmt.Errorf("%w %d: banner %d", ErrShowCapReached, slotID, bannerID)
+	}
+	return fmt.Errorf("failed to record show: %w", err)
+}
+
+// unrecordShow откатывает показ, учтенный в кеше при выборе
+func (b *Bandit) unrecordShow(slotID, bannerID, groupID int) {
+	b.mu.RLock()
+	cache, ok := b.cache[b.getCacheKey(slotID, groupID)]
+	b.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if stat, exists := cache.banners[bannerID]; exists && stat.Shows > 0 {
+		stat.Shows--
+		cache.banners[bannerID] = stat
+		cache.totalShows--
+	}
+}
+
+// settleReservation снимает резерв баннера и, если показ записан,
+// учитывает его в кеше. Показ, подтвержденный на другом экземпляре,
+// учитывается без резерва.
+func (b *Bandit) settleReservation(slotID, bannerID, groupID int, shown bool) {
+	b.mu.RLock()
+	cache, ok := b.cache[b.getCacheKey(slotID, groupID)]
+	b.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.release(bannerID)
+	if !shown {
+		return
+	}
+	if stat, exists := cache.banners[bannerID]; exists {
+		stat.Shows++
+		cache.banners[bannerID] = stat
+		cache.totalShows++
+	}
+}
+
+// markCapped исключает баннер из выбора в слоте до изменения ротации
+func (b *Bandit) markCapped(slotID, bannerID int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	capped := make(map[int]struct{}, len(b.capped[slotID])+1)
+	for id := range b.capped[slotID] {
+		capped[id] = struct{}{}
+	}
+	capped[bannerID] = struct{}{}
+	b.capped[slotID] = capped
+}
+
+// pick выбирает баннер и сразу учитывает его в кеше, чтобы параллельные
+// запросы видели выбор до записи в хранилище: как показ или, если reserve,
+// как резерв до подтверждения
+func (b *Bandit) pick(ctx context.Context, slotID, groupID int, visitor storage.Visitor, reserve bool) (int, error) {
 	cache, err := b.loadStats(ctx, slotID, groupID)
 	if err != nil {
 		return 0, err
 	}
 
 	if len(cache.banners) == 0 {
-		return 0, fmt.Errorf("no banners in rotation for slot %d", slotID)
+		return 0, fmt.Errorf("%w %d", ErrNoBanners, slotID)
 	}
 
-	var bannerID int
 	var stat BannerStat
 
+	b.mu.RLock()
+	capped := b.capped[slotID]
+	b.mu.RUnlock()
+
+	now := b.now()
+
 	// Полностью защищаем работу с кешом
 	cache.mu.Lock()
-	bannerID = b.chooseBannerSafe(cache) // Теперь безопасно
+	bannerID, filtered := b.chooseBannerSafe(cache, visitor, capped, now)
+	if bannerID == 0 {
+		cache.mu.Unlock()
+		if filtered {
+			return 0, fmt.Errorf("%w %d", ErrNoEligibleBanners, slotID)
+		}
+		return 0, fmt.Errorf("%w %d: all banners are inactive or reached show cap", ErrNoBanners, slotID)
+	}
+
+	if reserve {
+		cache.reserve(bannerID, now.Add(b.reservationTTL))
+		cache.mu.Unlock()
+		return bannerID, nil
+	}
 
 	// Обновляем статистику сразу в этом же блоке
 	stat = cache.banners[bannerID]
@@ -175,11 +557,6 @@ func (b *Bandit) ChooseBanner(ctx context.Context, slotID, groupID int) (int, er
 	cache.totalShows++
 	cache.mu.Unlock()
 
-	if err := b.store.RecordShow(ctx, slotID, bannerID, groupID); err != nil {
-		return 0, fmt.Errorf("failed to record show: %w", err)
-	}
-
-	b.sendEvent(events.EventShow, slotID, bannerID, groupID)
 	return bannerID, nil
 }
 
@@ -226,8 +603,13 @@ func (b *Bandit) RecordClick(ctx context.Context, slotID, bannerID, groupID int)
 	return nil
 }
 
-// AddBannerToSlot добавляет баннер в ротацию слота
+// AddBannerToSlot добавляет баннер в ротацию слота.
+// Возвращает *SizeMismatchError, если размер баннера не подходит слоту.
 func (b *Bandit) AddBannerToSlot(ctx context.Context, slotID, bannerID int) error {
+	if err := b.checkSize(ctx, slotID, bannerID); err != nil {
+		return err
+	}
+
 	if err := b.store.AddBannerToSlot(ctx, slotID, bannerID); err != nil {
 		return fmt.Errorf("failed to add banner to slot: %w", err)
 	}
@@ -237,6 +619,57 @@ func (b *Bandit) AddBannerToSlot(ctx context.Context, slotID, bannerID int) erro
 	return nil
 }
 
+// checkSize сверяет размеры креативов баннеров с размерами слота. Слоты и баннеры
+// вне справочника не проверяются: допустимы ли они, решает хранилище.
+// Баннер без размеров в слот с ограничениями не попадает.
+func (b *Bandit) checkSize(ctx context.Context, slotID int, bannerIDs ...int) error {
+	if len(bannerIDs) == 0 {
+		return nil
+	}
+
+	slot, err := b.store.GetSlot(ctx, slotID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get slot: %w", err)
+	}
+	return b.checkFits(ctx, slot, bannerIDs...)
+}
+
+// checkFits сверяет размеры креативов баннеров из справочника с размерами slot
+func (b *Bandit) checkFits(ctx context.Context, slot storage.Slot, bannerIDs ...int) error {
+	if len(slot.Sizes) == 0 {
+		return nil
+	}
+
+	banners, err := b.store.GetBanners(ctx, bannerIDs)
+	if err != nil {
+		return fmt.Errorf("failed to get banners: %w", err)
+	}
+
+	for _, banner := range banners {
+		if !slot.Accepts(banner.Size()) {
+			return &SizeMismatchError{SlotID: slot.ID, BannerID: banner.ID, Size: banner.Size(), Allowed: slot.Sizes}
+		}
+	}
+	return nil
+}
+
+// UpdateSlot изменяет слот в справочнике. Возвращает *SizeMismatchError,
+// если новые размеры не подходят баннеру, уже находящемуся в ротации слота.
+func (b *Bandit) UpdateSlot(ctx context.Context, slot storage.Slot) error {
+	bannerIDs, err := b.store.GetBannersForSlot(ctx, slot.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get banners: %w", err)
+	}
+	if err := b.checkFits(ctx, slot, bannerIDs...); err != nil {
+		return err
+	}
+
+	return b.store.UpdateSlot(ctx, slot)
+}
+
 // RemoveBannerFromSlot удаляет баннер из ротации слота
 func (b *Bandit) RemoveBannerFromSlot(ctx context.Context, slotID, bannerID int) error {
 	if err := b.store.RemoveBannerFromSlot(ctx, slotID, bannerID); err != nil {
@@ -247,11 +680,36 @@ func (b *Bandit) RemoveBannerFromSlot(ctx context.Context, slotID, bannerID int)
 	return nil
 }
 
+// UpdateSlotRotation атомарно применяет пакетное изменение ротации слота
+// и возвращает итоговый список баннеров. Размеры всех добавляемых баннеров
+// проверяются до изменения: при *SizeMismatchError ротация не меняется.
+// Кеш слота сбрасывается один раз на все изменение.
+func (b *Bandit) UpdateSlotRotation(ctx context.Context, slotID int, change storage.RotationChange) ([]int, error) {
+	if err := b.checkSize(ctx, slotID, change.Add...); err != nil {
+		return nil, err
+	}
+
+	if err := b.store.UpdateSlotRotation(ctx, slotID, change); err != nil {
+		return nil, fmt.Errorf("failed to update slot rotation: %w", err)
+	}
+	b.clearCacheForSlot(slotID)
+
+	bannerIDs, err := b.store.GetBannersForSlot(ctx, slotID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get banners for slot: %w", err)
+	}
+	slices.Sort(bannerIDs)
+	return bannerIDs, nil
+}
+
 // clearCacheForSlot очищает кеш для всех групп в указанном слоте
 func (b *Bandit) clearCacheForSlot(slotID int) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
+	// Баннер, вернувшийся в ротацию, получает новый лимит показов
+	delete(b.capped, slotID)
+
 	// Удаляем все кеши, которые относятся к этому слоту
 	for key := range b.cache {
 		var sID int
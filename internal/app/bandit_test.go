@@ -3,8 +3,8 @@ package app
 import (
 	"banner-rotation/internal/pkg/events"
 	"banner-rotation/internal/storage"
+	"banner-rotation/internal/storage/memory"
 	"context"
-	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -13,122 +13,35 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-// MockStorage - полная реализация mock-хранилища
-type MockStorage struct {
-	mu          sync.RWMutex
-	stats       map[string]storage.BannerStat // ключ: "slotID_groupID_bannerID"
-	bannerSlots map[int]map[int]bool          // slotID -> bannerID -> true
-}
-
-func NewMockStorage() *MockStorage {
-	return &MockStorage{
-		stats:       make(map[string]storage.BannerStat),
-		bannerSlots: make(map[int]map[int]bool),
-	}
-}
-
-func (m *MockStorage) key(slotID, groupID, bannerID int) string {
-	return fmt.Sprintf("%d_%d_%d", slotID, groupID, bannerID)
-}
-
-func (m *MockStorage) AddBannerToSlot(ctx context.Context, slotID, bannerID int) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	if _, ok := m.bannerSlots[slotID]; !ok {
-		m.bannerSlots[slotID] = make(map[int]bool)
-	}
-	m.bannerSlots[slotID][bannerID] = true
-	return nil
-}
-
-func (m *MockStorage) RemoveBannerFromSlot(ctx context.Context, slotID, bannerID int) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	if banners, ok := m.bannerSlots[slotID]; ok {
-		delete(banners, bannerID)
-	}
-	return nil
-}
-
-func (m *MockStorage) RecordShow(ctx context.Context, slotID, bannerID, groupID int) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+type MockProducer struct{}
 
-	key := m.key(slotID, groupID, bannerID)
-	stat := m.stats[key]
-	stat.BannerID = bannerID
-	stat.Shows++
-	m.stats[key] = stat
+func (m *MockProducer) SendEvent(ctx context.Context, eventType events.EventType, slotID, bannerID, groupID int) error {
 	return nil
 }
 
-func (m *MockStorage) RecordClick(ctx context.Context, slotID, bannerID, groupID int) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	key := m.key(slotID, groupID, bannerID)
-	stat := m.stats[key]
-	stat.BannerID = bannerID
-	stat.Clicks++
-	m.stats[key] = stat
+func (m *MockProducer) Close() error {
 	return nil
 }
 
-func (m *MockStorage) GetBannerStats(ctx context.Context, slotID, groupID int) ([]storage.BannerStat, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	var stats []storage.BannerStat
-	for key, stat := range m.stats {
-		var sID, gID, bID int
-		_, err := fmt.Sscanf(key, "%d_%d_%d", &sID, &gID, &bID)
-		if err != nil {
-			continue
-		}
-		if sID == slotID && gID == groupID {
-			// Проверяем что баннер все еще в слоте
-			if banners, ok := m.bannerSlots[slotID]; ok {
-				if _, exists := banners[bID]; exists {
-					stats = append(stats, stat)
-				}
-			}
-		}
+// newTestStore создает хранилище в памяти со слотами 1..slots
+// и баннерами 1..banners без ограничений размера
+func newTestStore(t *testing.T, slots, banners int) *memory.MemoryStorage {
+	t.Helper()
+	ctx := context.Background()
+	store := memory.New()
+	for id := 1; id <= slots; id++ {
+		_, err := store.CreateSlot(ctx, storage.Slot{ID: id})
+		require.NoError(t, err)
 	}
-	return stats, nil
-}
-
-func (m *MockStorage) GetBannersForSlot(ctx context.Context, slotID int) ([]int, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	if banners, ok := m.bannerSlots[slotID]; ok {
-		ids := make([]int, 0, len(banners))
-		for id := range banners {
-			ids = append(ids, id)
-		}
-		return ids, nil
+	for id := 1; id <= banners; id++ {
+		_, err := store.CreateBanner(ctx, storage.Banner{ID: id})
+		require.NoError(t, err)
 	}
-	return nil, nil
-}
-
-func (m *MockStorage) Close() error {
-	return nil
-}
-
-type MockProducer struct{}
-
-func (m *MockProducer) SendEvent(ctx context.Context, eventType events.EventType, slotID, bannerID, groupID int) error {
-	return nil
-}
-
-func (m *MockProducer) Close() error {
-	return nil
+	return store
 }
 
 func TestBandit_New(t *testing.T) {
-	store := NewMockStorage()
+	store := memory.New()
 	producer := &MockProducer{} // Используем mock, реализующий интерфейс
 
 	bandit := NewBandit(store, producer)
@@ -136,19 +49,19 @@ func TestBandit_New(t *testing.T) {
 }
 
 func TestBandit_ChooseBanner_NoBanners(t *testing.T) {
-	store := NewMockStorage()
+	store := memory.New()
 	producer := &MockProducer{} // Используем mock, реализующий интерфейс
 
 	bandit := NewBandit(store, producer)
 	ctx := context.Background()
 
-	_, err := bandit.ChooseBanner(ctx, 1, 1)
+	_, err := bandit.ChooseBanner(ctx, 1, 1, storage.Visitor{})
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "no banners in rotation")
 }
 
 func TestBandit_ChooseBanner_NewBanners(t *testing.T) {
-	store := NewMockStorage()
+	store := newTestStore(t, 2, 4)
 	producer := &MockProducer{} // Используем mock, реализующий интерфейс
 
 	bandit := NewBandit(store, producer)
@@ -159,7 +72,7 @@ func TestBandit_ChooseBanner_NewBanners(t *testing.T) {
 
 	selected := make(map[int]bool)
 	for i := 0; i < 100; i++ {
-		bannerID, err := bandit.ChooseBanner(ctx, 1, 1)
+		bannerID, err := bandit.ChooseBanner(ctx, 1, 1, storage.Visitor{})
 		require.NoError(t, err)
 		selected[bannerID] = true
 	}
@@ -169,7 +82,7 @@ func TestBandit_ChooseBanner_NewBanners(t *testing.T) {
 }
 
 func TestBandit_ChooseBanner_PrefersBetter(t *testing.T) {
-	store := NewMockStorage()
+	store := newTestStore(t, 2, 4)
 	producer := &MockProducer{} // Используем mock, реализующий интерфейс
 
 	bandit := NewBandit(store, producer)
@@ -197,7 +110,7 @@ func TestBandit_ChooseBanner_PrefersBetter(t *testing.T) {
 
 	counts := make(map[int]int)
 	for i := 0; i < 1000; i++ {
-		bannerID, err := bandit.ChooseBanner(ctx, 1, 1)
+		bannerID, err := bandit.ChooseBanner(ctx, 1, 1, storage.Visitor{})
 		require.NoError(t, err)
 		counts[bannerID]++
 	}
@@ -206,7 +119,7 @@ func TestBandit_ChooseBanner_PrefersBetter(t *testing.T) {
 }
 
 func TestBandit_ChooseBanner_NewBannerGetsChance(t *testing.T) {
-	store := NewMockStorage()
+	store := newTestStore(t, 2, 4)
 	producer := &MockProducer{} // Используем mock, реализующий интерфейс
 
 	bandit := NewBandit(store, producer)
@@ -230,7 +143,7 @@ func TestBandit_ChooseBanner_NewBannerGetsChance(t *testing.T) {
 
 	found := false
 	for i := 0; i < 100; i++ {
-		bannerID, err := bandit.ChooseBanner(ctx, 1, 1)
+		bannerID, err := bandit.ChooseBanner(ctx, 1, 1, storage.Visitor{})
 		require.NoError(t, err)
 		if bannerID == 2 {
 			found = true
@@ -242,7 +155,7 @@ func TestBandit_ChooseBanner_NewBannerGetsChance(t *testing.T) {
 }
 
 func TestBandit_AddNewBanner(t *testing.T) {
-	store := NewMockStorage()
+	store := newTestStore(t, 2, 4)
 	producer := &MockProducer{}
 	bandit := NewBandit(store, producer)
 	ctx := context.Background()
@@ -252,14 +165,14 @@ func TestBandit_AddNewBanner(t *testing.T) {
 
 	// Добавляем баннер 2 после 100 итераций
 	for i := 0; i < 100; i++ {
-		_, _ = bandit.ChooseBanner(ctx, 1, 1)
+		_, _ = bandit.ChooseBanner(ctx, 1, 1, storage.Visitor{})
 	}
 	require.NoError(t, bandit.AddBannerToSlot(ctx, 1, 2))
 
 	// Проверяем, что новый баннер появляется в выборе
 	found := false
 	for i := 0; i < 50; i++ {
-		bID, _ := bandit.ChooseBanner(ctx, 1, 1)
+		bID, _ := bandit.ChooseBanner(ctx, 1, 1, storage.Visitor{})
 		if bID == 2 {
 			found = true
 			break
@@ -269,7 +182,7 @@ func TestBandit_AddNewBanner(t *testing.T) {
 }
 
 func TestBandit_RecordClick(t *testing.T) {
-	store := NewMockStorage()
+	store := newTestStore(t, 2, 4)
 	producer := &MockProducer{} // Используем mock, реализующий интерфейс
 
 	bandit := NewBandit(store, producer)
@@ -277,7 +190,7 @@ func TestBandit_RecordClick(t *testing.T) {
 
 	require.NoError(t, bandit.AddBannerToSlot(ctx, 1, 1))
 
-	bannerID, err := bandit.ChooseBanner(ctx, 1, 1)
+	bannerID, err := bandit.ChooseBanner(ctx, 1, 1, storage.Visitor{})
 	require.NoError(t, err)
 	assert.Equal(t, 1, bannerID)
 
@@ -289,13 +202,196 @@ func TestBandit_RecordClick(t *testing.T) {
 	assert.Equal(t, 1, stats[0].Clicks)
 }
 
+func TestBandit_ReserveBanner(t *testing.T) {
+	store := newTestStore(t, 2, 4)
+	bandit := NewBandit(store, &MockProducer{})
+	ctx := context.Background()
+
+	require.NoError(t, bandit.AddBannerToSlot(ctx, 1, 1))
+	shows := func() int {
+		stats, err := store.GetBannerStats(ctx, 1, 1)
+		require.NoError(t, err)
+		total := 0
+		for _, stat := range stats {
+			total += stat.Shows
+		}
+		return total
+	}
+
+	bannerID, err := bandit.ReserveBanner(ctx, 1, 1, storage.Visitor{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, bannerID)
+	assert.Zero(t, shows(), "reservation is not a show")
+
+	decisions := bandit.ReserveBanners(ctx, []SlotChoice{{SlotID: 1, GroupID: 1}}, storage.Visitor{})
+	require.NoError(t, decisions[0].Err)
+	assert.Zero(t, shows())
+
+	require.NoError(t, bandit.ConfirmShow(ctx, 1, bannerID, 1))
+	assert.Equal(t, 1, shows())
+
+	assert.ErrorIs(t, bandit.ConfirmShow(ctx, 1, 2, 1), storage.ErrNotFound, "banner is not in slot")
+}
+
+func TestBandit_ReservationCache(t *testing.T) {
+	store := newTestStore(t, 2, 4)
+	bandit := NewBandit(store, &MockProducer{})
+	ctx := context.Background()
+
+	require.NoError(t, bandit.AddBannerToSlot(ctx, 1, 1))
+	require.NoError(t, bandit.AddBannerToSlot(ctx, 1, 2))
+
+	first, err := bandit.ReserveBanner(ctx, 1, 1, storage.Visitor{})
+	require.NoError(t, err)
+
+	cache, err := bandit.loadStats(ctx, 1, 1)
+	require.NoError(t, err)
+	assert.Zero(t, cache.totalShows, "reservation is not a show")
+	assert.Zero(t, cache.banners[first].Shows)
+
+	// Неподтвержденный выбор учитывается при ротации
+	second, err := bandit.ReserveBanner(ctx, 1, 1, storage.Visitor{})
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+
+	// Подтверждение переносит резерв в показы
+	require.NoError(t, bandit.ConfirmShow(ctx, 1, first, 1))
+	assert.Equal(t, 1, cache.totalShows)
+	assert.Equal(t, 1, cache.banners[first].Shows)
+	assert.Zero(t, cache.reservedShows(first, bandit.now()))
+
+	// Неподтвержденный выбор истекает, не увеличивая показы
+	expired := bandit.now().Add(DefaultReservationTTL + time.Minute)
+	assert.Equal(t, 1, cache.reservedShows(second, bandit.now()))
+	assert.Zero(t, cache.reservedShows(second, expired))
+	assert.Zero(t, cache.banners[second].Shows)
+	assert.Equal(t, 1, cache.totalShows)
+}
+
+// cappedStore ограничивает число показов каждого баннера в слоте, как redis
+type cappedStore struct {
+	*memory.MemoryStorage
+	limit int
+	shows map[int]int
+}
+
+func (s *cappedStore) RecordShow(ctx context.Context, slotID, bannerID, groupID int) error {
+	if s.shows[bannerID] >= s.limit {
+		return storage.ErrShowCapReached
+	}
+	s.shows[bannerID]++
+	return s.MemoryStorage.RecordShow(ctx, slotID, bannerID, groupID)
+}
+
+func TestBandit_ShowCap(t *testing.T) {
+	store := &cappedStore{MemoryStorage: newTestStore(t, 1, 3), limit: 2, shows: make(map[int]int)}
+	bandit := NewBandit(store, &MockProducer{})
+	ctx := context.Background()
+
+	require.NoError(t, bandit.AddBannerToSlot(ctx, 1, 1))
+	require.NoError(t, bandit.AddBannerToSlot(ctx, 1, 2))
+
+	// Исчерпавший лимит баннер исключается, и показ отдается другому
+	for i := 0; i < 4; i++ {
+		_, err := bandit.ChooseBanner(ctx, 1, 1, storage.Visitor{})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, map[int]int{1: 2, 2: 2}, store.shows)
+
+	_, err := bandit.ChooseBanner(ctx, 1, 1, storage.Visitor{})
+	assert.ErrorIs(t, err, ErrNoBanners)
+
+	decisions := bandit.ChooseBanners(ctx, []SlotChoice{{SlotID: 1, GroupID: 2}}, storage.Visitor{})
+	assert.ErrorIs(t, decisions[0].Err, ErrNoBanners)
+
+	// Кеш содержит только записанные показы
+	cache, err := bandit.loadStats(ctx, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 4, cache.totalShows)
+
+	err = bandit.ConfirmShow(ctx, 1, 1, 1)
+	assert.ErrorIs(t, err, ErrShowCapReached)
+	assert.ErrorIs(t, err, storage.ErrConflict)
+
+	// Изменение ротации снимает исключение
+	require.NoError(t, bandit.AddBannerToSlot(ctx, 1, 3))
+	bannerID, err := bandit.ChooseBanner(ctx, 1, 1, storage.Visitor{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, bannerID)
+}
+
+// countingStore считает чтения справочника слотов
+type countingStore struct {
+	*memory.MemoryStorage
+	mu        sync.Mutex
+	listSlots int
+	err       error
+}
+
+func (s *countingStore) ListSlots(ctx context.Context) ([]storage.Slot, error) {
+	s.mu.Lock()
+	s.listSlots++
+	err := s.err
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return s.MemoryStorage.ListSlots(ctx)
+}
+
+func TestBandit_Warm(t *testing.T) {
+	store := &countingStore{MemoryStorage: memory.New(), err: storage.ErrUnavailable}
+	bandit := NewBandit(store, &MockProducer{})
+	ctx := context.Background()
+
+	for _, id := range []int{1, 2} {
+		_, err := store.CreateSlot(ctx, storage.Slot{ID: id})
+		require.NoError(t, err)
+		_, err = store.CreateGroup(ctx, storage.Group{ID: id})
+		require.NoError(t, err)
+	}
+	_, err := store.CreateBanner(ctx, storage.Banner{ID: 1})
+	require.NoError(t, err)
+	require.NoError(t, bandit.AddBannerToSlot(ctx, 1, 1))
+
+	assert.ErrorIs(t, bandit.Warm(ctx), storage.ErrUnavailable)
+	assert.ErrorIs(t, bandit.Warmed(ctx), ErrCacheWarming)
+
+	store.err = nil
+	require.NoError(t, bandit.Warm(ctx))
+	require.NoError(t, bandit.Warmed(ctx))
+	bandit.mu.RLock()
+	assert.Contains(t, bandit.cache, "1_1")
+	assert.Contains(t, bandit.cache, "1_2")
+	assert.NotContains(t, bandit.cache, "2_1", "slot without banners is skipped")
+	bandit.mu.RUnlock()
+
+	require.NoError(t, bandit.Warm(ctx))
+	assert.Equal(t, 2, store.listSlots, "warm cache is not reloaded")
+}
+
+func TestBandit_StartWarm(t *testing.T) {
+	store := &countingStore{MemoryStorage: memory.New(), err: storage.ErrUnavailable}
+	bandit := NewBandit(store, &MockProducer{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bandit.StartWarm(ctx, 10*time.Millisecond)
+	assert.ErrorIs(t, bandit.Warmed(ctx), ErrCacheWarming)
+
+	store.mu.Lock()
+	store.err = nil
+	store.mu.Unlock()
+	assert.Eventually(t, func() bool { return bandit.Warmed(ctx) == nil }, time.Second, 10*time.Millisecond)
+}
+
 func TestBandit_CacheUpdate(t *testing.T) {
-	store := NewMockStorage()
+	store := newTestStore(t, 2, 4)
 	producer := &MockProducer{}
 	bandit := NewBandit(store, producer)
 	ctx := context.Background()
 	require.NoError(t, bandit.AddBannerToSlot(ctx, 1, 1))
-	bannerID, err := bandit.ChooseBanner(ctx, 1, 1)
+	bannerID, err := bandit.ChooseBanner(ctx, 1, 1, storage.Visitor{})
 	require.NoError(t, err)
 	assert.Equal(t, 1, bannerID)
 	key := bandit.getCacheKey(1, 1)
@@ -333,7 +429,7 @@ func TestBandit_CacheUpdate(t *testing.T) {
 }
 
 func TestBandit_CacheClearOnRemove(t *testing.T) {
-	store := NewMockStorage()
+	store := newTestStore(t, 2, 4)
 	producer := &MockProducer{} // Используем mock, реализующий интерфейс
 
 	bandit := NewBandit(store, producer)
@@ -341,7 +437,7 @@ func TestBandit_CacheClearOnRemove(t *testing.T) {
 
 	require.NoError(t, bandit.AddBannerToSlot(ctx, 1, 1))
 
-	_, err := bandit.ChooseBanner(ctx, 1, 1)
+	_, err := bandit.ChooseBanner(ctx, 1, 1, storage.Visitor{})
 	require.NoError(t, err)
 
 	key := bandit.getCacheKey(1, 1)
@@ -359,7 +455,7 @@ func TestBandit_CacheClearOnRemove(t *testing.T) {
 }
 
 func TestBandit_AddRemoveBanners(t *testing.T) {
-	store := NewMockStorage()
+	store := newTestStore(t, 2, 4)
 	producer := &MockProducer{} // Используем mock, реализующий интерфейс
 
 	bandit := NewBandit(store, producer)
@@ -369,7 +465,7 @@ func TestBandit_AddRemoveBanners(t *testing.T) {
 	require.NoError(t, bandit.AddBannerToSlot(ctx, 1, 1))
 
 	// Выбираем баннер
-	bannerID, err := bandit.ChooseBanner(ctx, 1, 1)
+	bannerID, err := bandit.ChooseBanner(ctx, 1, 1, storage.Visitor{})
 	require.NoError(t, err)
 	assert.Equal(t, 1, bannerID)
 
@@ -377,13 +473,155 @@ func TestBandit_AddRemoveBanners(t *testing.T) {
 	require.NoError(t, bandit.RemoveBannerFromSlot(ctx, 1, 1))
 
 	// Пытаемся выбрать снова
-	_, err = bandit.ChooseBanner(ctx, 1, 1)
+	_, err = bandit.ChooseBanner(ctx, 1, 1, storage.Visitor{})
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "no banners in rotation")
 }
 
+func TestBandit_AddBannerToSlot_SizeMismatch(t *testing.T) {
+	store := memory.New()
+	bandit := NewBandit(store, &MockProducer{})
+	ctx := context.Background()
+
+	_, err := store.CreateSlot(ctx, storage.Slot{ID: 1, Description: "sidebar", Sizes: []storage.Size{{Width: 300, Height: 250}}})
+	require.NoError(t, err)
+	_, err = store.CreateBanner(ctx, storage.Banner{ID: 1, Creative: storage.Creative{Width: 300, Height: 250}})
+	require.NoError(t, err)
+	_, err = store.CreateBanner(ctx, storage.Banner{ID: 2, Creative: storage.Creative{Width: 728, Height: 90}})
+	require.NoError(t, err)
+	_, err = store.CreateBanner(ctx, storage.Banner{ID: 3, Description: "no dimensions"})
+	require.NoError(t, err)
+
+	require.NoError(t, bandit.AddBannerToSlot(ctx, 1, 1))
+
+	var mismatch *SizeMismatchError
+	err = bandit.AddBannerToSlot(ctx, 1, 2)
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, storage.Size{Width: 728, Height: 90}, mismatch.Size)
+	assert.EqualError(t, err, "banner 2 of size 728x90 does not fit slot 1 (allowed: 300x250)")
+
+	require.ErrorAs(t, bandit.AddBannerToSlot(ctx, 1, 3), &mismatch)
+
+	banners, err := store.GetBannersForSlot(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, banners)
+
+	// Слот без ограничений принимает любой баннер
+	_, err = store.CreateSlot(ctx, storage.Slot{ID: 2})
+	require.NoError(t, err)
+	require.NoError(t, bandit.AddBannerToSlot(ctx, 2, 2))
+}
+
+func TestBandit_SizeMismatchOnUpdate(t *testing.T) {
+	store := memory.New()
+	bandit := NewBandit(store, &MockProducer{})
+	ctx := context.Background()
+
+	sidebar := storage.Slot{ID: 1, Description: "sidebar", Sizes: []storage.Size{{Width: 300, Height: 250}}}
+	_, err := store.CreateSlot(ctx, sidebar)
+	require.NoError(t, err)
+	_, err = store.CreateSlot(ctx, storage.Slot{ID: 2, Description: "any size"})
+	require.NoError(t, err)
+	banner := storage.Banner{ID: 1, Creative: storage.Creative{Width: 300, Height: 250}}
+	_, err = store.CreateBanner(ctx, banner)
+	require.NoError(t, err)
+	require.NoError(t, bandit.AddBannerToSlot(ctx, 1, 1))
+	require.NoError(t, bandit.AddBannerToSlot(ctx, 2, 1))
+
+	t.Run("banner resize", func(t *testing.T) {
+		resized := banner
+		resized.Creative = storage.Creative{Width: 728, Height: 90}
+
+		var mismatch *SizeMismatchError
+		require.ErrorAs(t, bandit.UpdateBanner(ctx, resized), &mismatch)
+		assert.Equal(t, 1, mismatch.SlotID)
+		assert.ErrorIs(t, mismatch, storage.ErrInvalid)
+
+		current, err := store.GetBanner(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, banner.Creative, current.Creative)
+
+		resized.Description = "same size"
+		resized.Creative = banner.Creative
+		require.NoError(t, bandit.UpdateBanner(ctx, resized))
+	})
+
+	t.Run("slot sizes change", func(t *testing.T) {
+		narrowed := sidebar
+		narrowed.Sizes = []storage.Size{{Width: 728, Height: 90}}
+
+		var mismatch *SizeMismatchError
+		require.ErrorAs(t, bandit.UpdateSlot(ctx, narrowed), &mismatch)
+		assert.Equal(t, 1, mismatch.BannerID)
+
+		current, err := store.GetSlot(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, sidebar.Sizes, current.Sizes)
+
+		// Расширение размеров и снятие ограничений допустимы
+		narrowed.Sizes = append(narrowed.Sizes, storage.Size{Width: 300, Height: 250})
+		require.NoError(t, bandit.UpdateSlot(ctx, narrowed))
+		narrowed.Sizes = nil
+		require.NoError(t, bandit.UpdateSlot(ctx, narrowed))
+	})
+}
+
+func TestBandit_UpdateSlotRotation(t *testing.T) {
+	store := memory.New()
+	bandit := NewBandit(store, &MockProducer{})
+	ctx := context.Background()
+
+	_, err := store.CreateSlot(ctx, storage.Slot{ID: 1, Description: "sidebar", Sizes: []storage.Size{{Width: 300, Height: 250}}})
+	require.NoError(t, err)
+	for id := 1; id <= 3; id++ {
+		_, err = store.CreateBanner(ctx, storage.Banner{ID: id, Creative: storage.Creative{Width: 300, Height: 250}})
+		require.NoError(t, err)
+	}
+	_, err = store.CreateBanner(ctx, storage.Banner{ID: 4, Creative: storage.Creative{Width: 728, Height: 90}})
+	require.NoError(t, err)
+
+	banners, err := bandit.UpdateSlotRotation(ctx, 1, storage.RotationChange{Add: []int{2, 1}})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, banners)
+
+	_, err = bandit.ChooseBanner(ctx, 1, 1, storage.Visitor{})
+	require.NoError(t, err)
+	key := bandit.getCacheKey(1, 1)
+
+	t.Run("size mismatch leaves rotation unchanged", func(t *testing.T) {
+		var mismatch *SizeMismatchError
+		_, err := bandit.UpdateSlotRotation(ctx, 1, storage.RotationChange{Add: []int{3, 4}, Remove: []int{1}})
+		require.ErrorAs(t, err, &mismatch)
+		assert.Equal(t, 4, mismatch.BannerID)
+
+		banners, err := store.GetBannersForSlot(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2}, banners)
+
+		bandit.mu.RLock()
+		_, exists := bandit.cache[key]
+		bandit.mu.RUnlock()
+		assert.True(t, exists, "cache should survive a rejected change")
+	})
+
+	t.Run("replace clears cache", func(t *testing.T) {
+		banners, err := bandit.UpdateSlotRotation(ctx, 1, storage.RotationChange{Add: []int{3}, Replace: true})
+		require.NoError(t, err)
+		assert.Equal(t, []int{3}, banners)
+
+		bandit.mu.RLock()
+		_, exists := bandit.cache[key]
+		bandit.mu.RUnlock()
+		assert.False(t, exists, "cache should be cleared for slot")
+
+		bannerID, err := bandit.ChooseBanner(ctx, 1, 1, storage.Visitor{})
+		require.NoError(t, err)
+		assert.Equal(t, 3, bannerID)
+	})
+}
+
 func TestBandit_StatsPersistence(t *testing.T) {
-	store := NewMockStorage()
+	store := newTestStore(t, 2, 4)
 	producer := &MockProducer{} // Используем mock, реализующий интерфейс
 
 	bandit1 := NewBandit(store, producer)
@@ -393,7 +631,7 @@ func TestBandit_StatsPersistence(t *testing.T) {
 	require.NoError(t, bandit1.AddBannerToSlot(ctx, 1, 1))
 
 	// Регистрируем действия
-	_, err := bandit1.ChooseBanner(ctx, 1, 1)
+	_, err := bandit1.ChooseBanner(ctx, 1, 1, storage.Visitor{})
 	require.NoError(t, err)
 	require.NoError(t, bandit1.RecordClick(ctx, 1, 1, 1))
 
@@ -401,7 +639,7 @@ func TestBandit_StatsPersistence(t *testing.T) {
 	bandit2 := NewBandit(store, producer)
 
 	// Выбираем баннер
-	bannerID, err := bandit2.ChooseBanner(ctx, 1, 1)
+	bannerID, err := bandit2.ChooseBanner(ctx, 1, 1, storage.Visitor{})
 	require.NoError(t, err)
 	assert.Equal(t, 1, bannerID)
 
@@ -417,7 +655,7 @@ func TestBandit_StatsPersistence(t *testing.T) {
 }
 
 func TestBandit_MultipleSlotsGroups(t *testing.T) {
-	store := NewMockStorage()
+	store := newTestStore(t, 2, 4)
 	producer := &MockProducer{} // Используем mock, реализующий интерфейс
 
 	bandit := NewBandit(store, producer)
@@ -432,17 +670,17 @@ func TestBandit_MultipleSlotsGroups(t *testing.T) {
 	require.NoError(t, bandit.AddBannerToSlot(ctx, 2, 4))
 
 	// Группа 1
-	banner1, err := bandit.ChooseBanner(ctx, 1, 1)
+	banner1, err := bandit.ChooseBanner(ctx, 1, 1, storage.Visitor{})
 	require.NoError(t, err)
 	assert.True(t, banner1 == 1 || banner1 == 2)
 
 	// Группа 2
-	banner2, err := bandit.ChooseBanner(ctx, 1, 2)
+	banner2, err := bandit.ChooseBanner(ctx, 1, 2, storage.Visitor{})
 	require.NoError(t, err)
 	assert.True(t, banner2 == 1 || banner2 == 2)
 
 	// Слот 2, группа 1
-	banner3, err := bandit.ChooseBanner(ctx, 2, 1)
+	banner3, err := bandit.ChooseBanner(ctx, 2, 1, storage.Visitor{})
 	require.NoError(t, err)
 	assert.True(t, banner3 == 3 || banner3 == 4)
 
@@ -451,7 +689,7 @@ func TestBandit_MultipleSlotsGroups(t *testing.T) {
 }
 
 func TestBandit_Performance(t *testing.T) {
-	store := NewMockStorage()
+	store := newTestStore(t, 1, 100)
 	producer := &MockProducer{} // Используем mock, реализующий интерфейс
 
 	bandit := NewBandit(store, producer)
@@ -464,7 +702,7 @@ func TestBandit_Performance(t *testing.T) {
 
 	start := time.Now()
 	for i := 0; i < 1000; i++ {
-		_, err := bandit.ChooseBanner(ctx, 1, 1)
+		_, err := bandit.ChooseBanner(ctx, 1, 1, storage.Visitor{})
 		require.NoError(t, err)
 	}
 	duration := time.Since(start)
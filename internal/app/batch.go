@@ -0,0 +1,99 @@
+package app
+
+import (
+	"banner-rotation/internal/pkg/events"
+	"banner-rotation/internal/storage"
+	"context"
+	"errors"
+)
+
+// SlotChoice - слот и группа для пакетного выбора баннеров
+type SlotChoice struct {
+	SlotID  int
+	GroupID int
+}
+
+// SlotDecision - результат выбора для одного слота пакета.
+// Err != nil, если баннер для слота выбрать или засчитать не удалось.
+type SlotDecision struct {
+	SlotID   int
+	GroupID  int
+	BannerID int
+	Err      error
+}
+
+// ChooseBanners выбирает баннеры для нескольких слотов одной страницы.
+// Ошибка одного слота не влияет на остальные. Показы записываются одной
+// пачкой, если хранилище поддерживает storage.BatchRecorder.
+func (b *Bandit) ChooseBanners(ctx context.Context, choices []SlotChoice, visitor storage.Visitor) []SlotDecision {
+	decisions := b.pickAll(ctx, choices, visitor, false)
+	b.recordShows(ctx, decisions, visitor)
+
+	for _, d := range decisions {
+		if d.Err == nil {
+			b.sendEvent(events.EventShow, d.SlotID, d.BannerID, d.GroupID)
+		}
+	}
+	return decisions
+}
+
+// ReserveBanners выбирает баннеры как ChooseBanners, не записывая показы:
+// каждый выбор подтверждается ConfirmShow отдельно
+func (b *Bandit) ReserveBanners(ctx context.Context, choices []SlotChoice, visitor storage.Visitor) []SlotDecision {
+	return b.pickAll(ctx, choices, visitor, true)
+}
+
+// pickAll выбирает баннер для каждого слота пакета, как pick
+func (b *Bandit) pickAll(ctx context.Context, choices []SlotChoice, visitor storage.Visitor, reserve bool) []SlotDecision {
+	decisions := make([]SlotDecision, len(choices))
+	for i, choice := range choices {
+		bannerID, err := b.pick(ctx, choice.SlotID, choice.GroupID, visitor, reserve)
+		decisions[i] = SlotDecision{SlotID: choice.SlotID, GroupID: choice.GroupID, BannerID: bannerID, Err: err}
+	}
+	return decisions
+}
+
+// recordShows записывает показы выбранных баннеров. Если пачка отклонена,
+// незаписанные показы пишутся по одному, чтобы ошибка досталась только
+// своему слоту. Для баннера, исчерпавшего лимит показов, баннер выбирается заново.
+func (b *Bandit) recordShows(ctx context.Context, decisions []SlotDecision, visitor storage.Visitor) {
+	hour := storage.HourBucket(b.now())
+	var (
+		deltas  []storage.StatDelta
+		indexes []int
+	)
+	for i, d := range decisions {
+		if d.Err == nil {
+			deltas = append(deltas, storage.StatDelta{SlotID: d.SlotID, BannerID: d.BannerID, GroupID: d.GroupID, Hour: hour, Shows: 1})
+			indexes = append(indexes, i)
+		}
+	}
+	if len(deltas) == 0 {
+		return
+	}
+
+	var applied map[int]bool
+	if recorder, ok := b.store.(storage.BatchRecorder); ok {
+		err := recorder.RecordBatch(ctx, deltas)
+		if err == nil {
+			return
+		}
+		applied = storage.AppliedDeltas(err)
+	}
+
+	for k, i := range indexes {
+		if applied[k] {
+			continue
+		}
+		d := &decisions[i]
+		err := b.recordShow(ctx, d.SlotID, d.BannerID, d.GroupID)
+		if errors.Is(err, ErrShowCapReached) {
+			d.BannerID, d.Err = b.show(ctx, d.SlotID, d.GroupID, visitor)
+			continue
+		}
+		if err != nil {
+			d.BannerID = 0
+			d.Err = err
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"banner-rotation/internal/storage"
+	"banner-rotation/internal/storage/memory"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchStore считает пакетные записи показов
+type batchStore struct {
+	*memory.MemoryStorage
+	batches int
+}
+
+func (s *batchStore) RecordBatch(ctx context.Context, deltas []storage.StatDelta) error {
+	s.batches++
+	return s.MemoryStorage.RecordBatch(ctx, deltas)
+}
+
+func TestBandit_ChooseBanners(t *testing.T) {
+	store := &batchStore{MemoryStorage: newTestStore(t, 2, 2)}
+	bandit := NewBandit(store, &MockProducer{})
+	ctx := context.Background()
+
+	require.NoError(t, bandit.AddBannerToSlot(ctx, 1, 1))
+	require.NoError(t, bandit.AddBannerToSlot(ctx, 2, 2))
+
+	choices := []SlotChoice{{SlotID: 1, GroupID: 1}, {SlotID: 2, GroupID: 1}, {SlotID: 3, GroupID: 1}}
+	decisions := bandit.ChooseBanners(ctx, choices, storage.Visitor{})
+	require.Len(t, decisions, 3)
+	assert.Equal(t, SlotDecision{SlotID: 1, GroupID: 1, BannerID: 1}, decisions[0])
+	assert.Equal(t, SlotDecision{SlotID: 2, GroupID: 1, BannerID: 2}, decisions[1])
+	assert.ErrorIs(t, decisions[2].Err, ErrNoBanners)
+	assert.Equal(t, 1, store.batches, "shows are recorded in one batch")
+
+	for slotID := 1; slotID <= 2; slotID++ {
+		stats, err := store.GetBannerStats(ctx, slotID, 1)
+		require.NoError(t, err)
+		require.Len(t, stats, 1)
+		assert.Equal(t, 1, stats[0].Shows)
+	}
+
+	t.Run("rejected batch falls back to single shows", func(t *testing.T) {
+		// Баннер убран из ротации в обход кеша: пачка отклоняется целиком,
+		// но показ в первом слоте все равно засчитывается
+		require.NoError(t, store.RemoveBannerFromSlot(ctx, 2, 2))
+
+		decisions := bandit.ChooseBanners(ctx, choices[:2], storage.Visitor{})
+		require.NoError(t, decisions[0].Err)
+		assert.Equal(t, 1, decisions[0].BannerID)
+		assert.ErrorIs(t, decisions[1].Err, storage.ErrNotFound)
+		assert.Zero(t, decisions[1].BannerID)
+
+		stats, err := store.GetBannerStats(ctx, 1, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 2, stats[0].Shows)
+	})
+}
@@ -0,0 +1,175 @@
+package app
+
+import (
+	"banner-rotation/internal/storage"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// loadCampaigns отмечает в кеше баннеры, кампании которых сейчас не показываются,
+// и вычисляет момент, когда состав активных баннеров изменится сам собой.
+// Баннеры вне справочника и вне кампаний всегда активны. Баннеры и кампании
+// читаются двумя запросами независимо от размера ротации.
+func (b *Bandit) loadCampaigns(ctx context.Context, cache *banditCache, bannerIDs []int, now time.Time) error {
+	cache.inactive = make(map[int]struct{})
+	cache.campaigns = make(map[int]int)
+
+	banners, err := b.store.GetBanners(ctx, bannerIDs)
+	if err != nil {
+		return fmt.Errorf("failed to get banners: %w", err)
+	}
+	var campaignIDs []int
+	for _, banner := range banners {
+		if banner.CampaignID != 0 {
+			cache.campaigns[banner.ID] = banner.CampaignID
+			campaignIDs = append(campaignIDs, banner.CampaignID)
+		}
+	}
+	if len(campaignIDs) == 0 {
+		return nil
+	}
+
+	list, err := b.store.GetCampaigns(ctx, campaignIDs)
+	if err != nil {
+		return fmt.Errorf("failed to get campaigns: %w", err)
+	}
+	campaigns := make(map[int]storage.Campaign, len(list))
+	for _, campaign := range list {
+		campaigns[campaign.ID] = campaign
+	}
+
+	for bannerID, campaignID := range cache.campaigns {
+		campaign, ok := campaigns[campaignID]
+		if !ok {
+			return fmt.Errorf("failed to get campaign %d: %w", campaignID, storage.ErrNotFound)
+		}
+		if !campaign.Running(now) {
+			cache.inactive[bannerID] = struct{}{}
+		}
+	}
+
+	for _, campaign := range campaigns {
+		if campaign.Status != storage.CampaignActive {
+			continue
+		}
+		var boundary time.Time
+		switch {
+		case !campaign.StartAt.IsZero() && now.Before(campaign.StartAt):
+			boundary = campaign.StartAt
+		case !campaign.EndAt.IsZero() && now.Before(campaign.EndAt):
+			boundary = campaign.EndAt
+		default:
+			continue
+		}
+		if cache.expiresAt.IsZero() || boundary.Before(cache.expiresAt) {
+			cache.expiresAt = boundary
+		}
+	}
+	return nil
+}
+
+// UpdateBanner изменяет баннер в справочнике. Кеш слотов с этим баннером
+// сбрасывается, так как баннер мог перейти в другую кампанию.
+// Возвращает *SizeMismatchError, если новый размер креатива не подходит
+// слоту, в ротации которого баннер уже находится.
+func (b *Bandit) UpdateBanner(ctx context.Context, banner storage.Banner) error {
+	if err := b.checkBannerSlots(ctx, banner); err != nil {
+		return err
+	}
+
+	if err := b.store.UpdateBanner(ctx, banner); err != nil {
+		return err
+	}
+
+	b.clearCacheForBanner(banner.ID)
+	return nil
+}
+
+// checkBannerSlots сверяет размер баннера с размерами слотов справочника,
+// в ротации которых он находится
+func (b *Bandit) checkBannerSlots(ctx context.Context, banner storage.Banner) error {
+	slots, err := b.store.ListSlots(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list slots: %w", err)
+	}
+
+	for _, slot := range slots {
+		if len(slot.Sizes) == 0 || slot.Accepts(banner.Size()) {
+			continue
+		}
+		bannerIDs, err := b.store.GetBannersForSlot(ctx, slot.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get banners: %w", err)
+		}
+		if slices.Contains(bannerIDs, banner.ID) {
+			return &SizeMismatchError{SlotID: slot.ID, BannerID: banner.ID, Size: banner.Size(), Allowed: slot.Sizes}
+		}
+	}
+	return nil
+}
+
+// UpdateCampaign изменяет кампанию и сбрасывает кеш всех слотов с ее баннерами.
+// Остановленную кампанию можно изменить, но не вывести из статуса stopped.
+func (b *Bandit) UpdateCampaign(ctx context.Context, campaign storage.Campaign) error {
+	current, err := b.store.GetCampaign(ctx, campaign.ID)
+	if err != nil {
+		return err
+	}
+	if current.Status == storage.CampaignStopped && campaign.Status != storage.CampaignStopped {
+		return fmt.Errorf("campaign %d: %w", campaign.ID, ErrCampaignStopped)
+	}
+
+	if err := b.store.UpdateCampaign(ctx, campaign); err != nil {
+		return err
+	}
+
+	b.clearCacheForCampaign(campaign.ID)
+	return nil
+}
+
+// SetCampaignStatus ставит на паузу, возобновляет или останавливает
+// показ всех баннеров кампании во всех слотах
+func (b *Bandit) SetCampaignStatus(ctx context.Context, campaignID int, status storage.CampaignStatus) (storage.Campaign, error) {
+	campaign, err := b.store.GetCampaign(ctx, campaignID)
+	if err != nil {
+		return storage.Campaign{}, err
+	}
+
+	campaign.Status = status
+	if err := b.UpdateCampaign(ctx, campaign); err != nil {
+		return storage.Campaign{}, err
+	}
+	return campaign, nil
+}
+
+// clearCacheForCampaign очищает кеш всех слотов и групп, где есть баннеры кампании
+func (b *Bandit) clearCacheForCampaign(campaignID int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key, cache := range b.cache {
+		for _, id := range cache.campaigns {
+			if id == campaignID {
+				delete(b.cache, key)
+				break
+			}
+		}
+	}
+}
+
+// clearCacheForBanner очищает кеш всех слотов и групп, где есть баннер
+func (b *Bandit) clearCacheForBanner(bannerID int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key, cache := range b.cache {
+		cache.mu.RLock()
+		_, ok := cache.banners[bannerID]
+		cache.mu.RUnlock()
+		if ok {
+			delete(b.cache, key)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package app
+
+import (
+	"banner-rotation/internal/storage"
+	"banner-rotation/internal/storage/memory"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCampaignFixture заводит рекламодателя, кампанию 1 с баннером 1
+// и баннер 2 вне кампаний; оба баннера в ротации слотов 1 и 2
+func newCampaignFixture(t *testing.T, campaign storage.Campaign) (*Bandit, *memory.MemoryStorage) {
+	t.Helper()
+	ctx := context.Background()
+	store := newTestStore(t, 2, 0)
+	bandit := NewBandit(store, &MockProducer{})
+
+	_, err := store.CreateAdvertiser(ctx, storage.Advertiser{ID: 1, Name: "acme"})
+	require.NoError(t, err)
+	campaign.ID, campaign.AdvertiserID = 1, 1
+	_, err = store.CreateCampaign(ctx, campaign)
+	require.NoError(t, err)
+	_, err = store.CreateBanner(ctx, storage.Banner{ID: 1, CampaignID: 1})
+	require.NoError(t, err)
+	_, err = store.CreateBanner(ctx, storage.Banner{ID: 2})
+	require.NoError(t, err)
+
+	for _, slotID := range []int{1, 2} {
+		require.NoError(t, bandit.AddBannerToSlot(ctx, slotID, 1))
+		require.NoError(t, bandit.AddBannerToSlot(ctx, slotID, 2))
+	}
+	return bandit, store
+}
+
+// chosenBanners возвращает множество баннеров, выбранных за n показов
+func chosenBanners(t *testing.T, bandit *Bandit, slotID, n int) map[int]bool {
+	t.Helper()
+	chosen := make(map[int]bool)
+	for i := 0; i < n; i++ {
+		bannerID, err := bandit.ChooseBanner(context.Background(), slotID, 1, storage.Visitor{})
+		require.NoError(t, err)
+		chosen[bannerID] = true
+	}
+	return chosen
+}
+
+func TestBandit_CampaignPauseResume(t *testing.T) {
+	ctx := context.Background()
+	bandit, _ := newCampaignFixture(t, storage.Campaign{Status: storage.CampaignActive})
+
+	// Прогреваем кеш обоих слотов
+	assert.Equal(t, map[int]bool{1: true, 2: true}, chosenBanners(t, bandit, 1, 10))
+	assert.Equal(t, map[int]bool{1: true, 2: true}, chosenBanners(t, bandit, 2, 10))
+
+	campaign, err := bandit.SetCampaignStatus(ctx, 1, storage.CampaignPaused)
+	require.NoError(t, err)
+	assert.Equal(t, storage.CampaignPaused, campaign.Status)
+
+	// Пауза действует сразу во всех слотах
+	assert.Equal(t, map[int]bool{2: true}, chosenBanners(t, bandit, 1, 10))
+	assert.Equal(t, map[int]bool{2: true}, chosenBanners(t, bandit, 2, 10))
+
+	// Статистика приостановленного баннера остается в отчете
+	report, err := bandit.GetSlotStats(ctx, 1, 1, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, report.Banners, 2)
+
+	_, err = bandit.SetCampaignStatus(ctx, 1, storage.CampaignActive)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]bool{1: true, 2: true}, chosenBanners(t, bandit, 1, 50))
+}
+
+func TestBandit_CampaignStop(t *testing.T) {
+	ctx := context.Background()
+	bandit, store := newCampaignFixture(t, storage.Campaign{Status: storage.CampaignActive})
+
+	_, err := bandit.SetCampaignStatus(ctx, 1, storage.CampaignStopped)
+	require.NoError(t, err)
+
+	_, err = bandit.SetCampaignStatus(ctx, 1, storage.CampaignActive)
+	assert.ErrorIs(t, err, ErrCampaignStopped)
+
+	campaign, err := store.GetCampaign(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, storage.CampaignStopped, campaign.Status)
+
+	// Когда в слоте не осталось активных баннеров, выбирать нечего
+	require.NoError(t, bandit.RemoveBannerFromSlot(ctx, 1, 2))
+	_, err = bandit.ChooseBanner(ctx, 1, 1, storage.Visitor{})
+	assert.ErrorIs(t, err, ErrNoBanners)
+}
+
+func TestBandit_CampaignFlightDates(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	bandit, _ := newCampaignFixture(t, storage.Campaign{Status: storage.CampaignActive, StartAt: start, EndAt: end})
+
+	now := start.Add(-time.Hour)
+	bandit.now = func() time.Time { return now }
+
+	assert.Equal(t, map[int]bool{2: true}, chosenBanners(t, bandit, 1, 10))
+
+	// Кеш перечитывается на границах периода без явного сброса
+	now = start
+	assert.Equal(t, map[int]bool{1: true, 2: true}, chosenBanners(t, bandit, 1, 50))
+
+	now = end
+	assert.Equal(t, map[int]bool{2: true}, chosenBanners(t, bandit, 1, 10))
+}
+
+func TestBandit_UpdateBannerCampaign(t *testing.T) {
+	ctx := context.Background()
+	bandit, _ := newCampaignFixture(t, storage.Campaign{Status: storage.CampaignPaused})
+
+	assert.Equal(t, map[int]bool{2: true}, chosenBanners(t, bandit, 1, 10))
+
+	// Баннер 2 переходит в приостановленную кампанию - в слоте никого не остается
+	require.NoError(t, bandit.UpdateBanner(ctx, storage.Banner{ID: 2, CampaignID: 1}))
+	_, err := bandit.ChooseBanner(ctx, 1, 1, storage.Visitor{})
+	assert.ErrorIs(t, err, ErrNoBanners)
+}
+
+// lookupCountingStore считает чтения баннеров и кампаний из справочника
+type lookupCountingStore struct {
+	*memory.MemoryStorage
+	lookups int
+}
+
+func (s *lookupCountingStore) GetBanner(ctx context.Context, id int) (storage.Banner, error) {
+	s.lookups++
+	return s.MemoryStorage.GetBanner(ctx, id)
+}
+
+func (s *lookupCountingStore) GetBanners(ctx context.Context, ids []int) ([]storage.Banner, error) {
+	s.lookups++
+	return s.MemoryStorage.GetBanners(ctx, ids)
+}
+
+func (s *lookupCountingStore) GetCampaign(ctx context.Context, id int) (storage.Campaign, error) {
+	s.lookups++
+	return s.MemoryStorage.GetCampaign(ctx, id)
+}
+
+func (s *lookupCountingStore) GetCampaigns(ctx context.Context, ids []int) ([]storage.Campaign, error) {
+	s.lookups++
+	return s.MemoryStorage.GetCampaigns(ctx, ids)
+}
+
+func TestBandit_LoadCampaignsBulk(t *testing.T) {
+	ctx := context.Background()
+	store := &lookupCountingStore{MemoryStorage: newTestStore(t, 1, 0)}
+	bandit := NewBandit(store, &MockProducer{})
+
+	_, err := store.CreateAdvertiser(ctx, storage.Advertiser{ID: 1, Name: "acme"})
+	require.NoError(t, err)
+	for id := 1; id <= 3; id++ {
+		_, err = store.CreateCampaign(ctx, storage.Campaign{ID: id, AdvertiserID: 1, Status: storage.CampaignActive})
+		require.NoError(t, err)
+	}
+	require.NoError(t, store.UpdateCampaign(ctx, storage.Campaign{ID: 3, AdvertiserID: 1, Status: storage.CampaignPaused}))
+	for id := 1; id <= 10; id++ {
+		_, err = store.CreateBanner(ctx, storage.Banner{ID: id, CampaignID: id%3 + 1})
+		require.NoError(t, err)
+		require.NoError(t, store.AddBannerToSlot(ctx, 1, id))
+	}
+
+	// Баннеры и кампании ротации читаются двумя запросами
+	cache, err := bandit.loadStats(ctx, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, store.lookups)
+	assert.Len(t, cache.campaigns, 10)
+	assert.Equal(t, map[int]struct{}{2: {}, 5: {}, 8: {}}, cache.inactive)
+}
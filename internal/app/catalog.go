@@ -0,0 +1,17 @@
+package app
+
+import "banner-rotation/internal/storage"
+
+// CatalogInterface определяет контракт для работы со справочниками
+// баннеров, слотов, групп, рекламодателей и кампаний. Реализуется
+// хранилищем напрямую; изменения баннеров, слотов и кампаний, влияющие
+// на кеш бандита или ротацию, выполняются через BanditInterface.
+type CatalogInterface interface {
+	storage.BannerStorage
+	storage.SlotStorage
+	storage.GroupStorage
+	storage.AdvertiserStorage
+	storage.CampaignStorage
+}
+
+var _ CatalogInterface = (storage.Storage)(nil)
@@ -0,0 +1,46 @@
+package app
+
+import "banner-rotation/internal/storage"
+
+// Error - ошибка предметной области с машиночитаемым кодом. Вид ошибки
+// задается одной из ошибок storage (ErrNotFound, ErrConflict, ErrInvalid,
+// ErrUnavailable) и проверяется через errors.Is.
+type Error struct {
+	code    string
+	kind    error
+	message string
+}
+
+// NewError создает ошибку предметной области заданного вида
+func NewError(code string, kind error, message string) *Error {
+	return &Error{code: code, kind: kind, message: message}
+}
+
+func (e *Error) Error() string {
+	return e.message
+}
+
+// Code возвращает стабильный машиночитаемый код ошибки
+func (e *Error) Code() string {
+	return e.code
+}
+
+func (e *Error) Unwrap() error {
+	return e.kind
+}
+
+var (
+	// ErrNoBanners - в ротации слота нет баннеров, доступных для показа
+	ErrNoBanners = NewError("no_banners", storage.ErrNotFound, "no banners in rotation for slot")
+	// ErrNoEligibleBanners - в слоте есть активные баннеры, но ограничения
+	// показа ни одного из них не допускают посетителя
+	ErrNoEligibleBanners = NewError("no_eligible_banners", storage.ErrNotFound, "no banners eligible for visitor in slot")
+	// ErrNoMatchingGroup - ни одно правило групп не подходит пользователю
+	ErrNoMatchingGroup = NewError("no_matching_group", storage.ErrInvalid, "no group matches user attributes")
+	// ErrShowCapReached - баннер исчерпал лимит показов в слоте
+	ErrShowCapReached = NewError("show_cap_reached", storage.ErrConflict, "banner reached show cap in slot")
+	// ErrCampaignStopped - остановленную кампанию нельзя возобновить
+	ErrCampaignStopped = NewError("campaign_stopped", storage.ErrConflict, "campaign is stopped")
+	// ErrCacheWarming - кеш статистики бандита еще не прогрет
+	ErrCacheWarming = NewError("cache_warming", storage.ErrUnavailable, "bandit stats cache is warming up")
+)
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// z95 - квантиль нормального распределения для 95% доверительного интервала
+const z95 = 1.96
+
+// BannerReport - статистика баннера в слоте для группы
+type BannerReport struct {
+	BannerID int
+	Shows    int
+	Clicks   int
+	// CTR и границы его 95% доверительного интервала (Уилсон)
+	CTR    float64
+	CILow  float64
+	CIHigh float64
+	// Score - текущее значение UCB по кешу; math.MaxFloat64, если баннер еще не показывали
+	Score float64
+	// Share - доля показов баннера среди всех показов в отчете
+	Share float64
+}
+
+// SlotReport - отчет по баннерам слота для группы
+type SlotReport struct {
+	SlotID     int
+	GroupID    int
+	From       time.Time // нулевые From и To - статистика за все время
+	To         time.Time
+	TotalShows int
+	Banners    []BannerReport
+}
+
+// GetSlotStats возвращает отчет по баннерам слота. Если from и to нулевые,
+// используются общие счетчики из кеша, иначе - статистика хранилища за период.
+// Score всегда считается по текущему состоянию кеша.
+func (b *Bandit) GetSlotStats(ctx context.Context, slotID, groupID int, from, to time.Time) (*SlotReport, error) {
+	cache, err := b.loadStats(ctx, slotID, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[int]BannerStat)
+	scores := make(map[int]float64)
+
+	cache.mu.RLock()
+	for bannerID, stat := range cache.banners {
+		scores[bannerID] = b.calculateUCB(stat, cache.totalShows)
+		counts[bannerID] = stat
+	}
+	cache.mu.RUnlock()
+
+	if !from.IsZero() || !to.IsZero() {
+		stats, err := b.store.GetBannerStatsRange(ctx, slotID, groupID, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stats for slot %d group %d: %w", slotID, groupID, err)
+		}
+
+		for bannerID := range counts {
+			counts[bannerID] = BannerStat{}
+		}
+		for _, stat := range stats {
+			if _, ok := counts[stat.BannerID]; ok {
+				counts[stat.BannerID] = BannerStat{Shows: stat.Shows, Clicks: stat.Clicks}
+			}
+		}
+	}
+
+	report := &SlotReport{
+		SlotID:  slotID,
+		GroupID: groupID,
+		From:    from,
+		To:      to,
+		Banners: make([]BannerReport, 0, len(counts)),
+	}
+	for _, stat := range counts {
+		report.TotalShows += stat.Shows
+	}
+
+	for bannerID, stat := range counts {
+		r := BannerReport{
+			BannerID: bannerID,
+			Shows:    stat.Shows,
+			Clicks:   stat.Clicks,
+			Score:    scores[bannerID],
+		}
+		if stat.Shows > 0 {
+			r.CTR = float64(stat.Clicks) / float64(stat.Shows)
+			r.CILow, r.CIHigh = wilsonInterval(stat.Clicks, stat.Shows)
+		}
+		if report.TotalShows > 0 {
+			r.Share = float64(stat.Shows) / float64(report.TotalShows)
+		}
+		report.Banners = append(report.Banners, r)
+	}
+
+	sort.Slice(report.Banners, func(i, j int) bool {
+		return report.Banners[i].BannerID < report.Banners[j].BannerID
+	})
+	return report, nil
+}
+
+// wilsonInterval вычисляет 95% доверительный интервал Уилсона для доли clicks/shows
+func wilsonInterval(clicks, shows int) (float64, float64) {
+	n := float64(shows)
+	p := float64(clicks) / n
+	z2 := z95 * z95
+
+	center := (p + z2/(2*n)) / (1 + z2/n)
+	margin := z95 * math.Sqrt(p*(1-p)/n+z2/(4*n*n)) / (1 + z2/n)
+
+	return math.Max(0, center-margin), math.Min(1, center+margin)
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBandit_GetSlotStats(t *testing.T) {
+	store := newTestStore(t, 1, 3)
+	bandit := NewBandit(store, &MockProducer{})
+	ctx := context.Background()
+
+	require.NoError(t, bandit.AddBannerToSlot(ctx, 1, 1))
+	require.NoError(t, bandit.AddBannerToSlot(ctx, 1, 2))
+	require.NoError(t, bandit.AddBannerToSlot(ctx, 1, 3))
+
+	for i := 0; i < 30; i++ {
+		require.NoError(t, store.RecordShow(ctx, 1, 1, 1))
+		require.NoError(t, store.RecordShow(ctx, 1, 2, 1))
+		if i < 3 {
+			require.NoError(t, store.RecordClick(ctx, 1, 1, 1))
+		}
+		if i < 9 {
+			require.NoError(t, store.RecordClick(ctx, 1, 2, 1))
+		}
+	}
+
+	t.Run("lifetime", func(t *testing.T) {
+		report, err := bandit.GetSlotStats(ctx, 1, 1, time.Time{}, time.Time{})
+		require.NoError(t, err)
+		require.Len(t, report.Banners, 3)
+		assert.Equal(t, 60, report.TotalShows)
+
+		b1, b2, b3 := report.Banners[0], report.Banners[1], report.Banners[2]
+		assert.Equal(t, 1, b1.BannerID)
+		assert.InDelta(t, 0.1, b1.CTR, 1e-9)
+		assert.InDelta(t, 0.3, b2.CTR, 1e-9)
+		assert.InDelta(t, 0.5, b1.Share, 1e-9)
+
+		assert.Less(t, b1.CILow, b1.CTR)
+		assert.Greater(t, b1.CIHigh, b1.CTR)
+		assert.Greater(t, b2.Score, b1.Score)
+
+		// Непоказанный баннер имеет максимальный приоритет
+		assert.Equal(t, 0, b3.Shows)
+		assert.Equal(t, math.MaxFloat64, b3.Score)
+	})
+
+	t.Run("range", func(t *testing.T) {
+		now := time.Now()
+		report, err := bandit.GetSlotStats(ctx, 1, 1, now.Add(-time.Hour), now.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 60, report.TotalShows)
+
+		report, err = bandit.GetSlotStats(ctx, 1, 1, now.Add(time.Hour), now.Add(2*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 0, report.TotalShows)
+		assert.Len(t, report.Banners, 3)
+	})
+
+	t.Run("no banners", func(t *testing.T) {
+		_, err := bandit.GetSlotStats(ctx, 2, 1, time.Time{}, time.Time{})
+		assert.ErrorIs(t, err, ErrNoBanners)
+	})
+}
+
+func TestWilsonInterval(t *testing.T) {
+	low, high := wilsonInterval(10, 100)
+	assert.InDelta(t, 0.0552, low, 1e-3)
+	assert.InDelta(t, 0.1744, high, 1e-3)
+
+	low, high = wilsonInterval(0, 10)
+	assert.Equal(t, 0.0, low)
+	assert.Greater(t, high, 0.0)
+}
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"banner-rotation/internal/storage"
+	"context"
+	"log"
+	"time"
+)
+
+// StartStatsRollup запускает фоновую свертку статистики: раз в interval часовые
+// корзины старше retention переносятся в дневные. Останавливается вместе с ctx.
+func StartStatsRollup(ctx context.Context, store storage.Storage, retention, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := rollupStats(ctx, store, retention, time.Now()); err != nil {
+				log.Printf("Failed to roll up hourly stats: %v", err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// rollupStats сворачивает часовые корзины, начавшиеся до полуночи UTC
+// дня now-retention, чтобы в дневную корзину попадали только целые сутки
+func rollupStats(ctx context.Context, store storage.Storage, retention time.Duration, now time.Time) error {
+	return store.RollupHourlyStats(ctx, storage.DayBucket(now.Add(-retention)))
+}
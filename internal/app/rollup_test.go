@@ -0,0 +1,36 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollupStats(t *testing.T) {
+	store := newTestStore(t, 1, 1)
+	ctx := context.Background()
+
+	require.NoError(t, store.AddBannerToSlot(ctx, 1, 1))
+	require.NoError(t, store.RecordShow(ctx, 1, 1, 1))
+	require.NoError(t, store.RecordClick(ctx, 1, 1, 1))
+
+	now := time.Now()
+	from, to := now.Add(-72*time.Hour), now.Add(72*time.Hour)
+
+	// Свежие корзины не трогаются
+	require.NoError(t, rollupStats(ctx, store, 7*24*time.Hour, now))
+	stats, err := store.GetBannerStatsRange(ctx, 1, 1, now.Add(-time.Hour), to)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+
+	// Через трое суток при хранении в сутки корзины свернуты, итоги сохраняются
+	require.NoError(t, rollupStats(ctx, store, 24*time.Hour, now.Add(72*time.Hour)))
+	stats, err = store.GetBannerStatsRange(ctx, 1, 1, from, to)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 1, stats[0].Shows)
+	assert.Equal(t, 1, stats[0].Clicks)
+}
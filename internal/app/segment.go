@@ -0,0 +1,155 @@
+package app
+
+import (
+	"banner-rotation/internal/storage"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultRulesReloadInterval - как часто правила групп перечитываются из хранилища
+const DefaultRulesReloadInterval = 30 * time.Second
+
+// rulesReloadTimeout ограничивает одно перечитывание правил
+const rulesReloadTimeout = 5 * time.Second
+
+// Segmenter подбирает группу пользователя по правилам групп. Правила
+// кешируются и перечитываются раз в interval, поэтому изменения, сделанные
+// в базе или другим экземпляром сервиса, применяются без перезапуска.
+type Segmenter struct {
+	groups   storage.GroupStorage
+	interval time.Duration
+	now      func() time.Time
+
+	mu       sync.RWMutex
+	rules    []storage.Group // группы с правилами в порядке подбора
+	loadedAt time.Time
+	// version увеличивается при Invalidate, чтобы загрузка, начатая
+	// до сброса, не сохранила устаревшие правила
+	version int
+
+	// reload объединяет параллельные перечитывания правил в одно
+	reload singleflight.Group
+}
+
+// NewSegmenter создает подбор групп поверх справочника групп
+func NewSegmenter(groups storage.GroupStorage, interval time.Duration) *Segmenter {
+	return &Segmenter{groups: groups, interval: interval, now: time.Now}
+}
+
+// ResolveGroup возвращает группу с наибольшим приоритетом, правило которой
+// подходит пользователю; при равных приоритетах - группу с меньшим ID.
+// Возвращает ErrNoMatchingGroup, если подходящих правил нет.
+func (s *Segmenter) ResolveGroup(ctx context.Context, user storage.UserAttributes) (int, error) {
+	rules, err := s.load(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, group := range rules {
+		if group.Rule.Matches(user) {
+			return group.ID, nil
+		}
+	}
+	return 0, ErrNoMatchingGroup
+}
+
+// Warm загружает правила, если они еще ни разу не загружались. Ошибка
+// означает, что подбор групп пока не может работать.
+func (s *Segmenter) Warm(ctx context.Context) error {
+	s.mu.RLock()
+	warm := s.rules != nil
+	s.mu.RUnlock()
+	if warm {
+		return nil
+	}
+
+	_, err := s.load(ctx)
+	return err
+}
+
+// Invalidate сбрасывает кеш правил: следующий подбор перечитает их
+func (s *Segmenter) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.loadedAt = time.Time{}
+	s.version++
+}
+
+// load возвращает правила из кеша, перечитывая устаревшие. Параллельные
+// запросы ждут одно перечитывание, а не обращаются к хранилищу каждый.
+// Если перечитать не удалось, подбор продолжает работать по прежним правилам.
+func (s *Segmenter) load(ctx context.Context) ([]storage.Group, error) {
+	s.mu.RLock()
+	rules, loadedAt, version := s.rules, s.loadedAt, s.version
+	s.mu.RUnlock()
+
+	if !loadedAt.IsZero() && s.now().Sub(loadedAt) < s.interval {
+		return rules, nil
+	}
+
+	// Ключ включает версию: запрос после Invalidate не присоединится
+	// к перечитыванию, начатому до сброса
+	ch := s.reload.DoChan(strconv.Itoa(version), func() (any, error) {
+		// Отмена запроса, начавшего перечитывание, не должна прерывать его для остальных
+		reloadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rulesReloadTimeout)
+		defer cancel()
+		return s.reloadRules(reloadCtx, version)
+	})
+
+	var err error
+	select {
+	case res := <-ch:
+		if res.Err == nil {
+			return res.Val.([]storage.Group), nil
+		}
+		err = res.Err
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if rules == nil {
+		return nil, fmt.Errorf("failed to load group rules: %w", err)
+	}
+	log.Printf("Failed to reload group rules, using previous: %v", err)
+	return rules, nil
+}
+
+// reloadRules читает правила из хранилища и сохраняет их в кеш,
+// если с начала чтения кеш не сбрасывали
+func (s *Segmenter) reloadRules(ctx context.Context, version int) ([]storage.Group, error) {
+	now := s.now()
+	groups, err := s.groups.ListGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fresh := make([]storage.Group, 0, len(groups))
+	for _, group := range groups {
+		if group.Rule != nil {
+			fresh = append(fresh, group)
+		}
+	}
+	sort.SliceStable(fresh, func(i, j int) bool {
+		if fresh[i].Rule.Priority != fresh[j].Rule.Priority {
+			return fresh[i].Rule.Priority > fresh[j].Rule.Priority
+		}
+		return fresh[i].ID < fresh[j].ID
+	})
+
+	s.mu.Lock()
+	if s.version == version {
+		s.rules = fresh
+		s.loadedAt = now
+	}
+	s.mu.Unlock()
+
+	return fresh, nil
+}
@@ -0,0 +1,152 @@
+package app
+
+import (
+	"banner-rotation/internal/storage"
+	"banner-rotation/internal/storage/memory"
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupRule_Matches(t *testing.T) {
+	rule := storage.GroupRule{MinAge: 18, MaxAge: 25, Genders: []string{"f"}, Interests: []string{"sport", "music"}}
+
+	assert.True(t, rule.Matches(storage.UserAttributes{Age: 20, Gender: "f", Interests: []string{"music"}}))
+	assert.True(t, rule.Matches(storage.UserAttributes{Age: 25, Gender: "f", Interests: []string{"sport", "cars"}}))
+	assert.False(t, rule.Matches(storage.UserAttributes{Age: 26, Gender: "f", Interests: []string{"sport"}}))
+	assert.False(t, rule.Matches(storage.UserAttributes{Age: 20, Gender: "m", Interests: []string{"sport"}}))
+	assert.False(t, rule.Matches(storage.UserAttributes{Age: 20, Gender: "f", Interests: []string{"cars"}}))
+	assert.False(t, rule.Matches(storage.UserAttributes{Gender: "f", Interests: []string{"sport"}}), "unknown age")
+
+	assert.True(t, storage.GroupRule{}.Matches(storage.UserAttributes{}))
+}
+
+func TestSegmenter_ResolveGroup(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+
+	for _, group := range []storage.Group{
+		{ID: 1, Description: "everyone", Rule: &storage.GroupRule{}},
+		{ID: 2, Description: "students", Rule: &storage.GroupRule{Priority: 10, MinAge: 18, MaxAge: 23}},
+		{ID: 3, Description: "athletes", Rule: &storage.GroupRule{Priority: 10, Interests: []string{"sport"}}},
+		{ID: 4, Description: "manual"},
+	} {
+		_, err := store.CreateGroup(ctx, group)
+		require.NoError(t, err)
+	}
+
+	now := time.Now()
+	segments := NewSegmenter(store, time.Minute)
+	segments.now = func() time.Time { return now }
+
+	resolve := func(user storage.UserAttributes) int {
+		groupID, err := segments.ResolveGroup(ctx, user)
+		require.NoError(t, err)
+		return groupID
+	}
+
+	assert.Equal(t, 2, resolve(storage.UserAttributes{Age: 20, Interests: []string{"sport"}}), "equal priority - lower id")
+	assert.Equal(t, 3, resolve(storage.UserAttributes{Age: 40, Interests: []string{"sport"}}))
+	assert.Equal(t, 1, resolve(storage.UserAttributes{Age: 40}))
+
+	t.Run("rules are reloaded after interval", func(t *testing.T) {
+		require.NoError(t, store.UpdateGroup(ctx, storage.Group{ID: 4, Description: "seniors", Rule: &storage.GroupRule{Priority: 5, MinAge: 40}}))
+		assert.Equal(t, 1, resolve(storage.UserAttributes{Age: 40}), "cached rules")
+
+		now = now.Add(time.Minute)
+		assert.Equal(t, 4, resolve(storage.UserAttributes{Age: 40}))
+	})
+
+	t.Run("Invalidate reloads rules immediately", func(t *testing.T) {
+		require.NoError(t, store.DeleteGroup(ctx, 1))
+		segments.Invalidate()
+
+		_, err := segments.ResolveGroup(ctx, storage.UserAttributes{Age: 30})
+		assert.ErrorIs(t, err, ErrNoMatchingGroup)
+	})
+}
+
+// failingGroups - справочник групп, который не удается прочитать
+type failingGroups struct {
+	storage.GroupStorage
+	err error
+}
+
+func (g *failingGroups) ListGroups(ctx context.Context) ([]storage.Group, error) {
+	if g.err != nil {
+		return nil, g.err
+	}
+	return g.GroupStorage.ListGroups(ctx)
+}
+
+func TestSegmenter_Warm(t *testing.T) {
+	ctx := context.Background()
+	groups := &failingGroups{GroupStorage: memory.New(), err: storage.ErrUnavailable}
+	segments := NewSegmenter(groups, time.Minute)
+
+	assert.ErrorIs(t, segments.Warm(ctx), storage.ErrUnavailable, "cold cache")
+
+	groups.err = nil
+	require.NoError(t, segments.Warm(ctx))
+
+	groups.err = storage.ErrUnavailable
+	segments.Invalidate()
+	assert.NoError(t, segments.Warm(ctx), "rules loaded once stay usable")
+}
+
+// slowGroups считает чтения справочника групп и задерживает их до release
+type slowGroups struct {
+	storage.GroupStorage
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (g *slowGroups) ListGroups(ctx context.Context) ([]storage.Group, error) {
+	g.calls.Add(1)
+	<-g.release
+	return g.GroupStorage.ListGroups(ctx)
+}
+
+func TestSegmenter_ConcurrentReload(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	_, err := store.CreateGroup(ctx, storage.Group{ID: 1, Rule: &storage.GroupRule{}})
+	require.NoError(t, err)
+
+	groups := &slowGroups{GroupStorage: store, release: make(chan struct{})}
+	segments := NewSegmenter(groups, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			groupID, err := segments.ResolveGroup(ctx, storage.UserAttributes{})
+			assert.NoError(t, err)
+			assert.Equal(t, 1, groupID)
+		}()
+	}
+
+	assert.Eventually(t, func() bool { return groups.calls.Load() > 0 }, time.Second, time.Millisecond)
+	close(groups.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), groups.calls.Load(), "concurrent requests share one reload")
+
+	t.Run("canceled request does not wait for reload", func(t *testing.T) {
+		groups.release = make(chan struct{})
+		defer close(groups.release)
+		segments.Invalidate()
+
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		groupID, err := segments.ResolveGroup(ctx, storage.UserAttributes{})
+		require.NoError(t, err, "previous rules are used")
+		assert.Equal(t, 1, groupID)
+	})
+}
@@ -0,0 +1,18 @@
+package app
+
+import (
+	"banner-rotation/internal/storage"
+	"context"
+	"fmt"
+)
+
+// SetBannerTargeting задает ограничения показа баннера в слоте
+// и сбрасывает кеш слота
+func (b *Bandit) SetBannerTargeting(ctx context.Context, slotID, bannerID int, targeting storage.Targeting) error {
+	if err := b.store.SetBannerTargeting(ctx, slotID, bannerID, targeting); err != nil {
+		return fmt.Errorf("failed to set targeting: %w", err)
+	}
+
+	b.clearCacheForSlot(slotID)
+	return nil
+}
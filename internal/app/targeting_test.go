@@ -0,0 +1,57 @@
+package app
+
+import (
+	"banner-rotation/internal/storage"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargeting_Allows(t *testing.T) {
+	targeting := storage.Targeting{Countries: []string{"RU", "KZ"}, Devices: []string{"mobile"}}
+
+	assert.True(t, targeting.Allows(storage.Visitor{Country: "ru", Device: "mobile", OS: "ios"}))
+	assert.False(t, targeting.Allows(storage.Visitor{Country: "DE", Device: "mobile"}))
+	assert.False(t, targeting.Allows(storage.Visitor{Country: "RU", Device: "desktop"}))
+	assert.False(t, targeting.Allows(storage.Visitor{Device: "mobile"}), "unknown country")
+
+	assert.True(t, storage.Targeting{}.Allows(storage.Visitor{}))
+}
+
+func TestBandit_ChooseBanner_Targeting(t *testing.T) {
+	store := newTestStore(t, 1, 2)
+	bandit := NewBandit(store, &MockProducer{})
+	ctx := context.Background()
+
+	require.NoError(t, bandit.AddBannerToSlot(ctx, 1, 1))
+	require.NoError(t, bandit.AddBannerToSlot(ctx, 1, 2))
+	require.NoError(t, bandit.SetBannerTargeting(ctx, 1, 1, storage.Targeting{Countries: []string{"RU"}}))
+	require.NoError(t, bandit.SetBannerTargeting(ctx, 1, 2, storage.Targeting{Devices: []string{"mobile"}, OS: []string{"android"}}))
+
+	choose := func(visitor storage.Visitor) int {
+		bannerID, err := bandit.ChooseBanner(ctx, 1, 1, visitor)
+		require.NoError(t, err)
+		return bannerID
+	}
+
+	for range 5 {
+		assert.Equal(t, 1, choose(storage.Visitor{Country: "RU", Device: "desktop"}))
+		assert.Equal(t, 2, choose(storage.Visitor{Country: "DE", Device: "mobile", OS: "android"}))
+	}
+
+	_, err := bandit.ChooseBanner(ctx, 1, 1, storage.Visitor{Country: "DE", Device: "desktop"})
+	require.ErrorIs(t, err, ErrNoEligibleBanners)
+	assert.NotErrorIs(t, err, ErrNoBanners)
+
+	t.Run("cache is cleared on targeting change", func(t *testing.T) {
+		require.NoError(t, bandit.SetBannerTargeting(ctx, 1, 1, storage.Targeting{}))
+		assert.Equal(t, 1, choose(storage.Visitor{Country: "DE", Device: "desktop"}))
+	})
+
+	t.Run("banner not in slot", func(t *testing.T) {
+		err := bandit.SetBannerTargeting(ctx, 1, 3, storage.Targeting{Countries: []string{"RU"}})
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+}
@@ -0,0 +1,152 @@
+// Package auth проверяет API-ключи и их права
+package auth
+
+import (
+	"banner-rotation/internal/storage"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Scope - право API-ключа
+type Scope string
+
+const (
+	// ScopeServe - выбор баннеров и регистрация кликов
+	ScopeServe Scope = "serve"
+	// ScopeAdmin - управление справочниками, ротацией и статистика;
+	// включает ScopeServe
+	ScopeAdmin Scope = "admin"
+)
+
+// DefaultCacheTTL - сколько проверенный ключ хранится в кеше. Отзыв ключа
+// вступает в силу не позже чем через это время.
+const DefaultCacheTTL = 30 * time.Second
+
+// keyPrefix отличает ключи сервиса от других секретов, например в логах
+const keyPrefix = "br_"
+
+var (
+	// ErrUnauthenticated - ключ не передан, не найден или отозван
+	ErrUnauthenticated = errors.New("missing or invalid api key")
+	// ErrForbidden - у ключа нет нужного права
+	ErrForbidden = errors.New("api key scope does not allow this operation")
+)
+
+// ParseScope проверяет имя права
+func ParseScope(s string) (Scope, error) {
+	switch scope := Scope(s); scope {
+	case ScopeServe, ScopeAdmin:
+		return scope, nil
+	default:
+		return "", fmt.Errorf("unknown scope %q", s)
+	}
+}
+
+// Key - API-ключ. Сам ключ не хранится, только его хеш.
+type Key struct {
+	ID        int
+	Name      string
+	Scopes    []Scope
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+// Allows сообщает, дает ли ключ право scope
+func (k Key) Allows(scope Scope) bool {
+	return slices.Contains(k.Scopes, scope) || slices.Contains(k.Scopes, ScopeAdmin)
+}
+
+// KeyStore - хранилище API-ключей
+type KeyStore interface {
+	// LookupKey возвращает действующий ключ по хешу или storage.ErrNotFound
+	LookupKey(ctx context.Context, hash string) (Key, error)
+}
+
+// GenerateKey создает новый ключ и возвращает его вместе с хешем для хранения
+func GenerateKey() (key, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	key = keyPrefix + hex.EncodeToString(buf)
+	return key, HashKey(key), nil
+}
+
+// HashKey возвращает хеш ключа, под которым он хранится
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Authenticator проверяет ключи запросов. Действующие ключи кешируются
+// на ttl, чтобы не обращаться к хранилищу на каждый запрос.
+type Authenticator struct {
+	keys KeyStore
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedKey
+}
+
+type cachedKey struct {
+	key       Key
+	expiresAt time.Time
+}
+
+func NewAuthenticator(keys KeyStore, ttl time.Duration) *Authenticator {
+	return &Authenticator{keys: keys, ttl: ttl, now: time.Now, cache: make(map[string]cachedKey)}
+}
+
+// Authorize проверяет ключ и его право scope. Возвращает ErrUnauthenticated
+// для отсутствующего или неизвестного ключа и ErrForbidden, если права нет.
+func (a *Authenticator) Authorize(ctx context.Context, key string, scope Scope) (Key, error) {
+	if key == "" {
+		return Key{}, ErrUnauthenticated
+	}
+
+	k, err := a.lookup(ctx, HashKey(key))
+	if err != nil {
+		return Key{}, err
+	}
+	if !k.Allows(scope) {
+		return Key{}, fmt.Errorf("%w: key %q has no %s scope", ErrForbidden, k.Name, scope)
+	}
+	return k, nil
+}
+
+// lookup ищет ключ в кеше, затем в хранилище. Неизвестные ключи
+// не кешируются, чтобы новый ключ заработал сразу.
+func (a *Authenticator) lookup(ctx context.Context, hash string) (Key, error) {
+	now := a.now()
+
+	a.mu.Lock()
+	cached, ok := a.cache[hash]
+	a.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.key, nil
+	}
+
+	k, err := a.keys.LookupKey(ctx, hash)
+	if errors.Is(err, storage.ErrNotFound) {
+		a.mu.Lock()
+		delete(a.cache, hash)
+		a.mu.Unlock()
+		return Key{}, ErrUnauthenticated
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to look up api key: %w", err)
+	}
+
+	a.mu.Lock()
+	a.cache[hash] = cachedKey{key: k, expiresAt: now.Add(a.ttl)}
+	a.mu.Unlock()
+	return k, nil
+}
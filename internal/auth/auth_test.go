@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"banner-rotation/internal/storage"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mapKeyStore - ключи по хешу с подсчетом обращений
+type mapKeyStore struct {
+	keys    map[string]Key
+	lookups int
+}
+
+func (s *mapKeyStore) LookupKey(ctx context.Context, hash string) (Key, error) {
+	s.lookups++
+	key, ok := s.keys[hash]
+	if !ok {
+		return Key{}, storage.ErrNotFound
+	}
+	return key, nil
+}
+
+func TestGenerateKey(t *testing.T) {
+	key, hash, err := GenerateKey()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, keyPrefix))
+	assert.Equal(t, HashKey(key), hash)
+	assert.NotContains(t, hash, key)
+
+	other, _, err := GenerateKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+}
+
+func TestKey_Allows(t *testing.T) {
+	serve := Key{Scopes: []Scope{ScopeServe}}
+	assert.True(t, serve.Allows(ScopeServe))
+	assert.False(t, serve.Allows(ScopeAdmin))
+
+	admin := Key{Scopes: []Scope{ScopeAdmin}}
+	assert.True(t, admin.Allows(ScopeServe))
+	assert.True(t, admin.Allows(ScopeAdmin))
+
+	_, err := ParseScope("root")
+	assert.Error(t, err)
+}
+
+func TestAuthenticator(t *testing.T) {
+	ctx := context.Background()
+	serveKey, serveHash, err := GenerateKey()
+	require.NoError(t, err)
+	store := &mapKeyStore{keys: map[string]Key{serveHash: {ID: 1, Name: "web", Scopes: []Scope{ScopeServe}}}}
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	authenticator := NewAuthenticator(store, time.Minute)
+	authenticator.now = func() time.Time { return now }
+
+	key, err := authenticator.Authorize(ctx, serveKey, ScopeServe)
+	require.NoError(t, err)
+	assert.Equal(t, "web", key.Name)
+
+	_, err = authenticator.Authorize(ctx, serveKey, ScopeAdmin)
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.Equal(t, 1, store.lookups, "valid key is cached")
+
+	_, err = authenticator.Authorize(ctx, "", ScopeServe)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+	_, err = authenticator.Authorize(ctx, "br_unknown", ScopeServe)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+
+	t.Run("revoked key stops working after ttl", func(t *testing.T) {
+		delete(store.keys, serveHash)
+		_, err := authenticator.Authorize(ctx, serveKey, ScopeServe)
+		require.NoError(t, err)
+
+		now = now.Add(2 * time.Minute)
+		_, err = authenticator.Authorize(ctx, serveKey, ScopeServe)
+		assert.ErrorIs(t, err, ErrUnauthenticated)
+	})
+}
@@ -3,12 +3,22 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Kafka KafkaConfig
+	Kafka   KafkaConfig
+	Storage StorageConfig
+	GRPC    GRPCConfig
+
+	Idempotency IdempotencyConfig
+	Auth        AuthConfig
+	RateLimit   RateLimitConfig `mapstructure:"rate_limit"`
+	Health      HealthConfig
+	Tracking    TrackingConfig
 }
 
 type KafkaConfig struct {
@@ -16,6 +26,86 @@ type KafkaConfig struct {
 	TopicEvents string
 }
 
+// GRPCConfig - gRPC API рядом с HTTP API
+type GRPCConfig struct {
+	// Addr - адрес host:port, пусто - gRPC API выключен
+	Addr string
+}
+
+// TrackingConfig - подписанные ссылки переходов по баннерам
+type TrackingConfig struct {
+	// Secret - ключ подписи ссылок, общий для всех экземпляров; пусто - ссылки не выдаются
+	Secret string
+	// BaseURL - внешний адрес сервиса, с которого начинаются ссылки
+	BaseURL string `mapstructure:"base_url"`
+	// FallbackURL - куда вести переход, если посадочная страница баннера неизвестна
+	FallbackURL string `mapstructure:"fallback_url"`
+	// TTL - срок действия ссылки, 0 - tracking.DefaultClickTTL
+	TTL time.Duration
+	// ReserveShows - записывать показ не при выборе баннера, а по пикселю
+	// /i/{token}.gif; требует Secret
+	ReserveShows bool `mapstructure:"reserve_shows"`
+	// ImpressionTTL - сколько ждать пиксель, 0 - tracking.DefaultImpressionTTL
+	ImpressionTTL time.Duration `mapstructure:"impression_ttl"`
+}
+
+// HealthConfig - проверки живости и готовности
+type HealthConfig struct {
+	// DrainDelay - сколько /readyz отвечает draining перед остановкой сервера
+	DrainDelay time.Duration `mapstructure:"drain_delay"`
+}
+
+// AuthConfig - проверка API-ключей
+type AuthConfig struct {
+	// Enabled - требовать API-ключ; ключи хранятся в PostgreSQL, поэтому
+	// если параметр не задан, проверка включается только для драйвера postgres
+	Enabled bool
+}
+
+// RateLimitConfig - лимиты частоты запросов на клиента
+type RateLimitConfig struct {
+	// Store - memory (по умолчанию), postgres или redis; общие хранилища
+	// требуют такого же storage.driver
+	Store string
+	// Serve и Admin - лимиты маршрутов областей serve и admin, нулевой лимит выключен
+	Serve LimitConfig
+	Admin LimitConfig
+}
+
+// LimitConfig - корзина токенов: Burst запросов подряд, затем Rate в секунду
+type LimitConfig struct {
+	Rate  float64
+	Burst int
+}
+
+// IdempotencyConfig - хранение ответов на запросы с Idempotency-Key
+type IdempotencyConfig struct {
+	// Store - memory (по умолчанию) или postgres; postgres требует storage.driver postgres
+	Store string
+	// TTL - сколько хранится ответ, 0 - idempotency.DefaultTTL
+	TTL time.Duration
+}
+
+// StorageConfig - выбор реализации хранилища
+type StorageConfig struct {
+	// Driver - postgres (по умолчанию), memory, bolt или redis
+	Driver string
+	// AutoMigrate - применять миграции PostgreSQL при запуске
+	AutoMigrate bool `mapstructure:"auto_migrate"`
+	// SnapshotPath - файл снимка для memory, пусто - без снимков
+	SnapshotPath string `mapstructure:"snapshot_path"`
+	// BoltPath - файл базы для bolt
+	BoltPath string `mapstructure:"bolt_path"`
+	// RedisAddr - адрес host:port или URL redis:// для redis
+	RedisAddr string `mapstructure:"redis_addr"`
+	// ShowCap - лимит показов баннера в слоте для redis, 0 - без лимита
+	ShowCap int `mapstructure:"show_cap"`
+	// BufferFlushInterval - интервал отложенной записи показов и кликов, 0 - писать сразу
+	BufferFlushInterval time.Duration `mapstructure:"buffer_flush_interval"`
+	// HourlyRetention - сколько хранить часовые корзины до свертки в дневные, 0 - не сворачивать
+	HourlyRetention time.Duration `mapstructure:"hourly_retention"`
+}
+
 func Load() (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
@@ -39,5 +129,90 @@ func Load() (*Config, error) {
 		cfg.Kafka.TopicEvents = topic
 	}
 
+	if driver := os.Getenv("STORAGE_DRIVER"); driver != "" {
+		cfg.Storage.Driver = driver
+	}
+	if migrate := os.Getenv("STORAGE_AUTO_MIGRATE"); migrate != "" {
+		v, err := strconv.ParseBool(migrate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid STORAGE_AUTO_MIGRATE: %w", err)
+		}
+		cfg.Storage.AutoMigrate = v
+	}
+	if path := os.Getenv("STORAGE_SNAPSHOT_PATH"); path != "" {
+		cfg.Storage.SnapshotPath = path
+	}
+	if path := os.Getenv("STORAGE_BOLT_PATH"); path != "" {
+		cfg.Storage.BoltPath = path
+	}
+	if addr := os.Getenv("STORAGE_REDIS_ADDR"); addr != "" {
+		cfg.Storage.RedisAddr = addr
+	}
+	if interval := os.Getenv("STORAGE_BUFFER_FLUSH_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid STORAGE_BUFFER_FLUSH_INTERVAL: %w", err)
+		}
+		cfg.Storage.BufferFlushInterval = d
+	}
+	if retention := os.Getenv("STORAGE_HOURLY_RETENTION"); retention != "" {
+		d, err := time.ParseDuration(retention)
+		if err != nil {
+			return nil, fmt.Errorf("invalid STORAGE_HOURLY_RETENTION: %w", err)
+		}
+		cfg.Storage.HourlyRetention = d
+	}
+	if addr := os.Getenv("GRPC_ADDR"); addr != "" {
+		cfg.GRPC.Addr = addr
+	}
+	if enabled := os.Getenv("AUTH_ENABLED"); enabled != "" {
+		v, err := strconv.ParseBool(enabled)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AUTH_ENABLED: %w", err)
+		}
+		cfg.Auth.Enabled = v
+	} else if !viper.IsSet("auth.enabled") {
+		cfg.Auth.Enabled = cfg.Storage.Driver == "postgres"
+	}
+	if store := os.Getenv("IDEMPOTENCY_STORE"); store != "" {
+		cfg.Idempotency.Store = store
+	}
+	if ttl := os.Getenv("IDEMPOTENCY_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IDEMPOTENCY_TTL: %w", err)
+		}
+		cfg.Idempotency.TTL = d
+	}
+	if store := os.Getenv("RATE_LIMIT_STORE"); store != "" {
+		cfg.RateLimit.Store = store
+	}
+	if delay := os.Getenv("HEALTH_DRAIN_DELAY"); delay != "" {
+		d, err := time.ParseDuration(delay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HEALTH_DRAIN_DELAY: %w", err)
+		}
+		cfg.Health.DrainDelay = d
+	}
+	if secret := os.Getenv("TRACKING_SECRET"); secret != "" {
+		cfg.Tracking.Secret = secret
+	}
+	if url := os.Getenv("TRACKING_BASE_URL"); url != "" {
+		cfg.Tracking.BaseURL = url
+	}
+	if url := os.Getenv("TRACKING_FALLBACK_URL"); url != "" {
+		cfg.Tracking.FallbackURL = url
+	}
+	if reserve := os.Getenv("TRACKING_RESERVE_SHOWS"); reserve != "" {
+		v, err := strconv.ParseBool(reserve)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRACKING_RESERVE_SHOWS: %w", err)
+		}
+		cfg.Tracking.ReserveShows = v
+	}
+	if cfg.Storage.Driver == "" {
+		cfg.Storage.Driver = "postgres"
+	}
+
 	return &cfg, nil
 }
@@ -0,0 +1,49 @@
+package grpc
+
+import (
+	"banner-rotation/internal/auth"
+	"banner-rotation/internal/grpc/pb"
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// methodScopes - право, нужное для вызова метода; как в REST API,
+// управление ротацией доступно только admin
+var methodScopes = map[string]auth.Scope{
+	pb.BannerRotation_AddBannerToSlot_FullMethodName:      auth.ScopeAdmin,
+	pb.BannerRotation_RemoveBannerFromSlot_FullMethodName: auth.ScopeAdmin,
+	pb.BannerRotation_ChooseBanner_FullMethodName:         auth.ScopeServe,
+	pb.BannerRotation_RecordClick_FullMethodName:          auth.ScopeServe,
+}
+
+// authInterceptor проверяет API-ключ из метаданных x-api-key
+// или authorization: Bearer <ключ>
+func authInterceptor(authenticator *auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		scope, ok := methodScopes[info.FullMethod]
+		if !ok {
+			scope = auth.ScopeAdmin
+		}
+		if _, err := authenticator.Authorize(ctx, metadataKey(ctx), scope); err != nil {
+			return nil, toStatus(err)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// metadataKey возвращает API-ключ из метаданных вызова
+func metadataKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if keys := md.Get("x-api-key"); len(keys) > 0 {
+		return keys[0]
+	}
+	for _, value := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(value, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
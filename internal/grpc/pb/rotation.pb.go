@@ -0,0 +1,510 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: rotation.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type AddBannerToSlotRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SlotId        int64                  `protobuf:"varint,1,opt,name=slot_id,json=slotId,proto3" json:"slot_id,omitempty"`
+	BannerId      int64                  `protobuf:"varint,2,opt,name=banner_id,json=bannerId,proto3" json:"banner_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddBannerToSlotRequest) Reset() {
+	*x = AddBannerToSlotRequest{}
+	mi := &file_rotation_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddBannerToSlotRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddBannerToSlotRequest) ProtoMessage() {}
+
+func (x *AddBannerToSlotRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rotation_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddBannerToSlotRequest.ProtoReflect.Descriptor instead.
+func (*AddBannerToSlotRequest) Descriptor() ([]byte, []int) {
+	return file_rotation_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *AddBannerToSlotRequest) GetSlotId() int64 {
+	if x != nil {
+		return x.SlotId
+	}
+	return 0
+}
+
+func (x *AddBannerToSlotRequest) GetBannerId() int64 {
+	if x != nil {
+		return x.BannerId
+	}
+	return 0
+}
+
+type AddBannerToSlotResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddBannerToSlotResponse) Reset() {
+	*x = AddBannerToSlotResponse{}
+	mi := &file_rotation_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddBannerToSlotResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddBannerToSlotResponse) ProtoMessage() {}
+
+func (x *AddBannerToSlotResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rotation_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddBannerToSlotResponse.ProtoReflect.Descriptor instead.
+func (*AddBannerToSlotResponse) Descriptor() ([]byte, []int) {
+	return file_rotation_proto_rawDescGZIP(), []int{1}
+}
+
+type RemoveBannerFromSlotRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SlotId        int64                  `protobuf:"varint,1,opt,name=slot_id,json=slotId,proto3" json:"slot_id,omitempty"`
+	BannerId      int64                  `protobuf:"varint,2,opt,name=banner_id,json=bannerId,proto3" json:"banner_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveBannerFromSlotRequest) Reset() {
+	*x = RemoveBannerFromSlotRequest{}
+	mi := &file_rotation_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveBannerFromSlotRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveBannerFromSlotRequest) ProtoMessage() {}
+
+func (x *RemoveBannerFromSlotRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rotation_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveBannerFromSlotRequest.ProtoReflect.Descriptor instead.
+func (*RemoveBannerFromSlotRequest) Descriptor() ([]byte, []int) {
+	return file_rotation_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RemoveBannerFromSlotRequest) GetSlotId() int64 {
+	if x != nil {
+		return x.SlotId
+	}
+	return 0
+}
+
+func (x *RemoveBannerFromSlotRequest) GetBannerId() int64 {
+	if x != nil {
+		return x.BannerId
+	}
+	return 0
+}
+
+type RemoveBannerFromSlotResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveBannerFromSlotResponse) Reset() {
+	*x = RemoveBannerFromSlotResponse{}
+	mi := &file_rotation_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveBannerFromSlotResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveBannerFromSlotResponse) ProtoMessage() {}
+
+func (x *RemoveBannerFromSlotResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rotation_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveBannerFromSlotResponse.ProtoReflect.Descriptor instead.
+func (*RemoveBannerFromSlotResponse) Descriptor() ([]byte, []int) {
+	return file_rotation_proto_rawDescGZIP(), []int{3}
+}
+
+type ChooseBannerRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	SlotId  int64                  `protobuf:"varint,1,opt,name=slot_id,json=slotId,proto3" json:"slot_id,omitempty"`
+	GroupId int64                  `protobuf:"varint,2,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	// Контекст показа для таргетинга, пустые значения не заданы
+	Country       string `protobuf:"bytes,3,opt,name=country,proto3" json:"country,omitempty"`
+	Device        string `protobuf:"bytes,4,opt,name=device,proto3" json:"device,omitempty"`
+	Os            string `protobuf:"bytes,5,opt,name=os,proto3" json:"os,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChooseBannerRequest) Reset() {
+	*x = ChooseBannerRequest{}
+	mi := &file_rotation_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChooseBannerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChooseBannerRequest) ProtoMessage() {}
+
+func (x *ChooseBannerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rotation_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChooseBannerRequest.ProtoReflect.Descriptor instead.
+func (*ChooseBannerRequest) Descriptor() ([]byte, []int) {
+	return file_rotation_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ChooseBannerRequest) GetSlotId() int64 {
+	if x != nil {
+		return x.SlotId
+	}
+	return 0
+}
+
+func (x *ChooseBannerRequest) GetGroupId() int64 {
+	if x != nil {
+		return x.GroupId
+	}
+	return 0
+}
+
+func (x *ChooseBannerRequest) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *ChooseBannerRequest) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *ChooseBannerRequest) GetOs() string {
+	if x != nil {
+		return x.Os
+	}
+	return ""
+}
+
+type ChooseBannerResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BannerId      int64                  `protobuf:"varint,1,opt,name=banner_id,json=bannerId,proto3" json:"banner_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChooseBannerResponse) Reset() {
+	*x = ChooseBannerResponse{}
+	mi := &file_rotation_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChooseBannerResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChooseBannerResponse) ProtoMessage() {}
+
+func (x *ChooseBannerResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rotation_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChooseBannerResponse.ProtoReflect.Descriptor instead.
+func (*ChooseBannerResponse) Descriptor() ([]byte, []int) {
+	return file_rotation_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ChooseBannerResponse) GetBannerId() int64 {
+	if x != nil {
+		return x.BannerId
+	}
+	return 0
+}
+
+type RecordClickRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SlotId        int64                  `protobuf:"varint,1,opt,name=slot_id,json=slotId,proto3" json:"slot_id,omitempty"`
+	BannerId      int64                  `protobuf:"varint,2,opt,name=banner_id,json=bannerId,proto3" json:"banner_id,omitempty"`
+	GroupId       int64                  `protobuf:"varint,3,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecordClickRequest) Reset() {
+	*x = RecordClickRequest{}
+	mi := &file_rotation_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecordClickRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecordClickRequest) ProtoMessage() {}
+
+func (x *RecordClickRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rotation_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecordClickRequest.ProtoReflect.Descriptor instead.
+func (*RecordClickRequest) Descriptor() ([]byte, []int) {
+	return file_rotation_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *RecordClickRequest) GetSlotId() int64 {
+	if x != nil {
+		return x.SlotId
+	}
+	return 0
+}
+
+func (x *RecordClickRequest) GetBannerId() int64 {
+	if x != nil {
+		return x.BannerId
+	}
+	return 0
+}
+
+func (x *RecordClickRequest) GetGroupId() int64 {
+	if x != nil {
+		return x.GroupId
+	}
+	return 0
+}
+
+type RecordClickResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecordClickResponse) Reset() {
+	*x = RecordClickResponse{}
+	mi := &file_rotation_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecordClickResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecordClickResponse) ProtoMessage() {}
+
+func (x *RecordClickResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rotation_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecordClickResponse.ProtoReflect.Descriptor instead.
+func (*RecordClickResponse) Descriptor() ([]byte, []int) {
+	return file_rotation_proto_rawDescGZIP(), []int{7}
+}
+
+var File_rotation_proto protoreflect.FileDescriptor
+
+const file_rotation_proto_rawDesc = "" +
+	"\n" +
+	"\x0erotation.proto\x12\vrotation.v1\"N\n" +
+	"\x16AddBannerToSlotRequest\x12\x17\n" +
+	"\aslot_id\x18\x01 \x01(\x03R\x06slotId\x12\x1b\n" +
+	"\tbanner_id\x18\x02 \x01(\x03R\bbannerId\"\x19\n" +
+	"\x17AddBannerToSlotResponse\"S\n" +
+	"\x1bRemoveBannerFromSlotRequest\x12\x17\n" +
+	"\aslot_id\x18\x01 \x01(\x03R\x06slotId\x12\x1b\n" +
+	"\tbanner_id\x18\x02 \x01(\x03R\bbannerId\"\x1e\n" +
+	"\x1cRemoveBannerFromSlotResponse\"\x8b\x01\n" +
+	"\x13ChooseBannerRequest\x12\x17\n" +
+	"\aslot_id\x18\x01 \x01(\x03R\x06slotId\x12\x19\n" +
+	"\bgroup_id\x18\x02 \x01(\x03R\agroupId\x12\x18\n" +
+	"\acountry\x18\x03 \x01(\tR\acountry\x12\x16\n" +
+	"\x06device\x18\x04 \x01(\tR\x06device\x12\x0e\n" +
+	"\x02os\x18\x05 \x01(\tR\x02os\"3\n" +
+	"\x14ChooseBannerResponse\x12\x1b\n" +
+	"\tbanner_id\x18\x01 \x01(\x03R\bbannerId\"e\n" +
+	"\x12RecordClickRequest\x12\x17\n" +
+	"\aslot_id\x18\x01 \x01(\x03R\x06slotId\x12\x1b\n" +
+	"\tbanner_id\x18\x02 \x01(\x03R\bbannerId\x12\x19\n" +
+	"\bgroup_id\x18\x03 \x01(\x03R\agroupId\"\x15\n" +
+	"\x13RecordClickResponse2\x82\x03\n" +
+	"\x0eBannerRotation\x12\\\n" +
+	"\x0fAddBannerToSlot\x12#.rotation.v1.AddBannerToSlotRequest\x1a$.rotation.v1.AddBannerToSlotResponse\x12k\n" +
+	"\x14RemoveBannerFromSlot\x12(.rotation.v1.RemoveBannerFromSlotRequest\x1a).rotation.v1.RemoveBannerFromSlotResponse\x12S\n" +
+	"\fChooseBanner\x12 .rotation.v1.ChooseBannerRequest\x1a!.rotation.v1.ChooseBannerResponse\x12P\n" +
+	"\vRecordClick\x12\x1f.rotation.v1.RecordClickRequest\x1a .rotation.v1.RecordClickResponseB\"Z banner-rotation/internal/grpc/pbb\x06proto3"
+
+var (
+	file_rotation_proto_rawDescOnce sync.Once
+	file_rotation_proto_rawDescData []byte
+)
+
+func file_rotation_proto_rawDescGZIP() []byte {
+	file_rotation_proto_rawDescOnce.Do(func() {
+		file_rotation_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_rotation_proto_rawDesc), len(file_rotation_proto_rawDesc)))
+	})
+	return file_rotation_proto_rawDescData
+}
+
+var file_rotation_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_rotation_proto_goTypes = []any{
+	(*AddBannerToSlotRequest)(nil),       // 0: rotation.v1.AddBannerToSlotRequest
+	(*AddBannerToSlotResponse)(nil),      // 1: rotation.v1.AddBannerToSlotResponse
+	(*RemoveBannerFromSlotRequest)(nil),  // 2: rotation.v1.RemoveBannerFromSlotRequest
+	(*RemoveBannerFromSlotResponse)(nil), // 3: rotation.v1.RemoveBannerFromSlotResponse
+	(*ChooseBannerRequest)(nil),          // 4: rotation.v1.ChooseBannerRequest
+	(*ChooseBannerResponse)(nil),         // 5: rotation.v1.ChooseBannerResponse
+	(*RecordClickRequest)(nil),           // 6: rotation.v1.RecordClickRequest
+	(*RecordClickResponse)(nil),          // 7: rotation.v1.RecordClickResponse
+}
+var file_rotation_proto_depIdxs = []int32{
+	0, // 0: rotation.v1.BannerRotation.AddBannerToSlot:input_type -> rotation.v1.AddBannerToSlotRequest
+	2, // 1: rotation.v1.BannerRotation.RemoveBannerFromSlot:input_type -> rotation.v1.RemoveBannerFromSlotRequest
+	4, // 2: rotation.v1.BannerRotation.ChooseBanner:input_type -> rotation.v1.ChooseBannerRequest
+	6, // 3: rotation.v1.BannerRotation.RecordClick:input_type -> rotation.v1.RecordClickRequest
+	1, // 4: rotation.v1.BannerRotation.AddBannerToSlot:output_type -> rotation.v1.AddBannerToSlotResponse
+	3, // 5: rotation.v1.BannerRotation.RemoveBannerFromSlot:output_type -> rotation.v1.RemoveBannerFromSlotResponse
+	5, // 6: rotation.v1.BannerRotation.ChooseBanner:output_type -> rotation.v1.ChooseBannerResponse
+	7, // 7: rotation.v1.BannerRotation.RecordClick:output_type -> rotation.v1.RecordClickResponse
+	4, // [4:8] is the sub-list for method output_type
+	0, // [0:4] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_rotation_proto_init() }
+func file_rotation_proto_init() {
+	if File_rotation_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_rotation_proto_rawDesc), len(file_rotation_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_rotation_proto_goTypes,
+		DependencyIndexes: file_rotation_proto_depIdxs,
+		MessageInfos:      file_rotation_proto_msgTypes,
+	}.Build()
+	File_rotation_proto = out.File
+	file_rotation_proto_goTypes = nil
+	file_rotation_proto_depIdxs = nil
+}
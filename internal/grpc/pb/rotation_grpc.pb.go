@@ -0,0 +1,247 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: rotation.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	BannerRotation_AddBannerToSlot_FullMethodName      = "/rotation.v1.BannerRotation/AddBannerToSlot"
+	BannerRotation_RemoveBannerFromSlot_FullMethodName = "/rotation.v1.BannerRotation/RemoveBannerFromSlot"
+	BannerRotation_ChooseBanner_FullMethodName         = "/rotation.v1.BannerRotation/ChooseBanner"
+	BannerRotation_RecordClick_FullMethodName          = "/rotation.v1.BannerRotation/RecordClick"
+)
+
+// BannerRotationClient is the client API for BannerRotation service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// BannerRotation - ротация баннеров, повторяет REST API /api/v1
+type BannerRotationClient interface {
+	// Добавляет баннер в ротацию слота
+	AddBannerToSlot(ctx context.Context, in *AddBannerToSlotRequest, opts ...grpc.CallOption) (*AddBannerToSlotResponse, error)
+	// Удаляет баннер из ротации слота
+	RemoveBannerFromSlot(ctx context.Context, in *RemoveBannerFromSlotRequest, opts ...grpc.CallOption) (*RemoveBannerFromSlotResponse, error)
+	// Выбирает баннер для показа и засчитывает показ
+	ChooseBanner(ctx context.Context, in *ChooseBannerRequest, opts ...grpc.CallOption) (*ChooseBannerResponse, error)
+	// Засчитывает клик по баннеру
+	RecordClick(ctx context.Context, in *RecordClickRequest, opts ...grpc.CallOption) (*RecordClickResponse, error)
+}
+
+type bannerRotationClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBannerRotationClient(cc grpc.ClientConnInterface) BannerRotationClient {
+	return &bannerRotationClient{cc}
+}
+
+func (c *bannerRotationClient) AddBannerToSlot(ctx context.Context, in *AddBannerToSlotRequest, opts ...grpc.CallOption) (*AddBannerToSlotResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddBannerToSlotResponse)
+	err := c.cc.Invoke(ctx, BannerRotation_AddBannerToSlot_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bannerRotationClient) RemoveBannerFromSlot(ctx context.Context, in *RemoveBannerFromSlotRequest, opts ...grpc.CallOption) (*RemoveBannerFromSlotResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveBannerFromSlotResponse)
+	err := c.cc.Invoke(ctx, BannerRotation_RemoveBannerFromSlot_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bannerRotationClient) ChooseBanner(ctx context.Context, in *ChooseBannerRequest, opts ...grpc.CallOption) (*ChooseBannerResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChooseBannerResponse)
+	err := c.cc.Invoke(ctx, BannerRotation_ChooseBanner_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bannerRotationClient) RecordClick(ctx context.Context, in *RecordClickRequest, opts ...grpc.CallOption) (*RecordClickResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RecordClickResponse)
+	err := c.cc.Invoke(ctx, BannerRotation_RecordClick_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BannerRotationServer is the server API for BannerRotation service.
+// All implementations must embed UnimplementedBannerRotationServer
+// for forward compatibility.
+//
+// BannerRotation - ротация баннеров, повторяет REST API /api/v1
+type BannerRotationServer interface {
+	// Добавляет баннер в ротацию слота
+	AddBannerToSlot(context.Context, *AddBannerToSlotRequest) (*AddBannerToSlotResponse, error)
+	// Удаляет баннер из ротации слота
+	RemoveBannerFromSlot(context.Context, *RemoveBannerFromSlotRequest) (*RemoveBannerFromSlotResponse, error)
+	// Выбирает баннер для показа и засчитывает показ
+	ChooseBanner(context.Context, *ChooseBannerRequest) (*ChooseBannerResponse, error)
+	// Засчитывает клик по баннеру
+	RecordClick(context.Context, *RecordClickRequest) (*RecordClickResponse, error)
+	mustEmbedUnimplementedBannerRotationServer()
+}
+
+// UnimplementedBannerRotationServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedBannerRotationServer struct{}
+
+func (UnimplementedBannerRotationServer) AddBannerToSlot(context.Context, *AddBannerToSlotRequest) (*AddBannerToSlotResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddBannerToSlot not implemented")
+}
+func (UnimplementedBannerRotationServer) RemoveBannerFromSlot(context.Context, *RemoveBannerFromSlotRequest) (*RemoveBannerFromSlotResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveBannerFromSlot not implemented")
+}
+func (UnimplementedBannerRotationServer) ChooseBanner(context.Context, *ChooseBannerRequest) (*ChooseBannerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChooseBanner not implemented")
+}
+func (UnimplementedBannerRotationServer) RecordClick(context.Context, *RecordClickRequest) (*RecordClickResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RecordClick not implemented")
+}
+func (UnimplementedBannerRotationServer) mustEmbedUnimplementedBannerRotationServer() {}
+func (UnimplementedBannerRotationServer) testEmbeddedByValue()                        {}
+
+// UnsafeBannerRotationServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BannerRotationServer will
+// result in compilation errors.
+type UnsafeBannerRotationServer interface {
+	mustEmbedUnimplementedBannerRotationServer()
+}
+
+func RegisterBannerRotationServer(s grpc.ServiceRegistrar, srv BannerRotationServer) {
+	// If the following call pancis, it indicates UnimplementedBannerRotationServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&BannerRotation_ServiceDesc, srv)
+}
+
+func _BannerRotation_AddBannerToSlot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddBannerToSlotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BannerRotationServer).AddBannerToSlot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BannerRotation_AddBannerToSlot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BannerRotationServer).AddBannerToSlot(ctx, req.(*AddBannerToSlotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BannerRotation_RemoveBannerFromSlot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveBannerFromSlotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BannerRotationServer).RemoveBannerFromSlot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BannerRotation_RemoveBannerFromSlot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BannerRotationServer).RemoveBannerFromSlot(ctx, req.(*RemoveBannerFromSlotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BannerRotation_ChooseBanner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChooseBannerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BannerRotationServer).ChooseBanner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BannerRotation_ChooseBanner_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BannerRotationServer).ChooseBanner(ctx, req.(*ChooseBannerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BannerRotation_RecordClick_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecordClickRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BannerRotationServer).RecordClick(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BannerRotation_RecordClick_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BannerRotationServer).RecordClick(ctx, req.(*RecordClickRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// BannerRotation_ServiceDesc is the grpc.ServiceDesc for BannerRotation service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BannerRotation_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "rotation.v1.BannerRotation",
+	HandlerType: (*BannerRotationServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddBannerToSlot",
+			Handler:    _BannerRotation_AddBannerToSlot_Handler,
+		},
+		{
+			MethodName: "RemoveBannerFromSlot",
+			Handler:    _BannerRotation_RemoveBannerFromSlot_Handler,
+		},
+		{
+			MethodName: "ChooseBanner",
+			Handler:    _BannerRotation_ChooseBanner_Handler,
+		},
+		{
+			MethodName: "RecordClick",
+			Handler:    _BannerRotation_RecordClick_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "rotation.proto",
+}
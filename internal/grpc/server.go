@@ -0,0 +1,164 @@
+package grpc
+
+import (
+	"banner-rotation/internal/app"
+	"banner-rotation/internal/auth"
+	"banner-rotation/internal/grpc/pb"
+	"banner-rotation/internal/storage"
+	"context"
+	"errors"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server - gRPC API ротации баннеров поверх того же сервиса, что и REST API
+type Server struct {
+	pb.UnimplementedBannerRotationServer
+
+	bandit app.BanditInterface
+	server *grpc.Server
+	// reserveShows - показы подтверждаются пикселем REST API,
+	// которого в ответе gRPC нет
+	reserveShows bool
+}
+
+// NewServer создает сервер; authenticator nil выключает проверку API-ключей
+func NewServer(bandit app.BanditInterface, authenticator *auth.Authenticator) *Server {
+	var opts []grpc.ServerOption
+	if authenticator != nil {
+		opts = append(opts, grpc.UnaryInterceptor(authInterceptor(authenticator)))
+	}
+
+	s := &Server{
+		bandit: bandit,
+		server: grpc.NewServer(opts...),
+	}
+	pb.RegisterBannerRotationServer(s.server, s)
+	return s
+}
+
+// SetShowReservations отражает настройку tracking.reserve_shows. Пока она
+// включена, ChooseBanner отклоняется с FailedPrecondition: ответ gRPC
+// не содержит пикселя показа, и сразу засчитанный показ разошелся бы
+// с учетом REST API. Вызывается до Start.
+func (s *Server) SetShowReservations(enabled bool) {
+	s.reserveShows = enabled
+}
+
+// Start слушает address и обслуживает запросы до вызова Shutdown
+func (s *Server) Start(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve обслуживает запросы на готовом listener. Остановка через Shutdown
+// не считается ошибкой.
+func (s *Server) Serve(listener net.Listener) error {
+	if err := s.server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown дожидается завершения текущих запросов, а если ctx истек
+// раньше - обрывает их
+func (s *Server) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
+
+func (s *Server) AddBannerToSlot(ctx context.Context, req *pb.AddBannerToSlotRequest) (*pb.AddBannerToSlotResponse, error) {
+	if err := requireIDs(req.GetSlotId(), req.GetBannerId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.bandit.AddBannerToSlot(ctx, int(req.GetSlotId()), int(req.GetBannerId())); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.AddBannerToSlotResponse{}, nil
+}
+
+func (s *Server) RemoveBannerFromSlot(ctx context.Context, req *pb.RemoveBannerFromSlotRequest) (*pb.RemoveBannerFromSlotResponse, error) {
+	if err := requireIDs(req.GetSlotId(), req.GetBannerId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.bandit.RemoveBannerFromSlot(ctx, int(req.GetSlotId()), int(req.GetBannerId())); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.RemoveBannerFromSlotResponse{}, nil
+}
+
+func (s *Server) ChooseBanner(ctx context.Context, req *pb.ChooseBannerRequest) (*pb.ChooseBannerResponse, error) {
+	if err := requireIDs(req.GetSlotId(), req.GetGroupId()); err != nil {
+		return nil, err
+	}
+	if s.reserveShows {
+		return nil, status.Error(codes.FailedPrecondition, "shows are confirmed by impression pixel, use POST /api/v1/choose_banner")
+	}
+
+	visitor := storage.Visitor{Country: req.GetCountry(), Device: req.GetDevice(), OS: req.GetOs()}
+	bannerID, err := s.bandit.ChooseBanner(ctx, int(req.GetSlotId()), int(req.GetGroupId()), visitor)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.ChooseBannerResponse{BannerId: int64(bannerID)}, nil
+}
+
+func (s *Server) RecordClick(ctx context.Context, req *pb.RecordClickRequest) (*pb.RecordClickResponse, error) {
+	if err := requireIDs(req.GetSlotId(), req.GetBannerId(), req.GetGroupId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.bandit.RecordClick(ctx, int(req.GetSlotId()), int(req.GetBannerId()), int(req.GetGroupId())); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.RecordClickResponse{}, nil
+}
+
+// requireIDs проверяет, что все идентификаторы заданы, как binding:"required" в REST API
+func requireIDs(ids ...int64) error {
+	for _, id := range ids {
+		if id <= 0 {
+			return status.Error(codes.InvalidArgument, "ids must be positive")
+		}
+	}
+	return nil
+}
+
+// toStatus сопоставляет ошибке сервиса код gRPC так же, как REST API - HTTP-статус
+func toStatus(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, auth.ErrUnauthenticated):
+		code = codes.Unauthenticated
+	case errors.Is(err, auth.ErrForbidden):
+		code = codes.PermissionDenied
+	case errors.Is(err, storage.ErrNotFound):
+		code = codes.NotFound
+	case errors.Is(err, storage.ErrConflict):
+		code = codes.FailedPrecondition
+	case errors.Is(err, storage.ErrInvalid):
+		code = codes.InvalidArgument
+	case storage.IsUnavailable(err):
+		code = codes.Unavailable
+	}
+	return status.Error(code, err.Error())
+}
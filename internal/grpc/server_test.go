@@ -0,0 +1,158 @@
+package grpc
+
+import (
+	"banner-rotation/internal/app"
+	"banner-rotation/internal/auth"
+	"banner-rotation/internal/grpc/pb"
+	"banner-rotation/internal/storage"
+	"banner-rotation/internal/storage/memory"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// mapKeyStore - ключи по хешу
+type mapKeyStore map[string]auth.Key
+
+func (s mapKeyStore) LookupKey(ctx context.Context, hash string) (auth.Key, error) {
+	key, ok := s[hash]
+	if !ok {
+		return auth.Key{}, storage.ErrNotFound
+	}
+	return key, nil
+}
+
+// dial запускает сервер на bufconn и возвращает клиента к нему
+func dial(t *testing.T, server *Server) (pb.BannerRotationClient, <-chan error) {
+	listener := bufconn.Listen(1 << 20)
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewBannerRotationClient(conn), served
+}
+
+// newRotationStore создает хранилище со слотом 1 и баннером 1 в его ротации
+func newRotationStore(t *testing.T) *memory.MemoryStorage {
+	t.Helper()
+	ctx := context.Background()
+	store := memory.New()
+	_, err := store.CreateSlot(ctx, storage.Slot{ID: 1})
+	require.NoError(t, err)
+	_, err = store.CreateBanner(ctx, storage.Banner{ID: 1})
+	require.NoError(t, err)
+	require.NoError(t, store.AddBannerToSlot(ctx, 1, 1))
+	return store
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	_, err := store.CreateBanner(ctx, storage.Banner{ID: 1})
+	require.NoError(t, err)
+	_, err = store.CreateSlot(ctx, storage.Slot{ID: 1})
+	require.NoError(t, err)
+	_, err = store.CreateGroup(ctx, storage.Group{ID: 1})
+	require.NoError(t, err)
+
+	server := NewServer(app.NewBandit(store, nil), nil)
+	client, served := dial(t, server)
+
+	t.Run("choose and click", func(t *testing.T) {
+		_, err := client.AddBannerToSlot(ctx, &pb.AddBannerToSlotRequest{SlotId: 1, BannerId: 1})
+		require.NoError(t, err)
+
+		resp, err := client.ChooseBanner(ctx, &pb.ChooseBannerRequest{SlotId: 1, GroupId: 1})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), resp.GetBannerId())
+
+		_, err = client.RecordClick(ctx, &pb.RecordClickRequest{SlotId: 1, BannerId: 1, GroupId: 1})
+		require.NoError(t, err)
+
+		stats, err := store.GetBannerStats(ctx, 1, 1)
+		require.NoError(t, err)
+		require.Len(t, stats, 1)
+		assert.Equal(t, 1, stats[0].Shows)
+		assert.Equal(t, 1, stats[0].Clicks)
+	})
+
+	t.Run("errors map to codes", func(t *testing.T) {
+		_, err := client.ChooseBanner(ctx, &pb.ChooseBannerRequest{SlotId: 1})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = client.RecordClick(ctx, &pb.RecordClickRequest{SlotId: 2, BannerId: 1, GroupId: 1})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+
+		_, err = client.RemoveBannerFromSlot(ctx, &pb.RemoveBannerFromSlotRequest{SlotId: 1, BannerId: 1})
+		require.NoError(t, err)
+		_, err = client.ChooseBanner(ctx, &pb.ChooseBannerRequest{SlotId: 1, GroupId: 1})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		shutdownCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		require.NoError(t, server.Shutdown(shutdownCtx))
+		assert.NoError(t, <-served)
+	})
+}
+
+func TestServer_ShowReservations(t *testing.T) {
+	ctx := context.Background()
+	store := newRotationStore(t)
+
+	server := NewServer(app.NewBandit(store, nil), nil)
+	server.SetShowReservations(true)
+	defer server.Shutdown(ctx)
+	client, _ := dial(t, server)
+
+	_, err := client.ChooseBanner(ctx, &pb.ChooseBannerRequest{SlotId: 1, GroupId: 1})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	stats, err := store.GetBannerStats(ctx, 1, 1)
+	require.NoError(t, err)
+	assert.Empty(t, stats, "show is not recorded without pixel")
+}
+
+func TestServer_Auth(t *testing.T) {
+	ctx := context.Background()
+	store := newRotationStore(t)
+
+	authenticator := auth.NewAuthenticator(mapKeyStore{
+		auth.HashKey("serve-key"): {ID: 1, Scopes: []auth.Scope{auth.ScopeServe}},
+	}, time.Minute)
+	server := NewServer(app.NewBandit(store, nil), authenticator)
+	defer server.Shutdown(ctx)
+	client, _ := dial(t, server)
+
+	_, err := client.ChooseBanner(ctx, &pb.ChooseBannerRequest{SlotId: 1, GroupId: 1})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	serveCtx := metadata.AppendToOutgoingContext(ctx, "x-api-key", "serve-key")
+	_, err = client.ChooseBanner(serveCtx, &pb.ChooseBannerRequest{SlotId: 1, GroupId: 1})
+	require.NoError(t, err)
+
+	bearerCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer serve-key")
+	_, err = client.ChooseBanner(bearerCtx, &pb.ChooseBannerRequest{SlotId: 1, GroupId: 1})
+	require.NoError(t, err)
+
+	_, err = client.RemoveBannerFromSlot(serveCtx, &pb.RemoveBannerFromSlotRequest{SlotId: 1, BannerId: 1})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
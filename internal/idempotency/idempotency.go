@@ -0,0 +1,38 @@
+// Package idempotency хранит ответы на запросы с заголовком Idempotency-Key,
+// чтобы повтор запроса получил тот же ответ без повторного выполнения
+package idempotency
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultTTL - сколько хранится ответ на запрос с ключом
+const DefaultTTL = 24 * time.Hour
+
+// Response - сохраненный ответ на запрос
+type Response struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// Record - состояние ключа
+type Record struct {
+	// Fingerprint - отпечаток запроса, с которым ключ был использован впервые
+	Fingerprint string
+	// Response - nil, пока первый запрос с ключом выполняется
+	Response *Response
+}
+
+// Store - хранилище ключей идемпотентности. Ключ, срок которого истек,
+// считается свободным.
+type Store interface {
+	// Begin занимает свободный ключ на ttl и возвращает true. Если ключ
+	// занят, возвращает его запись и false.
+	Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (Record, bool, error)
+	// Complete сохраняет ответ на запрос с занятым ключом на ttl
+	Complete(ctx context.Context, key string, resp Response, ttl time.Duration) error
+	// Release освобождает ключ без ответа, чтобы запрос можно было повторить
+	Release(ctx context.Context, key string) error
+}
@@ -0,0 +1,60 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	_, created, err := store.Begin(ctx, "k1", "fp", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	t.Run("in progress", func(t *testing.T) {
+		rec, created, err := store.Begin(ctx, "k1", "fp", time.Hour)
+		require.NoError(t, err)
+		assert.False(t, created)
+		assert.Equal(t, Record{Fingerprint: "fp"}, rec)
+	})
+
+	t.Run("completed", func(t *testing.T) {
+		resp := Response{Status: 200, ContentType: "application/json", Body: []byte(`{"banner_id":1}`)}
+		require.NoError(t, store.Complete(ctx, "k1", resp, time.Hour))
+
+		rec, created, err := store.Begin(ctx, "k1", "other", time.Hour)
+		require.NoError(t, err)
+		assert.False(t, created)
+		assert.Equal(t, "fp", rec.Fingerprint)
+		assert.Equal(t, &resp, rec.Response)
+	})
+
+	t.Run("expired key is free", func(t *testing.T) {
+		now = now.Add(2 * time.Hour)
+		_, created, err := store.Begin(ctx, "k1", "fp2", time.Hour)
+		require.NoError(t, err)
+		assert.True(t, created)
+	})
+
+	t.Run("released key is free", func(t *testing.T) {
+		require.NoError(t, store.Release(ctx, "k1"))
+		_, created, err := store.Begin(ctx, "k1", "fp3", time.Hour)
+		require.NoError(t, err)
+		assert.True(t, created)
+	})
+
+	t.Run("expired keys are swept", func(t *testing.T) {
+		now = now.Add(2 * time.Hour)
+		_, _, err := store.Begin(ctx, "k2", "fp", time.Hour)
+		require.NoError(t, err)
+		assert.Len(t, store.records, 1)
+	})
+}
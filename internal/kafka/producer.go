@@ -55,6 +55,27 @@ func (p *Producer) SendEvent(ctx context.Context, eventType events.EventType, sl
 	return nil
 }
 
+// Ping проверяет, что брокеры отвечают и топик писателя существует.
+// Для писателей, отличных от kafka.Writer, проверка не выполняется.
+func (p *Producer) Ping(ctx context.Context) error {
+	writer, ok := p.writer.(*kafka.Writer)
+	if !ok {
+		return nil
+	}
+
+	client := &kafka.Client{Addr: writer.Addr, Transport: writer.Transport}
+	resp, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{writer.Topic}})
+	if err != nil {
+		return fmt.Errorf("failed to fetch kafka metadata: %w", err)
+	}
+	for _, topic := range resp.Topics {
+		if topic.Error != nil {
+			return fmt.Errorf("kafka topic %s: %w", topic.Name, topic.Error)
+		}
+	}
+	return nil
+}
+
 func (p *Producer) Close() error {
 	return p.writer.Close()
 }
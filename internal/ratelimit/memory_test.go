@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+	limit := Limit{Rate: 2, Burst: 3}
+
+	t.Run("burst then limited", func(t *testing.T) {
+		for i := 0; i < limit.Burst; i++ {
+			ok, _, err := limiter.Allow(ctx, "a", limit)
+			require.NoError(t, err)
+			assert.True(t, ok, "request %d", i)
+		}
+
+		ok, retryAfter, err := limiter.Allow(ctx, "a", limit)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, 500*time.Millisecond, retryAfter)
+	})
+
+	t.Run("keys are independent", func(t *testing.T) {
+		ok, _, err := limiter.Allow(ctx, "b", limit)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("refills at rate", func(t *testing.T) {
+		now = now.Add(500 * time.Millisecond)
+		ok, _, err := limiter.Allow(ctx, "a", limit)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, _, err = limiter.Allow(ctx, "a", limit)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("full buckets are swept", func(t *testing.T) {
+		now = now.Add(time.Hour)
+		_, _, err := limiter.Allow(ctx, "c", limit)
+		require.NoError(t, err)
+		assert.NotContains(t, limiter.buckets, "a")
+		assert.NotContains(t, limiter.buckets, "b")
+		assert.Contains(t, limiter.buckets, "c")
+	})
+}
@@ -0,0 +1,44 @@
+// Package ratelimit ограничивает частоту запросов клиентов корзиной токенов.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrLimited - запрос отклонен, потому что клиент исчерпал лимит
+var ErrLimited = errors.New("rate limit exceeded")
+
+// Limit - параметры корзины токенов: Burst запросов подряд, затем Rate запросов в секунду
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Enabled сообщает, задан ли лимит. Нулевой Limit не ограничивает запросы.
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// Limiter хранит корзины токенов по ключу клиента
+type Limiter interface {
+	// Allow списывает токен из корзины key. Если токенов нет, возвращает
+	// false и время, через которое появится следующий токен.
+	Allow(ctx context.Context, key string, limit Limit) (bool, time.Duration, error)
+}
+
+// refill возвращает число токенов в корзине спустя elapsed после
+// последнего обновления, когда в ней было tokens
+func refill(tokens float64, elapsed time.Duration, limit Limit) float64 {
+	if elapsed > 0 {
+		tokens += elapsed.Seconds() * limit.Rate
+	}
+	return math.Min(tokens, float64(limit.Burst))
+}
+
+// Wait возвращает время, через которое в корзине с tokens появится целый токен
+func (l Limit) Wait(tokens float64) time.Duration {
+	return time.Duration(math.Ceil((1 - tokens) / l.Rate * float64(time.Second)))
+}
@@ -0,0 +1,413 @@
+package bolt
+
+import (
+	"banner-rotation/internal/storage"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketBanners       = []byte("banners")
+	bucketBannerAttrs   = []byte("banner_creatives")
+	bucketSlots         = []byte("slots")
+	bucketSlotSizes     = []byte("slot_sizes")
+	bucketGroups        = []byte("groups")
+	bucketGroupRules    = []byte("group_rules")
+	bucketAdvertisers   = []byte("advertisers")
+	bucketCampaigns     = []byte("campaigns")
+	bucketCampaignAttrs = []byte("campaign_attrs")
+	bucketBannerSlots   = []byte("banner_slots")
+	bucketStatistics    = []byte("statistics")
+	bucketHourly        = []byte("statistics_hourly")
+	bucketDaily         = []byte("statistics_daily")
+)
+
+// BoltStorage - встроенное файловое хранилище на bbolt для
+// однонодовых инсталляций без PostgreSQL.
+//
+// Раскладка повторяет схему PostgreSQL:
+//   - banners, slots, groups: id -> description
+//   - advertisers, campaigns: id -> name
+//   - banner_creatives: banner_id -> креатив и кампания в JSON
+//   - campaign_attrs: campaign_id -> кампания в JSON
+//   - slot_sizes: slot_id -> допустимые размеры в JSON
+//   - group_rules: group_id -> правило подбора группы в JSON
+//   - banner_slots: slot_id|banner_id -> ограничения показа в JSON или пусто
+//   - statistics: slot_id|banner_id|group_id -> shows|clicks
+//   - statistics_hourly, statistics_daily: slot_id|banner_id|group_id|bucket -> shows|clicks
+type BoltStorage struct {
+	db *bolt.DB
+}
+
+var (
+	_ storage.Storage       = (*BoltStorage)(nil)
+	_ storage.BatchRecorder = (*BoltStorage)(nil)
+)
+
+func New(path string) (*BoltStorage, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt db: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{
+			bucketBanners, bucketBannerAttrs, bucketSlots, bucketSlotSizes, bucketGroups, bucketGroupRules,
+			bucketAdvertisers, bucketCampaigns, bucketCampaignAttrs,
+			bucketBannerSlots, bucketStatistics, bucketHourly, bucketDaily,
+		} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create buckets: %w", err)
+	}
+
+	return &BoltStorage{db: db}, nil
+}
+
+func (s *BoltStorage) AddBannerToSlot(ctx context.Context, slotID, bannerID int) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		// Проверка существования баннера и слота
+		if tx.Bucket(bucketBanners).Get(encodeKey(bannerID)) == nil {
+			return fmt.Errorf("banner %d: %w", bannerID, storage.ErrNotFound)
+		}
+		if tx.Bucket(bucketSlots).Get(encodeKey(slotID)) == nil {
+			return fmt.Errorf("slot %d: %w", slotID, storage.ErrNotFound)
+		}
+
+		return addBannerTx(tx, slotID, bannerID)
+	})
+}
+
+func (s *BoltStorage) RemoveBannerFromSlot(ctx context.Context, slotID, bannerID int) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return removeBannerTx(tx, slotID, bannerID)
+	})
+}
+
+// UpdateSlotRotation применяет изменение в одной транзакции bbolt:
+// при любой ошибке ротация остается прежней
+func (s *BoltStorage) UpdateSlotRotation(ctx context.Context, slotID int, change storage.RotationChange) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if len(change.Add) > 0 && tx.Bucket(bucketSlots).Get(encodeKey(slotID)) == nil {
+			return fmt.Errorf("slot %d: %w", slotID, storage.ErrNotFound)
+		}
+
+		var current []int
+		prefix := encodeKey(slotID)
+		c := tx.Bucket(bucketBannerSlots).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			current = append(current, decodeKey(k)[1])
+		}
+		for _, id := range change.Removes(current) {
+			if err := removeBannerTx(tx, slotID, id); err != nil {
+				return err
+			}
+		}
+
+		for _, id := range change.Add {
+			if tx.Bucket(bucketBanners).Get(encodeKey(id)) == nil {
+				return fmt.Errorf("banner %d: %w", id, storage.ErrNotFound)
+			}
+			if err := addBannerTx(tx, slotID, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// addBannerTx добавляет баннер в ротацию, сохраняя ограничения показа,
+// если баннер уже в ней
+func addBannerTx(tx *bolt.Tx, slotID, bannerID int) error {
+	b := tx.Bucket(bucketBannerSlots)
+	if b.Get(encodeKey(slotID, bannerID)) != nil {
+		return nil
+	}
+	return b.Put(encodeKey(slotID, bannerID), []byte{})
+}
+
+func (s *BoltStorage) SetBannerTargeting(ctx context.Context, slotID, bannerID int, targeting storage.Targeting) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketBannerSlots)
+		key := encodeKey(slotID, bannerID)
+		if b.Get(key) == nil {
+			return fmt.Errorf("banner %d is not in slot %d: %w", bannerID, slotID, storage.ErrNotFound)
+		}
+		if targeting.IsZero() {
+			return b.Put(key, []byte{})
+		}
+
+		data, err := json.Marshal(targeting)
+		if err != nil {
+			return err
+		}
+		return b.Put(key, data)
+	})
+}
+
+func (s *BoltStorage) GetSlotTargeting(ctx context.Context, slotID int) (map[int]storage.Targeting, error) {
+	targeting := make(map[int]storage.Targeting)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		prefix := encodeKey(slotID)
+		c := tx.Bucket(bucketBannerSlots).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			if len(v) == 0 {
+				continue
+			}
+			var t storage.Targeting
+			if err := json.Unmarshal(v, &t); err != nil {
+				return fmt.Errorf("failed to decode targeting of %v: %w", decodeKey(k), err)
+			}
+			targeting[decodeKey(k)[1]] = t
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return targeting, nil
+}
+
+// removeBannerTx удаляет баннер из ротации слота вместе со статистикой
+func removeBannerTx(tx *bolt.Tx, slotID, bannerID int) error {
+	if err := tx.Bucket(bucketBannerSlots).Delete(encodeKey(slotID, bannerID)); err != nil {
+		return err
+	}
+
+	// Аналог ON DELETE CASCADE для статистики
+	for _, name := range [][]byte{bucketStatistics, bucketHourly, bucketDaily} {
+		if err := deletePrefix(tx.Bucket(name), encodeKey(slotID, bannerID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *BoltStorage) RecordShow(ctx context.Context, slotID, bannerID, groupID int) error {
+	return s.increment(slotID, bannerID, groupID, 1, 0)
+}
+
+func (s *BoltStorage) RecordClick(ctx context.Context, slotID, bannerID, groupID int) error {
+	return s.increment(slotID, bannerID, groupID, 0, 1)
+}
+
+// increment увеличивает счетчики текущей часовой корзины
+func (s *BoltStorage) increment(slotID, bannerID, groupID, shows, clicks int) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return recordTx(tx, storage.StatDelta{
+			SlotID:   slotID,
+			BannerID: bannerID,
+			GroupID:  groupID,
+			Hour:     time.Now(),
+			Shows:    shows,
+			Clicks:   clicks,
+		})
+	})
+}
+
+// RecordBatch применяет все приращения одной транзакцией, каждое в свою
+// часовую корзину d.Hour. Нулевой Hour означает текущий час.
+func (s *BoltStorage) RecordBatch(ctx context.Context, deltas []storage.StatDelta) error {
+	now := time.Now()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, d := range deltas {
+			if d.Hour.IsZero() {
+				d.Hour = now
+			}
+			if err := recordTx(tx, d); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// recordTx прибавляет приращение с проверкой внешних ключей statistics
+func recordTx(tx *bolt.Tx, d storage.StatDelta) error {
+	if tx.Bucket(bucketBannerSlots).Get(encodeKey(d.SlotID, d.BannerID)) == nil {
+		return fmt.Errorf("banner %d is not in slot %d: %w", d.BannerID, d.SlotID, storage.ErrNotFound)
+	}
+	if tx.Bucket(bucketGroups).Get(encodeKey(d.GroupID)) == nil {
+		return fmt.Errorf("group %d: %w", d.GroupID, storage.ErrNotFound)
+	}
+
+	hour := storage.HourBucket(d.Hour).Unix()
+	if err := addStat(tx.Bucket(bucketStatistics), encodeKey(d.SlotID, d.BannerID, d.GroupID), d.Shows, d.Clicks); err != nil {
+		return err
+	}
+	return addStat(tx.Bucket(bucketHourly), encodeKey(d.SlotID, d.BannerID, d.GroupID, int(hour)), d.Shows, d.Clicks)
+}
+
+// addStat прибавляет приращения к счетчикам по ключу
+func addStat(b *bolt.Bucket, key []byte, shows, clicks int) error {
+	curShows, curClicks := decodeStat(b.Get(key))
+	return b.Put(key, encodeStat(curShows+shows, curClicks+clicks))
+}
+
+func (s *BoltStorage) GetBannerStats(ctx context.Context, slotID, groupID int) ([]storage.BannerStat, error) {
+	var stats []storage.BannerStat
+	err := s.db.View(func(tx *bolt.Tx) error {
+		prefix := encodeKey(slotID)
+		c := tx.Bucket(bucketStatistics).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			ids := decodeKey(k)
+			if ids[2] != groupID {
+				continue
+			}
+			shows, clicks := decodeStat(v)
+			stats = append(stats, storage.BannerStat{BannerID: ids[1], Shows: shows, Clicks: clicks})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func (s *BoltStorage) GetBannerStatsRange(ctx context.Context, slotID, groupID int, from, to time.Time) ([]storage.BannerStat, error) {
+	fromUnix, toUnix := int(from.Unix()), int(to.Unix())
+	byBanner := make(map[int]*storage.BannerStat)
+	var order []int
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		prefix := encodeKey(slotID)
+		for _, name := range [][]byte{bucketHourly, bucketDaily} {
+			c := tx.Bucket(name).Cursor()
+			for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+				ids := decodeKey(k)
+				if ids[2] != groupID || ids[3] < fromUnix || ids[3] >= toUnix {
+					continue
+				}
+				stat, ok := byBanner[ids[1]]
+				if !ok {
+					stat = &storage.BannerStat{BannerID: ids[1]}
+					byBanner[ids[1]] = stat
+					order = append(order, ids[1])
+				}
+				shows, clicks := decodeStat(v)
+				stat.Shows += shows
+				stat.Clicks += clicks
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Ints(order)
+	stats := make([]storage.BannerStat, 0, len(order))
+	for _, bannerID := range order {
+		stats = append(stats, *byBanner[bannerID])
+	}
+	return stats, nil
+}
+
+func (s *BoltStorage) RollupHourlyStats(ctx context.Context, before time.Time) error {
+	beforeUnix := int(before.Unix())
+	return s.db.Update(func(tx *bolt.Tx) error {
+		hourly, daily := tx.Bucket(bucketHourly), tx.Bucket(bucketDaily)
+
+		var expired [][]byte
+		err := hourly.ForEach(func(k, v []byte) error {
+			ids := decodeKey(k)
+			if ids[3] >= beforeUnix {
+				return nil
+			}
+			day := storage.DayBucket(time.Unix(int64(ids[3]), 0)).Unix()
+			shows, clicks := decodeStat(v)
+			if err := addStat(daily, encodeKey(ids[0], ids[1], ids[2], int(day)), shows, clicks); err != nil {
+				return err
+			}
+			expired = append(expired, append([]byte(nil), k...))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			if err := hourly.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltStorage) GetBannersForSlot(ctx context.Context, slotID int) ([]int, error) {
+	var bannerIDs []int
+	err := s.db.View(func(tx *bolt.Tx) error {
+		prefix := encodeKey(slotID)
+		c := tx.Bucket(bucketBannerSlots).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			bannerIDs = append(bannerIDs, decodeKey(k)[1])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query banners for slot: %w", err)
+	}
+
+	return bannerIDs, nil
+}
+
+func (s *BoltStorage) Close() error {
+	return s.db.Close()
+}
+
+// encodeKey кодирует идентификаторы в big-endian, чтобы курсор
+// обходил ключи в порядке возрастания и работал поиск по префиксу
+func encodeKey(ids ...int) []byte {
+	key := make([]byte, 8*len(ids))
+	for i, id := range ids {
+		binary.BigEndian.PutUint64(key[i*8:], uint64(id))
+	}
+	return key
+}
+
+func decodeKey(key []byte) []int {
+	ids := make([]int, len(key)/8)
+	for i := range ids {
+		ids[i] = int(binary.BigEndian.Uint64(key[i*8:]))
+	}
+	return ids
+}
+
+func encodeStat(shows, clicks int) []byte {
+	return encodeKey(shows, clicks)
+}
+
+func decodeStat(value []byte) (shows, clicks int) {
+	if len(value) != 16 {
+		return 0, 0
+	}
+	ids := decodeKey(value)
+	return ids[0], ids[1]
+}
+
+// deletePrefix удаляет все ключи бакета с указанным префиксом
+func deletePrefix(b *bolt.Bucket, prefix []byte) error {
+	c := b.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Seek(prefix) {
+		if err := c.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
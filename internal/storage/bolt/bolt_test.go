@@ -0,0 +1,460 @@
+package bolt
+
+import (
+	"banner-rotation/internal/storage"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStorage(t *testing.T) (*BoltStorage, string) {
+	path := filepath.Join(t.TempDir(), "rotation.db")
+	store, err := New(path)
+	require.NoError(t, err)
+	return store, path
+}
+
+func TestBoltStorage(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStorage(t)
+	defer store.Close()
+
+	slot, err := store.CreateSlot(ctx, storage.Slot{Description: "main page"})
+	require.NoError(t, err)
+	slotID := slot.ID
+	banner, err := store.CreateBanner(ctx, storage.Banner{Description: "summer sale"})
+	require.NoError(t, err)
+	bannerID := banner.ID
+	group, err := store.CreateGroup(ctx, storage.Group{Description: "adults"})
+	require.NoError(t, err)
+	groupID := group.ID
+
+	t.Run("AddBannerToSlot - unknown banner", func(t *testing.T) {
+		err := store.AddBannerToSlot(ctx, slotID, 100)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+		assert.Contains(t, err.Error(), "banner 100")
+	})
+
+	t.Run("AddBannerToSlot - unknown slot", func(t *testing.T) {
+		err := store.AddBannerToSlot(ctx, 100, bannerID)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+		assert.Contains(t, err.Error(), "slot 100")
+	})
+
+	t.Run("AddBannerToSlot - success", func(t *testing.T) {
+		require.NoError(t, store.AddBannerToSlot(ctx, slotID, bannerID))
+		require.NoError(t, store.AddBannerToSlot(ctx, slotID, bannerID))
+
+		banners, err := store.GetBannersForSlot(ctx, slotID)
+		require.NoError(t, err)
+		assert.Equal(t, []int{bannerID}, banners)
+	})
+
+	t.Run("RecordShow and RecordClick", func(t *testing.T) {
+		require.NoError(t, store.RecordShow(ctx, slotID, bannerID, groupID))
+		require.NoError(t, store.RecordShow(ctx, slotID, bannerID, groupID))
+		require.NoError(t, store.RecordClick(ctx, slotID, bannerID, groupID))
+
+		stats, err := store.GetBannerStats(ctx, slotID, groupID)
+		require.NoError(t, err)
+		require.Len(t, stats, 1)
+		assert.Equal(t, bannerID, stats[0].BannerID)
+		assert.Equal(t, 2, stats[0].Shows)
+		assert.Equal(t, 1, stats[0].Clicks)
+	})
+
+	t.Run("RecordShow - unknown group", func(t *testing.T) {
+		assert.Error(t, store.RecordShow(ctx, slotID, bannerID, 100))
+	})
+
+	t.Run("RemoveBannerFromSlot drops stats", func(t *testing.T) {
+		require.NoError(t, store.RemoveBannerFromSlot(ctx, slotID, bannerID))
+
+		banners, err := store.GetBannersForSlot(ctx, slotID)
+		require.NoError(t, err)
+		assert.Empty(t, banners)
+
+		stats, err := store.GetBannerStats(ctx, slotID, groupID)
+		require.NoError(t, err)
+		assert.Empty(t, stats)
+	})
+}
+
+func TestBoltStorage_Reopen(t *testing.T) {
+	ctx := context.Background()
+	store, path := newTestStorage(t)
+
+	slot, err := store.CreateSlot(ctx, storage.Slot{Description: "sidebar"})
+	require.NoError(t, err)
+	slotID := slot.ID
+	banner, err := store.CreateBanner(ctx, storage.Banner{Description: "banner"})
+	require.NoError(t, err)
+	bannerID := banner.ID
+	group, err := store.CreateGroup(ctx, storage.Group{Description: "group"})
+	require.NoError(t, err)
+	groupID := group.ID
+	require.NoError(t, store.AddBannerToSlot(ctx, slotID, bannerID))
+	require.NoError(t, store.RecordShow(ctx, slotID, bannerID, groupID))
+	require.NoError(t, store.Close())
+
+	store, err = New(path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	stats, err := store.GetBannerStats(ctx, slotID, groupID)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 1, stats[0].Shows)
+}
+
+func TestBoltStorage_TimeBuckets(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStorage(t)
+	defer store.Close()
+
+	slot, err := store.CreateSlot(ctx, storage.Slot{Description: "slot"})
+	require.NoError(t, err)
+	slotID := slot.ID
+	banner, err := store.CreateBanner(ctx, storage.Banner{Description: "banner"})
+	require.NoError(t, err)
+	bannerID := banner.ID
+	group, err := store.CreateGroup(ctx, storage.Group{Description: "group"})
+	require.NoError(t, err)
+	groupID := group.ID
+	require.NoError(t, store.AddBannerToSlot(ctx, slotID, bannerID))
+
+	require.NoError(t, store.RecordShow(ctx, slotID, bannerID, groupID))
+	require.NoError(t, store.RecordShow(ctx, slotID, bannerID, groupID))
+	require.NoError(t, store.RecordClick(ctx, slotID, bannerID, groupID))
+
+	now := time.Now()
+	dayStart := now.Add(-48 * time.Hour)
+	dayEnd := now.Add(48 * time.Hour)
+
+	stats, err := store.GetBannerStatsRange(ctx, slotID, groupID, now.Add(-time.Hour), dayEnd)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 2, stats[0].Shows)
+	assert.Equal(t, 1, stats[0].Clicks)
+
+	stats, err = store.GetBannerStatsRange(ctx, slotID, groupID, dayEnd, dayEnd.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, stats)
+
+	require.NoError(t, store.RollupHourlyStats(ctx, dayEnd))
+
+	stats, err = store.GetBannerStatsRange(ctx, slotID, groupID, dayStart, dayEnd)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 2, stats[0].Shows)
+	assert.Equal(t, 1, stats[0].Clicks)
+}
+
+func TestBoltStorage_RecordBatch(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStorage(t)
+	defer store.Close()
+
+	slot, err := store.CreateSlot(ctx, storage.Slot{Description: "slot"})
+	require.NoError(t, err)
+	banner, err := store.CreateBanner(ctx, storage.Banner{Description: "banner"})
+	require.NoError(t, err)
+	group, err := store.CreateGroup(ctx, storage.Group{Description: "group"})
+	require.NoError(t, err)
+	require.NoError(t, store.AddBannerToSlot(ctx, slot.ID, banner.ID))
+
+	// Приращение прошлого часа попадает в свою корзину, а не в текущую
+	lastHour := storage.HourBucket(time.Now()).Add(-time.Hour)
+	require.NoError(t, store.RecordBatch(ctx, []storage.StatDelta{
+		{SlotID: slot.ID, BannerID: banner.ID, GroupID: group.ID, Hour: lastHour, Shows: 3, Clicks: 1},
+	}))
+
+	stats, err := store.GetBannerStatsRange(ctx, slot.ID, group.ID, lastHour, lastHour.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 3, stats[0].Shows)
+	assert.Equal(t, 1, stats[0].Clicks)
+
+	stats, err = store.GetBannerStatsRange(ctx, slot.ID, group.ID, lastHour.Add(time.Hour), lastHour.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, stats)
+
+	// Пачка с баннером вне слота не применяется целиком
+	err = store.RecordBatch(ctx, []storage.StatDelta{
+		{SlotID: slot.ID, BannerID: banner.ID, GroupID: group.ID, Hour: lastHour, Shows: 1},
+		{SlotID: slot.ID, BannerID: banner.ID + 1, GroupID: group.ID, Hour: lastHour, Shows: 1},
+	})
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	stats, err = store.GetBannerStats(ctx, slot.ID, group.ID)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 3, stats[0].Shows)
+}
+
+func TestBoltStorage_Catalog(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStorage(t)
+	defer store.Close()
+
+	banner, err := store.CreateBanner(ctx, storage.Banner{Description: "first"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, banner.ID)
+
+	_, err = store.CreateBanner(ctx, storage.Banner{ID: 10, Description: "explicit"})
+	require.NoError(t, err)
+	_, err = store.CreateBanner(ctx, storage.Banner{ID: 10, Description: "duplicate"})
+	assert.ErrorIs(t, err, storage.ErrConflict)
+
+	// Автоматический ID не пересекается с явно заданным
+	next, err := store.CreateBanner(ctx, storage.Banner{Description: "next"})
+	require.NoError(t, err)
+	assert.Equal(t, 11, next.ID)
+
+	require.NoError(t, store.UpdateBanner(ctx, storage.Banner{ID: 1, Description: "renamed"}))
+	banner, err = store.GetBanner(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", banner.Description)
+
+	banners, err := store.ListBanners(ctx)
+	require.NoError(t, err)
+	require.Len(t, banners, 3)
+	assert.Equal(t, []int{1, 10, 11}, []int{banners[0].ID, banners[1].ID, banners[2].ID})
+
+	_, err = store.GetBanner(ctx, 100)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	assert.ErrorIs(t, store.UpdateBanner(ctx, storage.Banner{ID: 100}), storage.ErrNotFound)
+	assert.ErrorIs(t, store.DeleteBanner(ctx, 100), storage.ErrNotFound)
+
+	sizes := []storage.Size{{Width: 300, Height: 250}, {Width: 336, Height: 280}}
+	slot, err := store.CreateSlot(ctx, storage.Slot{Description: "slot", Sizes: sizes})
+	require.NoError(t, err)
+	got, err := store.GetSlot(ctx, slot.ID)
+	require.NoError(t, err)
+	assert.Equal(t, sizes, got.Sizes)
+
+	group, err := store.CreateGroup(ctx, storage.Group{Description: "group"})
+	require.NoError(t, err)
+
+	require.NoError(t, store.AddBannerToSlot(ctx, slot.ID, banner.ID))
+	require.NoError(t, store.RecordShow(ctx, slot.ID, banner.ID, group.ID))
+
+	// Сущности, на которые ссылаются ротация и статистика, не удаляются
+	assert.ErrorIs(t, store.DeleteBanner(ctx, banner.ID), storage.ErrConflict)
+	assert.ErrorIs(t, store.DeleteSlot(ctx, slot.ID), storage.ErrConflict)
+	assert.ErrorIs(t, store.DeleteGroup(ctx, group.ID), storage.ErrConflict)
+
+	require.NoError(t, store.RemoveBannerFromSlot(ctx, slot.ID, banner.ID))
+	require.NoError(t, store.DeleteBanner(ctx, banner.ID))
+	require.NoError(t, store.DeleteSlot(ctx, slot.ID))
+	require.NoError(t, store.DeleteGroup(ctx, group.ID))
+
+	_, err = store.GetSlot(ctx, slot.ID)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	groups, err := store.ListGroups(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+}
+
+func TestBoltStorage_Creative(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStorage(t)
+	defer store.Close()
+
+	creative := storage.Creative{
+		ImageURL: "https://cdn.example.com/728x90.png",
+		ClickURL: "https://example.com/landing",
+		Width:    728,
+		Height:   90,
+		AltText:  "sale",
+	}
+	banner, err := store.CreateBanner(ctx, storage.Banner{Description: "leaderboard", Creative: creative})
+	require.NoError(t, err)
+
+	got, err := store.GetBanner(ctx, banner.ID)
+	require.NoError(t, err)
+	assert.Equal(t, creative, got.Creative)
+
+	// Обновление заменяет креатив целиком
+	update := storage.Creative{HTML: "<b>sale</b>"}
+	require.NoError(t, store.UpdateBanner(ctx, storage.Banner{ID: banner.ID, Description: "html", Creative: update}))
+	banners, err := store.ListBanners(ctx)
+	require.NoError(t, err)
+	require.Len(t, banners, 1)
+	assert.Equal(t, update, banners[0].Creative)
+
+	require.NoError(t, store.DeleteBanner(ctx, banner.ID))
+	_, err = store.CreateBanner(ctx, storage.Banner{ID: banner.ID, Description: "plain"})
+	require.NoError(t, err)
+	got, err = store.GetBanner(ctx, banner.ID)
+	require.NoError(t, err)
+	assert.Zero(t, got.Creative)
+}
+
+func TestBoltStorage_Campaigns(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStorage(t)
+	defer store.Close()
+
+	_, err := store.CreateCampaign(ctx, storage.Campaign{AdvertiserID: 1, Name: "orphan"})
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	advertiser, err := store.CreateAdvertiser(ctx, storage.Advertiser{Name: "acme"})
+	require.NoError(t, err)
+
+	campaign := storage.Campaign{
+		AdvertiserID: advertiser.ID,
+		Name:         "winter",
+		Status:       storage.CampaignPaused,
+		Budget:       100000,
+		StartAt:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	campaign, err = store.CreateCampaign(ctx, campaign)
+	require.NoError(t, err)
+
+	got, err := store.GetCampaign(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, campaign, got)
+
+	campaign.Status = storage.CampaignActive
+	campaign.EndAt = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, store.UpdateCampaign(ctx, campaign))
+	campaigns, err := store.ListCampaigns(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.Campaign{campaign}, campaigns)
+
+	_, err = store.CreateBanner(ctx, storage.Banner{Description: "orphan", CampaignID: 100})
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	banner, err := store.CreateBanner(ctx, storage.Banner{Description: "snow", CampaignID: campaign.ID})
+	require.NoError(t, err)
+	gotBanner, err := store.GetBanner(ctx, banner.ID)
+	require.NoError(t, err)
+	assert.Equal(t, campaign.ID, gotBanner.CampaignID)
+
+	// Пакетное чтение пропускает отсутствующие ID и повторы
+	banners, err := store.GetBanners(ctx, []int{banner.ID, 100, banner.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []storage.Banner{gotBanner}, banners)
+	campaigns, err = store.GetCampaigns(ctx, []int{100, campaign.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []storage.Campaign{campaign}, campaigns)
+
+	// Кампания с баннерами и рекламодатель с кампаниями не удаляются
+	assert.ErrorIs(t, store.DeleteCampaign(ctx, campaign.ID), storage.ErrConflict)
+	assert.ErrorIs(t, store.DeleteAdvertiser(ctx, advertiser.ID), storage.ErrConflict)
+
+	require.NoError(t, store.UpdateBanner(ctx, storage.Banner{ID: banner.ID, Description: "snow"}))
+	require.NoError(t, store.DeleteCampaign(ctx, campaign.ID))
+	require.NoError(t, store.DeleteAdvertiser(ctx, advertiser.ID))
+
+	_, err = store.GetCampaign(ctx, campaign.ID)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	advertisers, err := store.ListAdvertisers(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, advertisers)
+}
+
+func TestBoltStorage_UpdateSlotRotation(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStorage(t)
+	defer store.Close()
+
+	slot, err := store.CreateSlot(ctx, storage.Slot{Description: "main page"})
+	require.NoError(t, err)
+	group, err := store.CreateGroup(ctx, storage.Group{Description: "adults"})
+	require.NoError(t, err)
+	var ids []int
+	for range 3 {
+		banner, err := store.CreateBanner(ctx, storage.Banner{Description: "banner"})
+		require.NoError(t, err)
+		ids = append(ids, banner.ID)
+	}
+
+	require.NoError(t, store.UpdateSlotRotation(ctx, slot.ID, storage.RotationChange{Add: ids[:2]}))
+	require.NoError(t, store.RecordShow(ctx, slot.ID, ids[0], group.ID))
+
+	t.Run("unknown banner rolls back the whole change", func(t *testing.T) {
+		err := store.UpdateSlotRotation(ctx, slot.ID, storage.RotationChange{Add: []int{ids[2], 100}, Remove: []int{ids[0]}})
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		banners, err := store.GetBannersForSlot(ctx, slot.ID)
+		require.NoError(t, err)
+		assert.Equal(t, ids[:2], banners)
+
+		stats, err := store.GetBannerStats(ctx, slot.ID, group.ID)
+		require.NoError(t, err)
+		assert.Len(t, stats, 1)
+	})
+
+	t.Run("unknown slot", func(t *testing.T) {
+		err := store.UpdateSlotRotation(ctx, 100, storage.RotationChange{Add: ids[:1]})
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("replace drops stats of removed banners", func(t *testing.T) {
+		require.NoError(t, store.UpdateSlotRotation(ctx, slot.ID, storage.RotationChange{Add: ids[1:], Replace: true}))
+
+		banners, err := store.GetBannersForSlot(ctx, slot.ID)
+		require.NoError(t, err)
+		assert.Equal(t, ids[1:], banners)
+
+		stats, err := store.GetBannerStats(ctx, slot.ID, group.ID)
+		require.NoError(t, err)
+		assert.Empty(t, stats)
+	})
+}
+
+func TestBoltStorage_GroupRules(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStorage(t)
+	defer store.Close()
+
+	rule := &storage.GroupRule{Priority: 1, MinAge: 18, MaxAge: 25, Interests: []string{"sport"}}
+	group, err := store.CreateGroup(ctx, storage.Group{Description: "students", Rule: rule})
+	require.NoError(t, err)
+
+	groups, err := store.ListGroups(ctx)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, rule, groups[0].Rule)
+
+	require.NoError(t, store.UpdateGroup(ctx, storage.Group{ID: group.ID, Description: "everyone"}))
+	got, err := store.GetGroup(ctx, group.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got.Rule)
+}
+
+func TestBoltStorage_Targeting(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStorage(t)
+	defer store.Close()
+
+	slot, err := store.CreateSlot(ctx, storage.Slot{Description: "main page"})
+	require.NoError(t, err)
+	banner, err := store.CreateBanner(ctx, storage.Banner{Description: "banner"})
+	require.NoError(t, err)
+	require.NoError(t, store.AddBannerToSlot(ctx, slot.ID, banner.ID))
+
+	assert.ErrorIs(t, store.SetBannerTargeting(ctx, slot.ID, 100, storage.Targeting{OS: []string{"ios"}}), storage.ErrNotFound)
+
+	targeting := storage.Targeting{Countries: []string{"RU"}, OS: []string{"ios"}}
+	require.NoError(t, store.SetBannerTargeting(ctx, slot.ID, banner.ID, targeting))
+
+	// Повторное добавление не сбрасывает ограничения
+	require.NoError(t, store.AddBannerToSlot(ctx, slot.ID, banner.ID))
+	got, err := store.GetSlotTargeting(ctx, slot.ID)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]storage.Targeting{banner.ID: targeting}, got)
+
+	require.NoError(t, store.SetBannerTargeting(ctx, slot.ID, banner.ID, storage.Targeting{}))
+	got, err = store.GetSlotTargeting(ctx, slot.ID)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
@@ -0,0 +1,159 @@
+package bolt
+
+import (
+	"banner-rotation/internal/storage"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func (s *BoltStorage) CreateAdvertiser(ctx context.Context, advertiser storage.Advertiser) (storage.Advertiser, error) {
+	id, err := s.createEntity(bucketAdvertisers, advertiser.ID, advertiser.Name)
+	if err != nil {
+		return storage.Advertiser{}, err
+	}
+	advertiser.ID = id
+	return advertiser, nil
+}
+
+func (s *BoltStorage) GetAdvertiser(ctx context.Context, id int) (storage.Advertiser, error) {
+	name, err := s.getEntity(bucketAdvertisers, id)
+	if err != nil {
+		return storage.Advertiser{}, err
+	}
+	return storage.Advertiser{ID: id, Name: name}, nil
+}
+
+func (s *BoltStorage) ListAdvertisers(ctx context.Context) ([]storage.Advertiser, error) {
+	var advertisers []storage.Advertiser
+	err := s.listEntities(bucketAdvertisers, func(id int, name string) {
+		advertisers = append(advertisers, storage.Advertiser{ID: id, Name: name})
+	})
+	return advertisers, err
+}
+
+func (s *BoltStorage) UpdateAdvertiser(ctx context.Context, advertiser storage.Advertiser) error {
+	return s.updateEntity(bucketAdvertisers, advertiser.ID, advertiser.Name)
+}
+
+// DeleteAdvertiser удаляет рекламодателя, если у него нет кампаний
+func (s *BoltStorage) DeleteAdvertiser(ctx context.Context, id int) error {
+	return s.deleteEntity(bucketAdvertisers, id, func(tx *bolt.Tx) bool {
+		found := false
+		_ = tx.Bucket(bucketCampaignAttrs).ForEach(func(_, v []byte) error {
+			var campaign storage.Campaign
+			if json.Unmarshal(v, &campaign) == nil && campaign.AdvertiserID == id {
+				found = true
+			}
+			return nil
+		})
+		return found
+	})
+}
+
+func (s *BoltStorage) CreateCampaign(ctx context.Context, campaign storage.Campaign) (storage.Campaign, error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		id, err := createEntityTx(tx, bucketCampaigns, campaign.ID, campaign.Name)
+		if err != nil {
+			return err
+		}
+		campaign.ID = id
+		return putCampaignAttrs(tx, campaign)
+	})
+	if err != nil {
+		return storage.Campaign{}, fmt.Errorf("failed to create campaign: %w", err)
+	}
+	return campaign, nil
+}
+
+func (s *BoltStorage) GetCampaign(ctx context.Context, id int) (storage.Campaign, error) {
+	var campaign storage.Campaign
+	err := s.db.View(func(tx *bolt.Tx) error {
+		name, err := getEntityTx(tx, bucketCampaigns, id)
+		if err != nil {
+			return err
+		}
+		if err := getAttrs(tx, bucketCampaignAttrs, id, &campaign); err != nil {
+			return err
+		}
+		campaign.ID, campaign.Name = id, name
+		return nil
+	})
+	if err != nil {
+		return storage.Campaign{}, err
+	}
+	return campaign, nil
+}
+
+func (s *BoltStorage) GetCampaigns(ctx context.Context, ids []int) ([]storage.Campaign, error) {
+	var campaigns []storage.Campaign
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return someEntitiesTx(tx, bucketCampaigns, ids, func(id int, name string) error {
+			var campaign storage.Campaign
+			if err := getAttrs(tx, bucketCampaignAttrs, id, &campaign); err != nil {
+				return err
+			}
+			campaign.ID, campaign.Name = id, name
+			campaigns = append(campaigns, campaign)
+			return nil
+		})
+	})
+	return campaigns, err
+}
+
+func (s *BoltStorage) ListCampaigns(ctx context.Context) ([]storage.Campaign, error) {
+	var campaigns []storage.Campaign
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketCampaigns).ForEach(func(k, v []byte) error {
+			var campaign storage.Campaign
+			id := decodeKey(k)[0]
+			if err := getAttrs(tx, bucketCampaignAttrs, id, &campaign); err != nil {
+				return err
+			}
+			campaign.ID, campaign.Name = id, string(v)
+			campaigns = append(campaigns, campaign)
+			return nil
+		})
+	})
+	return campaigns, err
+}
+
+func (s *BoltStorage) UpdateCampaign(ctx context.Context, campaign storage.Campaign) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := updateEntityTx(tx, bucketCampaigns, campaign.ID, campaign.Name); err != nil {
+			return err
+		}
+		return putCampaignAttrs(tx, campaign)
+	})
+}
+
+// DeleteCampaign удаляет кампанию, если в ней нет баннеров
+func (s *BoltStorage) DeleteCampaign(ctx context.Context, id int) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		err := deleteEntityTx(tx, bucketCampaigns, id, func(tx *bolt.Tx) bool {
+			found := false
+			_ = tx.Bucket(bucketBannerAttrs).ForEach(func(_, v []byte) error {
+				var attrs bannerAttrs
+				if json.Unmarshal(v, &attrs) == nil && attrs.CampaignID == id {
+					found = true
+				}
+				return nil
+			})
+			return found
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(bucketCampaignAttrs).Delete(encodeKey(id))
+	})
+}
+
+// putCampaignAttrs сохраняет кампанию целиком, проверив рекламодателя
+func putCampaignAttrs(tx *bolt.Tx, campaign storage.Campaign) error {
+	if tx.Bucket(bucketAdvertisers).Get(encodeKey(campaign.AdvertiserID)) == nil {
+		return fmt.Errorf("advertiser %d: %w", campaign.AdvertiserID, storage.ErrNotFound)
+	}
+	return putAttrs(tx, bucketCampaignAttrs, campaign.ID, campaign, false)
+}
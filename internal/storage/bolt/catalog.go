@@ -0,0 +1,417 @@
+package bolt
+
+import (
+	"banner-rotation/internal/storage"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// bannerAttrs - атрибуты баннера в бакете banner_creatives. Креатив
+// встроен, поэтому записи, где хранился только креатив, читаются как есть.
+type bannerAttrs struct {
+	storage.Creative
+	CampaignID int `json:",omitempty"`
+}
+
+func putBannerAttrs(tx *bolt.Tx, banner storage.Banner) error {
+	if banner.CampaignID != 0 && tx.Bucket(bucketCampaigns).Get(encodeKey(banner.CampaignID)) == nil {
+		return fmt.Errorf("campaign %d: %w", banner.CampaignID, storage.ErrNotFound)
+	}
+	attrs := bannerAttrs{Creative: banner.Creative, CampaignID: banner.CampaignID}
+	return putAttrs(tx, bucketBannerAttrs, banner.ID, attrs, attrs == bannerAttrs{})
+}
+
+func getBannerAttrs(tx *bolt.Tx, banner *storage.Banner) error {
+	var attrs bannerAttrs
+	if err := getAttrs(tx, bucketBannerAttrs, banner.ID, &attrs); err != nil {
+		return err
+	}
+	banner.Creative = attrs.Creative
+	banner.CampaignID = attrs.CampaignID
+	return nil
+}
+
+func (s *BoltStorage) CreateBanner(ctx context.Context, banner storage.Banner) (storage.Banner, error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		id, err := createEntityTx(tx, bucketBanners, banner.ID, banner.Description)
+		if err != nil {
+			return err
+		}
+		banner.ID = id
+		return putBannerAttrs(tx, banner)
+	})
+	if err != nil {
+		return storage.Banner{}, fmt.Errorf("failed to create banner: %w", err)
+	}
+	return banner, nil
+}
+
+func (s *BoltStorage) GetBanner(ctx context.Context, id int) (storage.Banner, error) {
+	banner := storage.Banner{ID: id}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		description, err := getEntityTx(tx, bucketBanners, id)
+		if err != nil {
+			return err
+		}
+		banner.Description = description
+		return getBannerAttrs(tx, &banner)
+	})
+	if err != nil {
+		return storage.Banner{}, err
+	}
+	return banner, nil
+}
+
+func (s *BoltStorage) GetBanners(ctx context.Context, ids []int) ([]storage.Banner, error) {
+	var banners []storage.Banner
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return someEntitiesTx(tx, bucketBanners, ids, func(id int, description string) error {
+			banner := storage.Banner{ID: id, Description: description}
+			if err := getBannerAttrs(tx, &banner); err != nil {
+				return err
+			}
+			banners = append(banners, banner)
+			return nil
+		})
+	})
+	return banners, err
+}
+
+func (s *BoltStorage) ListBanners(ctx context.Context) ([]storage.Banner, error) {
+	var banners []storage.Banner
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketBanners).ForEach(func(k, v []byte) error {
+			banner := storage.Banner{ID: decodeKey(k)[0], Description: string(v)}
+			if err := getBannerAttrs(tx, &banner); err != nil {
+				return err
+			}
+			banners = append(banners, banner)
+			return nil
+		})
+	})
+	return banners, err
+}
+
+func (s *BoltStorage) UpdateBanner(ctx context.Context, banner storage.Banner) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := updateEntityTx(tx, bucketBanners, banner.ID, banner.Description); err != nil {
+			return err
+		}
+		return putBannerAttrs(tx, banner)
+	})
+}
+
+// DeleteBanner удаляет баннер, если он не находится в ротации какого-либо слота
+func (s *BoltStorage) DeleteBanner(ctx context.Context, id int) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		err := deleteEntityTx(tx, bucketBanners, id, func(tx *bolt.Tx) bool {
+			// Ключи banner_slots начинаются со слота, поэтому нужен полный обход
+			found := false
+			_ = tx.Bucket(bucketBannerSlots).ForEach(func(k, _ []byte) error {
+				if decodeKey(k)[1] == id {
+					found = true
+				}
+				return nil
+			})
+			return found
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(bucketBannerAttrs).Delete(encodeKey(id))
+	})
+}
+
+func (s *BoltStorage) CreateSlot(ctx context.Context, slot storage.Slot) (storage.Slot, error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		id, err := createEntityTx(tx, bucketSlots, slot.ID, slot.Description)
+		if err != nil {
+			return err
+		}
+		slot.ID = id
+		return putAttrs(tx, bucketSlotSizes, id, slot.Sizes, len(slot.Sizes) == 0)
+	})
+	if err != nil {
+		return storage.Slot{}, fmt.Errorf("failed to create slot: %w", err)
+	}
+	return slot, nil
+}
+
+func (s *BoltStorage) GetSlot(ctx context.Context, id int) (storage.Slot, error) {
+	slot := storage.Slot{ID: id}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		description, err := getEntityTx(tx, bucketSlots, id)
+		if err != nil {
+			return err
+		}
+		slot.Description = description
+		return getAttrs(tx, bucketSlotSizes, id, &slot.Sizes)
+	})
+	if err != nil {
+		return storage.Slot{}, err
+	}
+	return slot, nil
+}
+
+func (s *BoltStorage) ListSlots(ctx context.Context) ([]storage.Slot, error) {
+	var slots []storage.Slot
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketSlots).ForEach(func(k, v []byte) error {
+			slot := storage.Slot{ID: decodeKey(k)[0], Description: string(v)}
+			if err := getAttrs(tx, bucketSlotSizes, slot.ID, &slot.Sizes); err != nil {
+				return err
+			}
+			slots = append(slots, slot)
+			return nil
+		})
+	})
+	return slots, err
+}
+
+func (s *BoltStorage) UpdateSlot(ctx context.Context, slot storage.Slot) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := updateEntityTx(tx, bucketSlots, slot.ID, slot.Description); err != nil {
+			return err
+		}
+		return putAttrs(tx, bucketSlotSizes, slot.ID, slot.Sizes, len(slot.Sizes) == 0)
+	})
+}
+
+// DeleteSlot удаляет слот, если в его ротации нет баннеров
+func (s *BoltStorage) DeleteSlot(ctx context.Context, id int) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		err := deleteEntityTx(tx, bucketSlots, id, func(tx *bolt.Tx) bool {
+			prefix := encodeKey(id)
+			k, _ := tx.Bucket(bucketBannerSlots).Cursor().Seek(prefix)
+			return k != nil && bytes.HasPrefix(k, prefix)
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(bucketSlotSizes).Delete(encodeKey(id))
+	})
+}
+
+func (s *BoltStorage) CreateGroup(ctx context.Context, group storage.Group) (storage.Group, error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		id, err := createEntityTx(tx, bucketGroups, group.ID, group.Description)
+		if err != nil {
+			return err
+		}
+		group.ID = id
+		return putAttrs(tx, bucketGroupRules, id, group.Rule, group.Rule == nil)
+	})
+	if err != nil {
+		return storage.Group{}, fmt.Errorf("failed to create group: %w", err)
+	}
+	return group, nil
+}
+
+func (s *BoltStorage) GetGroup(ctx context.Context, id int) (storage.Group, error) {
+	group := storage.Group{ID: id}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		description, err := getEntityTx(tx, bucketGroups, id)
+		if err != nil {
+			return err
+		}
+		group.Description = description
+		return getAttrs(tx, bucketGroupRules, id, &group.Rule)
+	})
+	if err != nil {
+		return storage.Group{}, err
+	}
+	return group, nil
+}
+
+func (s *BoltStorage) ListGroups(ctx context.Context) ([]storage.Group, error) {
+	var groups []storage.Group
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketGroups).ForEach(func(k, v []byte) error {
+			group := storage.Group{ID: decodeKey(k)[0], Description: string(v)}
+			if err := getAttrs(tx, bucketGroupRules, group.ID, &group.Rule); err != nil {
+				return err
+			}
+			groups = append(groups, group)
+			return nil
+		})
+	})
+	return groups, err
+}
+
+func (s *BoltStorage) UpdateGroup(ctx context.Context, group storage.Group) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := updateEntityTx(tx, bucketGroups, group.ID, group.Description); err != nil {
+			return err
+		}
+		return putAttrs(tx, bucketGroupRules, group.ID, group.Rule, group.Rule == nil)
+	})
+}
+
+// DeleteGroup удаляет группу, если по ней нет статистики
+func (s *BoltStorage) DeleteGroup(ctx context.Context, id int) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		err := deleteEntityTx(tx, bucketGroups, id, func(tx *bolt.Tx) bool {
+			found := false
+			_ = tx.Bucket(bucketStatistics).ForEach(func(k, _ []byte) error {
+				if decodeKey(k)[2] == id {
+					found = true
+				}
+				return nil
+			})
+			return found
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(bucketGroupRules).Delete(encodeKey(id))
+	})
+}
+
+// entityName возвращает имя сущности для сообщений об ошибках
+func entityName(bucket []byte) string {
+	return string(bytes.TrimSuffix(bucket, []byte("s")))
+}
+
+// createEntity сохраняет запись справочника. Явный ID сдвигает
+// последовательность бакета, как setval для SERIAL в PostgreSQL.
+func (s *BoltStorage) createEntity(bucket []byte, id int, description string) (int, error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		id, err = createEntityTx(tx, bucket, id, description)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", entityName(bucket), err)
+	}
+	return id, nil
+}
+
+func (s *BoltStorage) getEntity(bucket []byte, id int) (string, error) {
+	var description string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		description, err = getEntityTx(tx, bucket, id)
+		return err
+	})
+	return description, err
+}
+
+func (s *BoltStorage) listEntities(bucket []byte, fn func(id int, description string)) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(k, v []byte) error {
+			fn(decodeKey(k)[0], string(v))
+			return nil
+		})
+	})
+}
+
+func (s *BoltStorage) updateEntity(bucket []byte, id int, description string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return updateEntityTx(tx, bucket, id, description)
+	})
+}
+
+// deleteEntity удаляет запись, если inUse не сообщает о ссылках на нее
+func (s *BoltStorage) deleteEntity(bucket []byte, id int, inUse func(tx *bolt.Tx) bool) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return deleteEntityTx(tx, bucket, id, inUse)
+	})
+}
+
+func createEntityTx(tx *bolt.Tx, bucket []byte, id int, description string) (int, error) {
+	b := tx.Bucket(bucket)
+	if id == 0 {
+		seq, err := b.NextSequence()
+		if err != nil {
+			return 0, err
+		}
+		id = int(seq)
+	} else {
+		if b.Get(encodeKey(id)) != nil {
+			return 0, fmt.Errorf("%s %d already exists: %w", entityName(bucket), id, storage.ErrConflict)
+		}
+		if uint64(id) > b.Sequence() {
+			if err := b.SetSequence(uint64(id)); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return id, b.Put(encodeKey(id), []byte(description))
+}
+
+func getEntityTx(tx *bolt.Tx, bucket []byte, id int) (string, error) {
+	v := tx.Bucket(bucket).Get(encodeKey(id))
+	if v == nil {
+		return "", fmt.Errorf("%s %d: %w", entityName(bucket), id, storage.ErrNotFound)
+	}
+	return string(v), nil
+}
+
+// someEntitiesTx вызывает fn по порядку ID для тех из ids, что есть в справочнике
+func someEntitiesTx(tx *bolt.Tx, bucket []byte, ids []int, fn func(id int, description string) error) error {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	b := tx.Bucket(bucket)
+	for _, id := range ids {
+		v := b.Get(encodeKey(id))
+		if v == nil {
+			continue
+		}
+		if err := fn(id, string(v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func updateEntityTx(tx *bolt.Tx, bucket []byte, id int, description string) error {
+	b := tx.Bucket(bucket)
+	if b.Get(encodeKey(id)) == nil {
+		return fmt.Errorf("%s %d: %w", entityName(bucket), id, storage.ErrNotFound)
+	}
+	return b.Put(encodeKey(id), []byte(description))
+}
+
+func deleteEntityTx(tx *bolt.Tx, bucket []byte, id int, inUse func(tx *bolt.Tx) bool) error {
+	b := tx.Bucket(bucket)
+	if b.Get(encodeKey(id)) == nil {
+		return fmt.Errorf("%s %d: %w", entityName(bucket), id, storage.ErrNotFound)
+	}
+	if inUse(tx) {
+		return fmt.Errorf("%s %d is in use: %w", entityName(bucket), id, storage.ErrConflict)
+	}
+	return b.Delete(encodeKey(id))
+}
+
+// putAttrs сохраняет дополнительные атрибуты записи справочника в JSON
+// в отдельном бакете; пустые атрибуты (empty) не хранятся
+func putAttrs(tx *bolt.Tx, bucket []byte, id int, attrs any, empty bool) error {
+	b := tx.Bucket(bucket)
+	if empty {
+		return b.Delete(encodeKey(id))
+	}
+	data, err := json.Marshal(attrs)
+	if err != nil {
+		return err
+	}
+	return b.Put(encodeKey(id), data)
+}
+
+// getAttrs читает атрибуты записи в attrs; без сохраненных атрибутов attrs не меняется
+func getAttrs(tx *bolt.Tx, bucket []byte, id int, attrs any) error {
+	data := tx.Bucket(bucket).Get(encodeKey(id))
+	if data == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, attrs); err != nil {
+		return fmt.Errorf("failed to decode %s of %d: %w", bucket, id, err)
+	}
+	return nil
+}
@@ -0,0 +1,448 @@
+package buffered
+
+import (
+	"banner-rotation/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// flushTimeout ограничивает финальный сброс буфера при закрытии
+const flushTimeout = 10 * time.Second
+
+// maxPending ограничивает число ключей в буфере. Приращения по новым
+// ключам сверх лимита отбрасываются до следующего успешного сброса.
+const maxPending = 100_000
+
+// deltaKey - ключ агрегации приращений
+type deltaKey struct {
+	SlotID   int
+	BannerID int
+	GroupID  int
+	Hour     int64 // часовая корзина, unix-время
+}
+
+// counters - накопленные приращения для одного ключа
+type counters struct {
+	Shows  int
+	Clicks int
+}
+
+// Stats - состояние буфера отложенной записи
+type Stats struct {
+	// PendingKeys - число комбинаций слот+баннер+группа в буфере
+	PendingKeys int
+	// PendingShows и PendingClicks - накопленные, но не записанные приращения
+	PendingShows  int
+	PendingClicks int
+	// Lag - возраст самого старого не записанного приращения
+	Lag time.Duration
+	// LastFlush - время последнего успешного сброса
+	LastFlush time.Time
+	// LastError - ошибка последнего сброса, nil если он прошел успешно
+	LastError error
+	// Dropped - число отброшенных приращений: отклоненных хранилищем
+	// как недопустимые или не поместившихся в буфер
+	Dropped int
+}
+
+// BufferedStorage - обертка над хранилищем с отложенной записью:
+// показы и клики агрегируются в памяти и периодически сбрасываются пачкой.
+// Остальные методы делегируются напрямую.
+type BufferedStorage struct {
+	storage.Storage
+
+	mu        sync.Mutex
+	pending   map[deltaKey]counters
+	oldest    time.Time
+	lastFlush time.Time
+	lastErr   error
+	dropped   int
+	// full - буфер достиг maxPending, сбрасывается при следующем сбросе
+	full bool
+
+	// flushMu сериализует сбросы, чтобы приращения не записались дважды
+	flushMu sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+var _ storage.Storage = (*BufferedStorage)(nil)
+
+// New оборачивает хранилище и запускает периодический сброс с интервалом interval.
+// Если хранилище реализует storage.BatchRecorder, сброс идет одной пачкой.
+func New(store storage.Storage, interval time.Duration) *BufferedStorage {
+	s := &BufferedStorage{
+		Storage: store,
+		pending: make(map[deltaKey]counters),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go s.run(interval)
+	return s
+}
+
+func (s *BufferedStorage) run(interval time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Flush(context.Background()); err != nil {
+				log.Printf("Failed to flush buffered stats: %v", err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *BufferedStorage) RecordShow(ctx context.Context, slotID, bannerID, groupID int) error {
+	s.add(slotID, bannerID, groupID, counters{Shows: 1})
+	return nil
+}
+
+func (s *BufferedStorage) RecordClick(ctx context.Context, slotID, bannerID, groupID int) error {
+	s.add(slotID, bannerID, groupID, counters{Clicks: 1})
+	return nil
+}
+
+func (s *BufferedStorage) add(slotID, bannerID, groupID int, delta counters) {
+	now := time.Now()
+	key := deltaKey{
+		SlotID:   slotID,
+		BannerID: bannerID,
+		GroupID:  groupID,
+		Hour:     storage.HourBucket(now).Unix(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.pending) == 0 {
+		s.oldest = now
+	}
+	s.merge(key, delta)
+}
+
+// merge прибавляет приращение к буферу, соблюдая maxPending.
+// Вызывается под s.mu.
+func (s *BufferedStorage) merge(key deltaKey, delta counters) {
+	c, ok := s.pending[key]
+	if !ok && len(s.pending) >= maxPending {
+		if !s.full {
+			s.full = true
+			log.Printf("Stats buffer is full (%d keys), dropping new deltas until next flush", maxPending)
+		}
+		s.dropped += delta.Shows + delta.Clicks
+		return
+	}
+	c.Shows += delta.Shows
+	c.Clicks += delta.Clicks
+	s.pending[key] = c
+}
+
+// GetBannerStats дополняет статистику хранилища еще не записанными приращениями
+func (s *BufferedStorage) GetBannerStats(ctx context.Context, slotID, groupID int) ([]storage.BannerStat, error) {
+	stats, err := s.Storage.GetBannerStats(ctx, slotID, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return mergePending(stats, s.pending, func(key deltaKey) bool {
+		return key.SlotID == slotID && key.GroupID == groupID
+	}), nil
+}
+
+// GetBannerStatsRange дополняет статистику за период еще не записанными приращениями
+func (s *BufferedStorage) GetBannerStatsRange(ctx context.Context, slotID, groupID int, from, to time.Time) ([]storage.BannerStat, error) {
+	stats, err := s.Storage.GetBannerStatsRange(ctx, slotID, groupID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fromUnix, toUnix := from.Unix(), to.Unix()
+	return mergePending(stats, s.pending, func(key deltaKey) bool {
+		return key.SlotID == slotID && key.GroupID == groupID && key.Hour >= fromUnix && key.Hour < toUnix
+	}), nil
+}
+
+// mergePending прибавляет к stats приращения из pending, отобранные match
+func mergePending(stats []storage.BannerStat, pending map[deltaKey]counters, match func(deltaKey) bool) []storage.BannerStat {
+	index := make(map[int]int, len(stats))
+	for i, stat := range stats {
+		index[stat.BannerID] = i
+	}
+	for key, c := range pending {
+		if !match(key) {
+			continue
+		}
+		if i, ok := index[key.BannerID]; ok {
+			stats[i].Shows += c.Shows
+			stats[i].Clicks += c.Clicks
+			continue
+		}
+		index[key.BannerID] = len(stats)
+		stats = append(stats, storage.BannerStat{BannerID: key.BannerID, Shows: c.Shows, Clicks: c.Clicks})
+	}
+	return stats
+}
+
+// RemoveBannerFromSlot удаляет баннер и отбрасывает его накопленные приращения
+func (s *BufferedStorage) RemoveBannerFromSlot(ctx context.Context, slotID, bannerID int) error {
+	if err := s.Storage.RemoveBannerFromSlot(ctx, slotID, bannerID); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.pending {
+		if key.SlotID == slotID && key.BannerID == bannerID {
+			delete(s.pending, key)
+		}
+	}
+	return nil
+}
+
+// UpdateSlotRotation применяет изменение и отбрасывает накопленные
+// приращения баннеров, удаленных из ротации
+func (s *BufferedStorage) UpdateSlotRotation(ctx context.Context, slotID int, change storage.RotationChange) error {
+	if err := s.Storage.UpdateSlotRotation(ctx, slotID, change); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.pending {
+		if key.SlotID != slotID {
+			continue
+		}
+		removed := slices.Contains(change.Remove, key.BannerID)
+		if change.Replace {
+			removed = !slices.Contains(change.Add, key.BannerID)
+		}
+		if removed {
+			delete(s.pending, key)
+		}
+	}
+	return nil
+}
+
+// Flush записывает накопленные приращения в хранилище.
+// Приращения, отклоненные как storage.ErrNotFound или storage.ErrInvalid,
+// отбрасываются с записью в лог: повтор для них не поможет.
+// При остальных ошибках приращения возвращаются в буфер для следующей попытки.
+func (s *BufferedStorage) Flush(ctx context.Context) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	batch := s.pending
+	oldest := s.oldest
+	s.pending = make(map[deltaKey]counters)
+	s.full = false
+	s.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	deltas := make([]storage.StatDelta, 0, len(batch))
+	for key, c := range batch {
+		deltas = append(deltas, storage.StatDelta{
+			SlotID:   key.SlotID,
+			BannerID: key.BannerID,
+			GroupID:  key.GroupID,
+			Hour:     time.Unix(key.Hour, 0).UTC(),
+			Shows:    c.Shows,
+			Clicks:   c.Clicks,
+		})
+	}
+	// Фиксированный порядок снижает вероятность взаимоблокировок в БД
+	sort.Slice(deltas, func(i, j int) bool {
+		a, b := deltas[i], deltas[j]
+		if a.SlotID != b.SlotID {
+			return a.SlotID < b.SlotID
+		}
+		if a.BannerID != b.BannerID {
+			return a.BannerID < b.BannerID
+		}
+		if a.GroupID != b.GroupID {
+			return a.GroupID < b.GroupID
+		}
+		return a.Hour.Before(b.Hour)
+	})
+
+	failed, rejected, err := s.write(ctx, deltas)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dropped += rejected
+	s.lastErr = err
+	if err != nil {
+		if len(s.pending) == 0 || oldest.Before(s.oldest) {
+			s.oldest = oldest
+		}
+		for _, d := range failed {
+			key := deltaKey{SlotID: d.SlotID, BannerID: d.BannerID, GroupID: d.GroupID, Hour: d.Hour.Unix()}
+			s.merge(key, counters{Shows: d.Shows, Clicks: d.Clicks})
+		}
+		return fmt.Errorf("failed to flush %d of %d stat deltas: %w", len(failed), len(deltas), err)
+	}
+
+	s.lastFlush = time.Now()
+	return nil
+}
+
+// write записывает пачку через BatchRecorder. Если пачка отклонена как
+// недопустимая, незаписанные приращения пишутся по одному, чтобы одна
+// ошибочная запись не блокировала остальные. При прочих ошибках, например
+// недоступности хранилища, незаписанный остаток целиком возвращается для
+// повторной записи. Возвращает приращения для повторной записи и число
+// отброшенных как недопустимые.
+func (s *BufferedStorage) write(ctx context.Context, deltas []storage.StatDelta) ([]storage.StatDelta, int, error) {
+	recorder, ok := s.Storage.(storage.BatchRecorder)
+	if ok {
+		err := recorder.RecordBatch(ctx, deltas)
+		if err == nil {
+			return nil, 0, nil
+		}
+		deltas = unapplied(deltas, storage.AppliedDeltas(err))
+		if !isRejected(err) {
+			return deltas, 0, err
+		}
+	}
+
+	var (
+		rejected  int
+		rejectErr error
+	)
+	for i, d := range deltas {
+		var err error
+		if ok {
+			err = recorder.RecordBatch(ctx, []storage.StatDelta{d})
+		} else {
+			err = s.writeOne(ctx, &d)
+		}
+		switch {
+		case err == nil:
+		case isRejected(err):
+			rejected += d.Shows + d.Clicks
+			rejectErr = err
+		default:
+			failed := append([]storage.StatDelta{d}, deltas[i+1:]...)
+			logRejected(rejected, rejectErr)
+			return failed, rejected, err
+		}
+	}
+	logRejected(rejected, rejectErr)
+	return nil, rejected, nil
+}
+
+// isRejected сообщает, что хранилище отклонило запись как недопустимую:
+// повтор такой записи не поможет
+func isRejected(err error) bool {
+	return errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalid)
+}
+
+// logRejected пишет в лог число отброшенных приращений
+func logRejected(rejected int, err error) {
+	if rejected > 0 {
+		log.Printf("Dropped %d stat increments rejected by storage, last error: %v", rejected, err)
+	}
+}
+
+// unapplied возвращает приращения пачки, не записанные RecordBatch
+func unapplied(deltas []storage.StatDelta, applied map[int]bool) []storage.StatDelta {
+	if len(applied) == 0 {
+		return deltas
+	}
+
+	rest := make([]storage.StatDelta, 0, len(deltas)-len(applied))
+	for i, d := range deltas {
+		if !applied[i] {
+			rest = append(rest, d)
+		}
+	}
+	return rest
+}
+
+// writeOne записывает приращение поштучными инкрементами для хранилищ
+// без пакетной записи. Такие хранилища пишут в текущую часовую корзину,
+// а не в d.Hour. Записанная часть вычитается из d.
+func (s *BufferedStorage) writeOne(ctx context.Context, d *storage.StatDelta) error {
+	for ; d.Shows > 0; d.Shows-- {
+		if err := s.Storage.RecordShow(ctx, d.SlotID, d.BannerID, d.GroupID); err != nil {
+			return err
+		}
+	}
+	for ; d.Clicks > 0; d.Clicks-- {
+		if err := s.Storage.RecordClick(ctx, d.SlotID, d.BannerID, d.GroupID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stats возвращает текущее отставание буфера
+func (s *BufferedStorage) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := Stats{
+		PendingKeys: len(s.pending),
+		LastFlush:   s.lastFlush,
+		LastError:   s.lastErr,
+		Dropped:     s.dropped,
+	}
+	for _, c := range s.pending {
+		st.PendingShows += c.Shows
+		st.PendingClicks += c.Clicks
+	}
+	if len(s.pending) > 0 {
+		st.Lag = time.Since(s.oldest)
+	}
+	return st
+}
+
+// Ping проверяет обернутое хранилище, если оно это поддерживает
+func (s *BufferedStorage) Ping(ctx context.Context) error {
+	if pinger, ok := s.Storage.(storage.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// Close останавливает периодический сброс, записывает остаток буфера
+// и закрывает обернутое хранилище
+func (s *BufferedStorage) Close() error {
+	close(s.stop)
+	<-s.done
+
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+
+	flushErr := s.Flush(ctx)
+	if err := s.Storage.Close(); err != nil {
+		return err
+	}
+	return flushErr
+}
@@ -0,0 +1,241 @@
+package buffered
+
+import (
+	"banner-rotation/internal/storage"
+	"banner-rotation/internal/storage/memory"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBackend создает хранилище в памяти со слотом 1 и баннерами 1..banners
+func newBackend(t *testing.T, banners int) *memory.MemoryStorage {
+	t.Helper()
+	ctx := context.Background()
+	backend := memory.New()
+	_, err := backend.CreateSlot(ctx, storage.Slot{ID: 1})
+	require.NoError(t, err)
+	for id := 1; id <= banners; id++ {
+		_, err := backend.CreateBanner(ctx, storage.Banner{ID: id})
+		require.NoError(t, err)
+	}
+	return backend
+}
+
+func TestBufferedStorage(t *testing.T) {
+	ctx := context.Background()
+	backend := newBackend(t, 2)
+	require.NoError(t, backend.AddBannerToSlot(ctx, 1, 1))
+	require.NoError(t, backend.AddBannerToSlot(ctx, 1, 2))
+
+	store := New(backend, time.Hour)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, store.RecordShow(ctx, 1, 1, 1))
+	}
+	require.NoError(t, store.RecordClick(ctx, 1, 1, 1))
+	require.NoError(t, store.RecordShow(ctx, 1, 2, 1))
+
+	t.Run("increments are buffered", func(t *testing.T) {
+		stats, err := backend.GetBannerStats(ctx, 1, 1)
+		require.NoError(t, err)
+		assert.Empty(t, stats)
+
+		st := store.Stats()
+		assert.Equal(t, 2, st.PendingKeys)
+		assert.Equal(t, 11, st.PendingShows)
+		assert.Equal(t, 1, st.PendingClicks)
+		assert.Greater(t, st.Lag, time.Duration(0))
+	})
+
+	t.Run("GetBannerStats includes pending", func(t *testing.T) {
+		stats, err := store.GetBannerStats(ctx, 1, 1)
+		require.NoError(t, err)
+		assert.Len(t, stats, 2)
+	})
+
+	t.Run("Flush writes aggregated deltas", func(t *testing.T) {
+		require.NoError(t, store.Flush(ctx))
+
+		stats, err := backend.GetBannerStats(ctx, 1, 1)
+		require.NoError(t, err)
+		require.Len(t, stats, 2)
+		for _, stat := range stats {
+			if stat.BannerID == 1 {
+				assert.Equal(t, 10, stat.Shows)
+				assert.Equal(t, 1, stat.Clicks)
+			}
+		}
+
+		st := store.Stats()
+		assert.Zero(t, st.PendingKeys)
+		assert.Zero(t, st.Lag)
+		assert.NoError(t, st.LastError)
+	})
+
+	t.Run("rejected deltas are dropped", func(t *testing.T) {
+		require.NoError(t, store.RecordShow(ctx, 1, 3, 1))
+		require.NoError(t, store.RecordShow(ctx, 1, 2, 1))
+		require.NoError(t, store.Flush(ctx))
+
+		st := store.Stats()
+		assert.Zero(t, st.PendingKeys)
+		assert.Equal(t, 1, st.Dropped)
+
+		// Корректное приращение записано несмотря на ошибку соседнего
+		stats, err := backend.GetBannerStats(ctx, 1, 1)
+		require.NoError(t, err)
+		for _, stat := range stats {
+			if stat.BannerID == 2 {
+				assert.Equal(t, 2, stat.Shows)
+			}
+		}
+	})
+}
+
+// unavailableStorage отклоняет запись показов временной ошибкой
+type unavailableStorage struct {
+	storage.Storage
+	down bool
+}
+
+func (s *unavailableStorage) RecordShow(ctx context.Context, slotID, bannerID, groupID int) error {
+	if s.down {
+		return storage.ErrUnavailable
+	}
+	return s.Storage.RecordShow(ctx, slotID, bannerID, groupID)
+}
+
+func TestBufferedStorage_TransientError(t *testing.T) {
+	ctx := context.Background()
+	backend := &unavailableStorage{Storage: newBackend(t, 1), down: true}
+	require.NoError(t, backend.AddBannerToSlot(ctx, 1, 1))
+
+	store := New(backend, time.Hour)
+	defer store.Close()
+
+	require.NoError(t, store.RecordShow(ctx, 1, 1, 1))
+	require.ErrorIs(t, store.Flush(ctx), storage.ErrUnavailable)
+
+	st := store.Stats()
+	assert.Equal(t, 1, st.PendingShows)
+	assert.Zero(t, st.Dropped)
+	assert.Error(t, st.LastError)
+
+	backend.down = false
+	require.NoError(t, store.Flush(ctx))
+
+	stats, err := backend.GetBannerStats(ctx, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 1, stats[0].Shows)
+
+	// Удаление баннера отбрасывает его приращения
+	require.NoError(t, store.RecordShow(ctx, 1, 1, 1))
+	require.NoError(t, store.RemoveBannerFromSlot(ctx, 1, 1))
+	assert.Zero(t, store.Stats().PendingKeys)
+}
+
+// unavailableRecorder отклоняет пакетную запись временной ошибкой и считает вызовы
+type unavailableRecorder struct {
+	storage.Storage
+	calls int
+}
+
+func (s *unavailableRecorder) RecordBatch(ctx context.Context, deltas []storage.StatDelta) error {
+	s.calls++
+	return storage.ErrUnavailable
+}
+
+func TestBufferedStorage_TransientBatchError(t *testing.T) {
+	ctx := context.Background()
+	backend := &unavailableRecorder{Storage: newBackend(t, 3)}
+
+	store := New(backend, time.Hour)
+	defer store.Close()
+
+	for bannerID := 1; bannerID <= 3; bannerID++ {
+		require.NoError(t, store.RecordShow(ctx, 1, bannerID, 1))
+	}
+	require.ErrorIs(t, store.Flush(ctx), storage.ErrUnavailable)
+
+	assert.Equal(t, 1, backend.calls, "batch is not split on transient error")
+	st := store.Stats()
+	assert.Equal(t, 3, st.PendingShows)
+	assert.Zero(t, st.Dropped)
+}
+
+func TestBufferedStorage_MaxPending(t *testing.T) {
+	ctx := context.Background()
+	store := New(memory.New(), time.Hour)
+	defer store.Close()
+
+	for bannerID := 1; bannerID <= maxPending+10; bannerID++ {
+		require.NoError(t, store.RecordShow(ctx, 1, bannerID, 1))
+	}
+	// Приращение по уже накопленному ключу принимается
+	require.NoError(t, store.RecordShow(ctx, 1, 1, 1))
+
+	st := store.Stats()
+	assert.Equal(t, maxPending, st.PendingKeys)
+	assert.Equal(t, maxPending+1, st.PendingShows)
+	assert.Equal(t, 10, st.Dropped)
+}
+
+func TestBufferedStorage_FlushOnClose(t *testing.T) {
+	ctx := context.Background()
+	backend := newBackend(t, 1)
+	require.NoError(t, backend.AddBannerToSlot(ctx, 1, 1))
+
+	store := New(backend, time.Hour)
+	require.NoError(t, store.RecordShow(ctx, 1, 1, 1))
+	require.NoError(t, store.Close())
+
+	stats, err := backend.GetBannerStats(ctx, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 1, stats[0].Shows)
+}
+
+func TestBufferedStorage_PeriodicFlush(t *testing.T) {
+	ctx := context.Background()
+	backend := newBackend(t, 1)
+	require.NoError(t, backend.AddBannerToSlot(ctx, 1, 1))
+
+	store := New(backend, 10*time.Millisecond)
+	defer store.Close()
+
+	require.NoError(t, store.RecordShow(ctx, 1, 1, 1))
+
+	assert.Eventually(t, func() bool {
+		stats, err := backend.GetBannerStats(ctx, 1, 1)
+		return err == nil && len(stats) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestBufferedStorage_UpdateSlotRotation(t *testing.T) {
+	ctx := context.Background()
+	backend := newBackend(t, 3)
+	store := New(backend, time.Hour)
+	defer store.Close()
+
+	require.NoError(t, store.UpdateSlotRotation(ctx, 1, storage.RotationChange{Add: []int{1, 2, 3}}))
+	for _, bannerID := range []int{1, 2, 3} {
+		require.NoError(t, store.RecordShow(ctx, 1, bannerID, 1))
+	}
+
+	require.NoError(t, store.UpdateSlotRotation(ctx, 1, storage.RotationChange{Remove: []int{1}}))
+	assert.Equal(t, 2, store.Stats().PendingKeys)
+
+	require.NoError(t, store.UpdateSlotRotation(ctx, 1, storage.RotationChange{Add: []int{3}, Replace: true}))
+	assert.Equal(t, 1, store.Stats().PendingKeys)
+
+	require.NoError(t, store.Flush(ctx))
+	stats, err := backend.GetBannerStats(ctx, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 3, stats[0].BannerID)
+}
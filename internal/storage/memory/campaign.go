@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"banner-rotation/internal/storage"
+	"context"
+	"fmt"
+)
+
+func (s *MemoryStorage) CreateAdvertiser(ctx context.Context, advertiser storage.Advertiser) (storage.Advertiser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, err := s.advertisers.create(advertiser.ID, advertiser.Name)
+	if err != nil {
+		return storage.Advertiser{}, err
+	}
+	advertiser.ID = id
+	return advertiser, nil
+}
+
+func (s *MemoryStorage) GetAdvertiser(ctx context.Context, id int) (storage.Advertiser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	name, err := s.advertisers.get(id)
+	if err != nil {
+		return storage.Advertiser{}, err
+	}
+	return storage.Advertiser{ID: id, Name: name}, nil
+}
+
+func (s *MemoryStorage) ListAdvertisers(ctx context.Context) ([]storage.Advertiser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	advertisers := make([]storage.Advertiser, 0, len(s.advertisers.items))
+	for _, id := range s.advertisers.ids() {
+		advertisers = append(advertisers, storage.Advertiser{ID: id, Name: s.advertisers.items[id]})
+	}
+	return advertisers, nil
+}
+
+func (s *MemoryStorage) UpdateAdvertiser(ctx context.Context, advertiser storage.Advertiser) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.advertisers.update(advertiser.ID, advertiser.Name)
+}
+
+func (s *MemoryStorage) DeleteAdvertiser(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.advertisers.get(id); err != nil {
+		return err
+	}
+	for campaignID, campaign := range s.campaigns.items {
+		if campaign.AdvertiserID == id {
+			return fmt.Errorf("advertiser %d owns campaign %d: %w", id, campaignID, storage.ErrConflict)
+		}
+	}
+	delete(s.advertisers.items, id)
+	return nil
+}
+
+func (s *MemoryStorage) CreateCampaign(ctx context.Context, campaign storage.Campaign) (storage.Campaign, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.advertisers.get(campaign.AdvertiserID); err != nil {
+		return storage.Campaign{}, err
+	}
+	id, err := s.campaigns.create(campaign.ID, campaign)
+	if err != nil {
+		return storage.Campaign{}, err
+	}
+	campaign.ID = id
+	s.campaigns.items[id] = campaign
+	return campaign, nil
+}
+
+func (s *MemoryStorage) GetCampaign(ctx context.Context, id int) (storage.Campaign, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.campaigns.get(id)
+}
+
+func (s *MemoryStorage) GetCampaigns(ctx context.Context, ids []int) ([]storage.Campaign, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	campaigns := make([]storage.Campaign, 0, len(ids))
+	for _, id := range s.campaigns.some(ids) {
+		campaigns = append(campaigns, s.campaigns.items[id])
+	}
+	return campaigns, nil
+}
+
+func (s *MemoryStorage) ListCampaigns(ctx context.Context) ([]storage.Campaign, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	campaigns := make([]storage.Campaign, 0, len(s.campaigns.items))
+	for _, id := range s.campaigns.ids() {
+		campaigns = append(campaigns, s.campaigns.items[id])
+	}
+	return campaigns, nil
+}
+
+func (s *MemoryStorage) UpdateCampaign(ctx context.Context, campaign storage.Campaign) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.campaigns.get(campaign.ID); err != nil {
+		return err
+	}
+	if _, err := s.advertisers.get(campaign.AdvertiserID); err != nil {
+		return err
+	}
+	return s.campaigns.update(campaign.ID, campaign)
+}
+
+func (s *MemoryStorage) DeleteCampaign(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.campaigns.get(id); err != nil {
+		return err
+	}
+	for bannerID, campaignID := range s.bannerCampaigns {
+		if campaignID == id {
+			return fmt.Errorf("campaign %d has banner %d: %w", id, bannerID, storage.ErrConflict)
+		}
+	}
+	delete(s.campaigns.items, id)
+	return nil
+}
+
+// checkCampaign проверяет, что кампания баннера заведена; 0 - баннер вне кампании
+func (s *MemoryStorage) checkCampaign(campaignID int) error {
+	if campaignID == 0 {
+		return nil
+	}
+	_, err := s.campaigns.get(campaignID)
+	return err
+}
+
+func (s *MemoryStorage) setBannerCampaign(bannerID, campaignID int) {
+	if campaignID == 0 {
+		delete(s.bannerCampaigns, bannerID)
+		return
+	}
+	s.bannerCampaigns[bannerID] = campaignID
+}
@@ -0,0 +1,332 @@
+package memory
+
+import (
+	"banner-rotation/internal/storage"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// catalog - справочник вида id -> запись с автоинкрементом
+type catalog[T any] struct {
+	name   string
+	items  map[int]T
+	lastID int
+}
+
+func newCatalog[T any](name string) *catalog[T] {
+	return &catalog[T]{name: name, items: make(map[int]T)}
+}
+
+func (c *catalog[T]) create(id int, item T) (int, error) {
+	if id == 0 {
+		c.lastID++
+		id = c.lastID
+	} else if _, ok := c.items[id]; ok {
+		return 0, fmt.Errorf("%s %d already exists: %w", c.name, id, storage.ErrConflict)
+	}
+	if id > c.lastID {
+		c.lastID = id
+	}
+	c.items[id] = item
+	return id, nil
+}
+
+func (c *catalog[T]) get(id int) (T, error) {
+	item, ok := c.items[id]
+	if !ok {
+		return item, fmt.Errorf("%s %d: %w", c.name, id, storage.ErrNotFound)
+	}
+	return item, nil
+}
+
+func (c *catalog[T]) update(id int, item T) error {
+	if _, ok := c.items[id]; !ok {
+		return fmt.Errorf("%s %d: %w", c.name, id, storage.ErrNotFound)
+	}
+	c.items[id] = item
+	return nil
+}
+
+// some возвращает по порядку те из ids, что есть в справочнике, без повторов
+func (c *catalog[T]) some(ids []int) []int {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	return slices.DeleteFunc(slices.Compact(ids), func(id int) bool {
+		_, ok := c.items[id]
+		return !ok
+	})
+}
+
+func (c *catalog[T]) ids() []int {
+	ids := make([]int, 0, len(c.items))
+	for id := range c.items {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func (s *MemoryStorage) CreateBanner(ctx context.Context, banner storage.Banner) (storage.Banner, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkCampaign(banner.CampaignID); err != nil {
+		return storage.Banner{}, err
+	}
+	id, err := s.banners.create(banner.ID, banner.Description)
+	if err != nil {
+		return storage.Banner{}, err
+	}
+	banner.ID = id
+	s.setCreative(id, banner.Creative)
+	s.setBannerCampaign(id, banner.CampaignID)
+	return banner, nil
+}
+
+func (s *MemoryStorage) GetBanner(ctx context.Context, id int) (storage.Banner, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, err := s.banners.get(id); err != nil {
+		return storage.Banner{}, err
+	}
+	return s.banner(id), nil
+}
+
+func (s *MemoryStorage) GetBanners(ctx context.Context, ids []int) ([]storage.Banner, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	banners := make([]storage.Banner, 0, len(ids))
+	for _, id := range s.banners.some(ids) {
+		banners = append(banners, s.banner(id))
+	}
+	return banners, nil
+}
+
+func (s *MemoryStorage) ListBanners(ctx context.Context) ([]storage.Banner, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	banners := make([]storage.Banner, 0, len(s.banners.items))
+	for _, id := range s.banners.ids() {
+		banners = append(banners, s.banner(id))
+	}
+	return banners, nil
+}
+
+// banner собирает баннер из справочника и атрибутов, вызывается под s.mu
+func (s *MemoryStorage) banner(id int) storage.Banner {
+	return storage.Banner{
+		ID:          id,
+		Description: s.banners.items[id],
+		CampaignID:  s.bannerCampaigns[id],
+		Creative:    s.creatives[id],
+	}
+}
+
+func (s *MemoryStorage) UpdateBanner(ctx context.Context, banner storage.Banner) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkCampaign(banner.CampaignID); err != nil {
+		return err
+	}
+	if err := s.banners.update(banner.ID, banner.Description); err != nil {
+		return err
+	}
+	s.setCreative(banner.ID, banner.Creative)
+	s.setBannerCampaign(banner.ID, banner.CampaignID)
+	return nil
+}
+
+func (s *MemoryStorage) DeleteBanner(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.banners.get(id); err != nil {
+		return err
+	}
+	for slotID, banners := range s.bannerSlots {
+		if _, ok := banners[id]; ok {
+			return fmt.Errorf("banner %d is in rotation of slot %d: %w", id, slotID, storage.ErrConflict)
+		}
+	}
+	delete(s.banners.items, id)
+	delete(s.creatives, id)
+	delete(s.bannerCampaigns, id)
+	return nil
+}
+
+// setCreative сохраняет креатив баннера; пустой креатив не хранится
+func (s *MemoryStorage) setCreative(bannerID int, creative storage.Creative) {
+	if creative == (storage.Creative{}) {
+		delete(s.creatives, bannerID)
+		return
+	}
+	s.creatives[bannerID] = creative
+}
+
+func (s *MemoryStorage) CreateSlot(ctx context.Context, slot storage.Slot) (storage.Slot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, err := s.slots.create(slot.ID, slot.Description)
+	if err != nil {
+		return storage.Slot{}, err
+	}
+	slot.ID = id
+	s.setSlotSizes(id, slot.Sizes)
+	return slot, nil
+}
+
+func (s *MemoryStorage) GetSlot(ctx context.Context, id int) (storage.Slot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	description, err := s.slots.get(id)
+	if err != nil {
+		return storage.Slot{}, err
+	}
+	return storage.Slot{ID: id, Description: description, Sizes: slices.Clone(s.slotSizes[id])}, nil
+}
+
+func (s *MemoryStorage) ListSlots(ctx context.Context) ([]storage.Slot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	slots := make([]storage.Slot, 0, len(s.slots.items))
+	for _, id := range s.slots.ids() {
+		slots = append(slots, storage.Slot{
+			ID:          id,
+			Description: s.slots.items[id],
+			Sizes:       slices.Clone(s.slotSizes[id]),
+		})
+	}
+	return slots, nil
+}
+
+func (s *MemoryStorage) UpdateSlot(ctx context.Context, slot storage.Slot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.slots.update(slot.ID, slot.Description); err != nil {
+		return err
+	}
+	s.setSlotSizes(slot.ID, slot.Sizes)
+	return nil
+}
+
+func (s *MemoryStorage) DeleteSlot(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.slots.get(id); err != nil {
+		return err
+	}
+	if len(s.bannerSlots[id]) > 0 {
+		return fmt.Errorf("slot %d has banners in rotation: %w", id, storage.ErrConflict)
+	}
+	delete(s.slots.items, id)
+	delete(s.slotSizes, id)
+	return nil
+}
+
+// setSlotSizes сохраняет копию списка размеров слота
+func (s *MemoryStorage) setSlotSizes(slotID int, sizes []storage.Size) {
+	if len(sizes) == 0 {
+		delete(s.slotSizes, slotID)
+		return
+	}
+	s.slotSizes[slotID] = slices.Clone(sizes)
+}
+
+func (s *MemoryStorage) CreateGroup(ctx context.Context, group storage.Group) (storage.Group, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, err := s.groups.create(group.ID, group.Description)
+	if err != nil {
+		return storage.Group{}, err
+	}
+	group.ID = id
+	s.setGroupRule(id, group.Rule)
+	return group, nil
+}
+
+func (s *MemoryStorage) GetGroup(ctx context.Context, id int) (storage.Group, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	description, err := s.groups.get(id)
+	if err != nil {
+		return storage.Group{}, err
+	}
+	return storage.Group{ID: id, Description: description, Rule: s.groupRule(id)}, nil
+}
+
+func (s *MemoryStorage) ListGroups(ctx context.Context) ([]storage.Group, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	groups := make([]storage.Group, 0, len(s.groups.items))
+	for _, id := range s.groups.ids() {
+		groups = append(groups, storage.Group{ID: id, Description: s.groups.items[id], Rule: s.groupRule(id)})
+	}
+	return groups, nil
+}
+
+func (s *MemoryStorage) UpdateGroup(ctx context.Context, group storage.Group) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.groups.update(group.ID, group.Description); err != nil {
+		return err
+	}
+	s.setGroupRule(group.ID, group.Rule)
+	return nil
+}
+
+func (s *MemoryStorage) DeleteGroup(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.groups.get(id); err != nil {
+		return err
+	}
+	for key := range s.stats {
+		if key.GroupID == id {
+			return fmt.Errorf("group %d has statistics: %w", id, storage.ErrConflict)
+		}
+	}
+	delete(s.groups.items, id)
+	delete(s.groupRules, id)
+	return nil
+}
+
+// setGroupRule сохраняет копию правила группы, nil удаляет правило
+func (s *MemoryStorage) setGroupRule(groupID int, rule *storage.GroupRule) {
+	if rule == nil {
+		delete(s.groupRules, groupID)
+		return
+	}
+	s.groupRules[groupID] = cloneRule(*rule)
+}
+
+// groupRule возвращает копию правила группы или nil
+func (s *MemoryStorage) groupRule(groupID int) *storage.GroupRule {
+	rule, ok := s.groupRules[groupID]
+	if !ok {
+		return nil
+	}
+	rule = cloneRule(rule)
+	return &rule
+}
+
+func cloneRule(rule storage.GroupRule) storage.GroupRule {
+	rule.Genders = slices.Clone(rule.Genders)
+	rule.Interests = slices.Clone(rule.Interests)
+	return rule
+}
@@ -0,0 +1,566 @@
+package memory
+
+import (
+	"banner-rotation/internal/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// statKey - ключ статистики для комбинации слот+баннер+группа
+type statKey struct {
+	SlotID   int
+	BannerID int
+	GroupID  int
+}
+
+// bucketKey - ключ статистики во временной корзине
+type bucketKey struct {
+	statKey
+	Bucket int64 // начало корзины, unix-время
+}
+
+// counts - счетчики во временной корзине
+type counts struct {
+	Shows  int
+	Clicks int
+}
+
+// MemoryStorage - потокобезопасное хранилище в памяти
+// с опциональным сохранением снимка на диск.
+//
+// В отличие от PostgreSQL, AddBannerToSlot и RecordShow не требуют,
+// чтобы баннер, слот и группа были заведены в справочниках.
+type MemoryStorage struct {
+	mu              sync.RWMutex
+	banners         *catalog[string]
+	creatives       map[int]storage.Creative // bannerID -> креатив
+	bannerCampaigns map[int]int              // bannerID -> campaignID
+	slots           *catalog[string]
+	slotSizes       map[int][]storage.Size // slotID -> допустимые размеры
+	groups          *catalog[string]
+	groupRules      map[int]storage.GroupRule // groupID -> правило подбора
+	advertisers     *catalog[string]
+	campaigns       *catalog[storage.Campaign]
+	bannerSlots     map[int]map[int]struct{}          // slotID -> bannerID
+	targeting       map[int]map[int]storage.Targeting // slotID -> bannerID -> ограничения
+	stats           map[statKey]storage.BannerStat
+	hourly          map[bucketKey]counts
+	daily           map[bucketKey]counts
+	snapshotPath    string
+	now             func() time.Time
+}
+
+var (
+	_ storage.Storage       = (*MemoryStorage)(nil)
+	_ storage.BatchRecorder = (*MemoryStorage)(nil)
+)
+
+// snapshot - формат снимка на диске
+type snapshot struct {
+	Banners         map[int]string                    `json:"banners,omitempty"`
+	Creatives       map[int]storage.Creative          `json:"creatives,omitempty"`
+	BannerCampaigns map[int]int                       `json:"banner_campaigns,omitempty"`
+	Slots           map[int]string                    `json:"slots,omitempty"`
+	SlotSizes       map[int][]storage.Size            `json:"slot_sizes,omitempty"`
+	Groups          map[int]string                    `json:"groups,omitempty"`
+	GroupRules      map[int]storage.GroupRule         `json:"group_rules,omitempty"`
+	Advertisers     map[int]string                    `json:"advertisers,omitempty"`
+	Campaigns       map[int]storage.Campaign          `json:"campaigns,omitempty"`
+	BannerSlots     map[int][]int                     `json:"banner_slots"`
+	Targeting       map[int]map[int]storage.Targeting `json:"targeting,omitempty"`
+	Stats           []snapshotStat                    `json:"stats"`
+	Hourly          []snapshotStat                    `json:"hourly,omitempty"`
+	Daily           []snapshotStat                    `json:"daily,omitempty"`
+}
+
+type snapshotStat struct {
+	SlotID   int   `json:"slot_id"`
+	BannerID int   `json:"banner_id"`
+	GroupID  int   `json:"group_id"`
+	Bucket   int64 `json:"bucket,omitempty"`
+	Shows    int   `json:"shows"`
+	Clicks   int   `json:"clicks"`
+}
+
+// New создает пустое хранилище в памяти без снимков
+func New() *MemoryStorage {
+	return &MemoryStorage{
+		banners:         newCatalog[string]("banner"),
+		creatives:       make(map[int]storage.Creative),
+		bannerCampaigns: make(map[int]int),
+		slots:           newCatalog[string]("slot"),
+		slotSizes:       make(map[int][]storage.Size),
+		groups:          newCatalog[string]("group"),
+		groupRules:      make(map[int]storage.GroupRule),
+		advertisers:     newCatalog[string]("advertiser"),
+		campaigns:       newCatalog[storage.Campaign]("campaign"),
+		bannerSlots:     make(map[int]map[int]struct{}),
+		targeting:       make(map[int]map[int]storage.Targeting),
+		stats:           make(map[statKey]storage.BannerStat),
+		hourly:          make(map[bucketKey]counts),
+		daily:           make(map[bucketKey]counts),
+		now:             time.Now,
+	}
+}
+
+// NewWithSnapshot создает хранилище, восстанавливая данные из снимка,
+// если файл существует. Снимок перезаписывается при вызове Snapshot и Close.
+func NewWithSnapshot(path string) (*MemoryStorage, error) {
+	s := New()
+	s.snapshotPath = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	for _, c := range []struct {
+		catalog *catalog[string]
+		items   map[int]string
+	}{{s.banners, snap.Banners}, {s.slots, snap.Slots}, {s.groups, snap.Groups}, {s.advertisers, snap.Advertisers}} {
+		for id, description := range c.items {
+			if _, err := c.catalog.create(id, description); err != nil {
+				return nil, fmt.Errorf("failed to restore snapshot: %w", err)
+			}
+		}
+	}
+	for id, campaign := range snap.Campaigns {
+		if _, err := s.campaigns.create(id, campaign); err != nil {
+			return nil, fmt.Errorf("failed to restore snapshot: %w", err)
+		}
+	}
+	for bannerID, creative := range snap.Creatives {
+		s.creatives[bannerID] = creative
+	}
+	for bannerID, campaignID := range snap.BannerCampaigns {
+		s.bannerCampaigns[bannerID] = campaignID
+	}
+	for slotID, sizes := range snap.SlotSizes {
+		s.slotSizes[slotID] = sizes
+	}
+	for groupID, rule := range snap.GroupRules {
+		s.groupRules[groupID] = rule
+	}
+	for slotID, bannerIDs := range snap.BannerSlots {
+		banners := make(map[int]struct{}, len(bannerIDs))
+		for _, bannerID := range bannerIDs {
+			banners[bannerID] = struct{}{}
+		}
+		s.bannerSlots[slotID] = banners
+	}
+	for slotID, banners := range snap.Targeting {
+		s.targeting[slotID] = banners
+	}
+	for _, st := range snap.Stats {
+		key := statKey{SlotID: st.SlotID, BannerID: st.BannerID, GroupID: st.GroupID}
+		s.stats[key] = storage.BannerStat{BannerID: st.BannerID, Shows: st.Shows, Clicks: st.Clicks}
+	}
+	for _, st := range snap.Hourly {
+		key := bucketKey{statKey{SlotID: st.SlotID, BannerID: st.BannerID, GroupID: st.GroupID}, st.Bucket}
+		s.hourly[key] = counts{Shows: st.Shows, Clicks: st.Clicks}
+	}
+	for _, st := range snap.Daily {
+		key := bucketKey{statKey{SlotID: st.SlotID, BannerID: st.BannerID, GroupID: st.GroupID}, st.Bucket}
+		s.daily[key] = counts{Shows: st.Shows, Clicks: st.Clicks}
+	}
+
+	return s, nil
+}
+
+// AddBannerToSlot добавляет баннер в ротацию, если баннер и слот заведены в справочнике
+func (s *MemoryStorage) AddBannerToSlot(ctx context.Context, slotID, bannerID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkRotation(slotID, bannerID); err != nil {
+		return err
+	}
+	banners, ok := s.bannerSlots[slotID]
+	if !ok {
+		banners = make(map[int]struct{})
+		s.bannerSlots[slotID] = banners
+	}
+	banners[bannerID] = struct{}{}
+	return nil
+}
+
+func (s *MemoryStorage) RemoveBannerFromSlot(ctx context.Context, slotID, bannerID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeBanner(slotID, bannerID)
+	return nil
+}
+
+// UpdateSlotRotation применяет изменение под одной блокировкой,
+// поэтому читатели видят ротацию либо до, либо после него
+func (s *MemoryStorage) UpdateSlotRotation(ctx context.Context, slotID int, change storage.RotationChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(change.Add) > 0 {
+		if err := s.checkRotation(slotID, change.Add...); err != nil {
+			return err
+		}
+	}
+
+	current := make([]int, 0, len(s.bannerSlots[slotID]))
+	for id := range s.bannerSlots[slotID] {
+		current = append(current, id)
+	}
+	for _, id := range change.Removes(current) {
+		s.removeBanner(slotID, id)
+	}
+
+	if len(change.Add) == 0 {
+		return nil
+	}
+	banners, ok := s.bannerSlots[slotID]
+	if !ok {
+		banners = make(map[int]struct{})
+		s.bannerSlots[slotID] = banners
+	}
+	for _, id := range change.Add {
+		banners[id] = struct{}{}
+	}
+	return nil
+}
+
+// checkRotation проверяет, что слот и баннеры заведены в справочнике
+func (s *MemoryStorage) checkRotation(slotID int, bannerIDs ...int) error {
+	if _, err := s.slots.get(slotID); err != nil {
+		return err
+	}
+	for _, bannerID := range bannerIDs {
+		if _, err := s.banners.get(bannerID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStorage) SetBannerTargeting(ctx context.Context, slotID, bannerID int, targeting storage.Targeting) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.bannerSlots[slotID][bannerID]; !ok {
+		return fmt.Errorf("banner %d is not in slot %d: %w", bannerID, slotID, storage.ErrNotFound)
+	}
+	s.setTargeting(slotID, bannerID, targeting)
+	return nil
+}
+
+func (s *MemoryStorage) GetSlotTargeting(ctx context.Context, slotID int) (map[int]storage.Targeting, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	targeting := make(map[int]storage.Targeting, len(s.targeting[slotID]))
+	for bannerID, t := range s.targeting[slotID] {
+		targeting[bannerID] = cloneTargeting(t)
+	}
+	return targeting, nil
+}
+
+// setTargeting сохраняет копию ограничений, пустые ограничения удаляются
+func (s *MemoryStorage) setTargeting(slotID, bannerID int, targeting storage.Targeting) {
+	if targeting.IsZero() {
+		delete(s.targeting[slotID], bannerID)
+		if len(s.targeting[slotID]) == 0 {
+			delete(s.targeting, slotID)
+		}
+		return
+	}
+
+	banners, ok := s.targeting[slotID]
+	if !ok {
+		banners = make(map[int]storage.Targeting)
+		s.targeting[slotID] = banners
+	}
+	banners[bannerID] = cloneTargeting(targeting)
+}
+
+func cloneTargeting(t storage.Targeting) storage.Targeting {
+	return storage.Targeting{
+		Countries: slices.Clone(t.Countries),
+		Devices:   slices.Clone(t.Devices),
+		OS:        slices.Clone(t.OS),
+	}
+}
+
+// removeBanner удаляет баннер из ротации слота, вызывается под s.mu
+func (s *MemoryStorage) removeBanner(slotID, bannerID int) {
+	if banners, ok := s.bannerSlots[slotID]; ok {
+		delete(banners, bannerID)
+		if len(banners) == 0 {
+			delete(s.bannerSlots, slotID)
+		}
+	}
+	s.setTargeting(slotID, bannerID, storage.Targeting{})
+
+	// Как и ON DELETE CASCADE в PostgreSQL, статистика удаляется вместе со связью
+	for key := range s.stats {
+		if key.SlotID == slotID && key.BannerID == bannerID {
+			delete(s.stats, key)
+		}
+	}
+	for _, buckets := range []map[bucketKey]counts{s.hourly, s.daily} {
+		for key := range buckets {
+			if key.SlotID == slotID && key.BannerID == bannerID {
+				delete(buckets, key)
+			}
+		}
+	}
+}
+
+func (s *MemoryStorage) RecordShow(ctx context.Context, slotID, bannerID, groupID int) error {
+	return s.increment(slotID, bannerID, groupID, 1, 0)
+}
+
+func (s *MemoryStorage) RecordClick(ctx context.Context, slotID, bannerID, groupID int) error {
+	return s.increment(slotID, bannerID, groupID, 0, 1)
+}
+
+// increment увеличивает счетчики, проверяя что баннер находится в слоте
+func (s *MemoryStorage) increment(slotID, bannerID, groupID, shows, clicks int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.bannerSlots[slotID][bannerID]; !ok {
+		return fmt.Errorf("banner %d is not in slot %d: %w", bannerID, slotID, storage.ErrNotFound)
+	}
+
+	s.apply(storage.StatDelta{
+		SlotID:   slotID,
+		BannerID: bannerID,
+		GroupID:  groupID,
+		Hour:     storage.HourBucket(s.now()),
+		Shows:    shows,
+		Clicks:   clicks,
+	})
+	return nil
+}
+
+// apply добавляет приращение к общим счетчикам и к часовой корзине.
+// Вызывается под s.mu.
+func (s *MemoryStorage) apply(d storage.StatDelta) {
+	key := statKey{SlotID: d.SlotID, BannerID: d.BannerID, GroupID: d.GroupID}
+	stat := s.stats[key]
+	stat.BannerID = d.BannerID
+	stat.Shows += d.Shows
+	stat.Clicks += d.Clicks
+	s.stats[key] = stat
+
+	hour := d.Hour
+	if hour.IsZero() {
+		hour = s.now()
+	}
+	hourKey := bucketKey{key, storage.HourBucket(hour).Unix()}
+	c := s.hourly[hourKey]
+	c.Shows += d.Shows
+	c.Clicks += d.Clicks
+	s.hourly[hourKey] = c
+}
+
+// RecordBatch применяет все приращения, если каждый баннер находится в своем слоте
+func (s *MemoryStorage) RecordBatch(ctx context.Context, deltas []storage.StatDelta) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range deltas {
+		if _, ok := s.bannerSlots[d.SlotID][d.BannerID]; !ok {
+			return fmt.Errorf("banner %d is not in slot %d: %w", d.BannerID, d.SlotID, storage.ErrNotFound)
+		}
+	}
+
+	for _, d := range deltas {
+		s.apply(d)
+	}
+	return nil
+}
+
+func (s *MemoryStorage) GetBannerStats(ctx context.Context, slotID, groupID int) ([]storage.BannerStat, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var stats []storage.BannerStat
+	for key, stat := range s.stats {
+		if key.SlotID == slotID && key.GroupID == groupID {
+			stats = append(stats, stat)
+		}
+	}
+	return stats, nil
+}
+
+func (s *MemoryStorage) GetBannerStatsRange(ctx context.Context, slotID, groupID int, from, to time.Time) ([]storage.BannerStat, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fromUnix, toUnix := from.Unix(), to.Unix()
+	byBanner := make(map[int]*storage.BannerStat)
+	for _, buckets := range []map[bucketKey]counts{s.hourly, s.daily} {
+		for key, c := range buckets {
+			if key.SlotID != slotID || key.GroupID != groupID || key.Bucket < fromUnix || key.Bucket >= toUnix {
+				continue
+			}
+			stat, ok := byBanner[key.BannerID]
+			if !ok {
+				stat = &storage.BannerStat{BannerID: key.BannerID}
+				byBanner[key.BannerID] = stat
+			}
+			stat.Shows += c.Shows
+			stat.Clicks += c.Clicks
+		}
+	}
+
+	stats := make([]storage.BannerStat, 0, len(byBanner))
+	for _, stat := range byBanner {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].BannerID < stats[j].BannerID })
+	return stats, nil
+}
+
+func (s *MemoryStorage) RollupHourlyStats(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	beforeUnix := before.Unix()
+	for key, c := range s.hourly {
+		if key.Bucket >= beforeUnix {
+			continue
+		}
+		dayKey := bucketKey{key.statKey, storage.DayBucket(time.Unix(key.Bucket, 0)).Unix()}
+		d := s.daily[dayKey]
+		d.Shows += c.Shows
+		d.Clicks += c.Clicks
+		s.daily[dayKey] = d
+		delete(s.hourly, key)
+	}
+	return nil
+}
+
+func (s *MemoryStorage) GetBannersForSlot(ctx context.Context, slotID int) ([]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	banners := s.bannerSlots[slotID]
+	if len(banners) == 0 {
+		return nil, nil
+	}
+
+	bannerIDs := make([]int, 0, len(banners))
+	for bannerID := range banners {
+		bannerIDs = append(bannerIDs, bannerID)
+	}
+	sort.Ints(bannerIDs)
+	return bannerIDs, nil
+}
+
+// Snapshot атомарно записывает текущее состояние в файл снимка.
+// Для хранилища без снимков ничего не делает.
+func (s *MemoryStorage) Snapshot() error {
+	if s.snapshotPath == "" {
+		return nil
+	}
+
+	s.mu.RLock()
+	snap := snapshot{
+		Banners:         maps.Clone(s.banners.items),
+		Creatives:       maps.Clone(s.creatives),
+		BannerCampaigns: maps.Clone(s.bannerCampaigns),
+		SlotSizes:       maps.Clone(s.slotSizes),
+		Slots:           maps.Clone(s.slots.items),
+		Groups:          maps.Clone(s.groups.items),
+		GroupRules:      maps.Clone(s.groupRules),
+		Advertisers:     maps.Clone(s.advertisers.items),
+		Campaigns:       maps.Clone(s.campaigns.items),
+		BannerSlots:     make(map[int][]int, len(s.bannerSlots)),
+		Targeting:       make(map[int]map[int]storage.Targeting, len(s.targeting)),
+		Stats:           make([]snapshotStat, 0, len(s.stats)),
+		Hourly:          snapshotBuckets(s.hourly),
+		Daily:           snapshotBuckets(s.daily),
+	}
+	// Вложенные карты копируются: снимок кодируется уже без блокировки
+	for slotID, banners := range s.targeting {
+		snap.Targeting[slotID] = maps.Clone(banners)
+	}
+	for slotID, banners := range s.bannerSlots {
+		bannerIDs := make([]int, 0, len(banners))
+		for bannerID := range banners {
+			bannerIDs = append(bannerIDs, bannerID)
+		}
+		sort.Ints(bannerIDs)
+		snap.BannerSlots[slotID] = bannerIDs
+	}
+	for key, stat := range s.stats {
+		snap.Stats = append(snap.Stats, snapshotStat{
+			SlotID:   key.SlotID,
+			BannerID: key.BannerID,
+			GroupID:  key.GroupID,
+			Shows:    stat.Shows,
+			Clicks:   stat.Clicks,
+		})
+	}
+	s.mu.RUnlock()
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	// Пишем во временный файл и переименовываем, чтобы не оставить битый снимок
+	tmp, err := os.CreateTemp(filepath.Dir(s.snapshotPath), filepath.Base(s.snapshotPath)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.snapshotPath); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+	return nil
+}
+
+func snapshotBuckets(buckets map[bucketKey]counts) []snapshotStat {
+	stats := make([]snapshotStat, 0, len(buckets))
+	for key, c := range buckets {
+		stats = append(stats, snapshotStat{
+			SlotID:   key.SlotID,
+			BannerID: key.BannerID,
+			GroupID:  key.GroupID,
+			Bucket:   key.Bucket,
+			Shows:    c.Shows,
+			Clicks:   c.Clicks,
+		})
+	}
+	return stats
+}
+
+func (s *MemoryStorage) Close() error {
+	return s.Snapshot()
+}
@@ -0,0 +1,449 @@
+package memory
+
+import (
+	"banner-rotation/internal/storage"
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedCatalog заводит слоты 1..slots и баннеры 1..banners
+func seedCatalog(t *testing.T, store *MemoryStorage, slots, banners int) {
+	t.Helper()
+	ctx := context.Background()
+	for id := 1; id <= slots; id++ {
+		_, err := store.CreateSlot(ctx, storage.Slot{ID: id})
+		require.NoError(t, err)
+	}
+	for id := 1; id <= banners; id++ {
+		_, err := store.CreateBanner(ctx, storage.Banner{ID: id})
+		require.NoError(t, err)
+	}
+}
+
+func TestMemoryStorage(t *testing.T) {
+	ctx := context.Background()
+	store := New()
+	seedCatalog(t, store, 2, 3)
+
+	t.Run("AddBannerToSlot - unknown banner", func(t *testing.T) {
+		err := store.AddBannerToSlot(ctx, 1, 100)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+		assert.Contains(t, err.Error(), "banner 100")
+	})
+
+	t.Run("AddBannerToSlot - unknown slot", func(t *testing.T) {
+		err := store.AddBannerToSlot(ctx, 100, 1)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+		assert.Contains(t, err.Error(), "slot 100")
+	})
+
+	t.Run("AddBannerToSlot", func(t *testing.T) {
+		require.NoError(t, store.AddBannerToSlot(ctx, 1, 2))
+		require.NoError(t, store.AddBannerToSlot(ctx, 1, 1))
+		require.NoError(t, store.AddBannerToSlot(ctx, 1, 1))
+
+		banners, err := store.GetBannersForSlot(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2}, banners)
+	})
+
+	t.Run("RecordShow and RecordClick", func(t *testing.T) {
+		require.NoError(t, store.RecordShow(ctx, 1, 1, 1))
+		require.NoError(t, store.RecordShow(ctx, 1, 1, 1))
+		require.NoError(t, store.RecordClick(ctx, 1, 1, 1))
+		require.NoError(t, store.RecordShow(ctx, 1, 1, 2))
+
+		stats, err := store.GetBannerStats(ctx, 1, 1)
+		require.NoError(t, err)
+		require.Len(t, stats, 1)
+		assert.Equal(t, 1, stats[0].BannerID)
+		assert.Equal(t, 2, stats[0].Shows)
+		assert.Equal(t, 1, stats[0].Clicks)
+	})
+
+	t.Run("RecordShow for banner not in slot", func(t *testing.T) {
+		assert.Error(t, store.RecordShow(ctx, 1, 3, 1))
+		assert.Error(t, store.RecordClick(ctx, 2, 1, 1))
+	})
+
+	t.Run("RemoveBannerFromSlot drops stats", func(t *testing.T) {
+		require.NoError(t, store.RemoveBannerFromSlot(ctx, 1, 1))
+
+		banners, err := store.GetBannersForSlot(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []int{2}, banners)
+
+		stats, err := store.GetBannerStats(ctx, 1, 1)
+		require.NoError(t, err)
+		assert.Empty(t, stats)
+	})
+}
+
+func TestMemoryStorage_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	store := New()
+	seedCatalog(t, store, 1, 1)
+	require.NoError(t, store.AddBannerToSlot(ctx, 1, 1))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				assert.NoError(t, store.RecordShow(ctx, 1, 1, 1))
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats, err := store.GetBannerStats(ctx, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 1000, stats[0].Shows)
+}
+
+func TestMemoryStorage_Snapshot(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	store, err := NewWithSnapshot(path)
+	require.NoError(t, err)
+	seedCatalog(t, store, 1, 1)
+	require.NoError(t, store.AddBannerToSlot(ctx, 1, 1))
+	require.NoError(t, store.RecordShow(ctx, 1, 1, 1))
+	require.NoError(t, store.RecordClick(ctx, 1, 1, 1))
+	require.NoError(t, store.Close())
+
+	restored, err := NewWithSnapshot(path)
+	require.NoError(t, err)
+
+	banners, err := restored.GetBannersForSlot(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, banners)
+
+	stats, err := restored.GetBannerStats(ctx, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 1, stats[0].Shows)
+	assert.Equal(t, 1, stats[0].Clicks)
+}
+
+func TestMemoryStorage_TimeBuckets(t *testing.T) {
+	ctx := context.Background()
+	store := New()
+	seedCatalog(t, store, 1, 1)
+	require.NoError(t, store.AddBannerToSlot(ctx, 1, 1))
+
+	day := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	record := func(at time.Time, shows, clicks int) {
+		store.now = func() time.Time { return at }
+		for i := 0; i < shows; i++ {
+			require.NoError(t, store.RecordShow(ctx, 1, 1, 1))
+		}
+		for i := 0; i < clicks; i++ {
+			require.NoError(t, store.RecordClick(ctx, 1, 1, 1))
+		}
+	}
+	record(day.Add(9*time.Hour+15*time.Minute), 10, 1)
+	record(day.Add(9*time.Hour+45*time.Minute), 10, 1)
+	record(day.Add(14*time.Hour), 5, 2)
+	record(day.Add(24*time.Hour+time.Hour), 7, 0)
+
+	t.Run("hourly range", func(t *testing.T) {
+		stats, err := store.GetBannerStatsRange(ctx, 1, 1, day.Add(9*time.Hour), day.Add(10*time.Hour))
+		require.NoError(t, err)
+		require.Len(t, stats, 1)
+		assert.Equal(t, 20, stats[0].Shows)
+		assert.Equal(t, 2, stats[0].Clicks)
+
+		stats, err = store.GetBannerStatsRange(ctx, 1, 1, day, day.Add(24*time.Hour))
+		require.NoError(t, err)
+		require.Len(t, stats, 1)
+		assert.Equal(t, 25, stats[0].Shows)
+		assert.Equal(t, 4, stats[0].Clicks)
+	})
+
+	t.Run("rollup keeps daily totals", func(t *testing.T) {
+		require.NoError(t, store.RollupHourlyStats(ctx, day.Add(24*time.Hour)))
+		assert.Len(t, store.hourly, 1)
+		assert.Len(t, store.daily, 1)
+
+		stats, err := store.GetBannerStatsRange(ctx, 1, 1, day, day.Add(48*time.Hour))
+		require.NoError(t, err)
+		require.Len(t, stats, 1)
+		assert.Equal(t, 32, stats[0].Shows)
+		assert.Equal(t, 4, stats[0].Clicks)
+
+		// Общие счетчики не зависят от корзин
+		stats, err = store.GetBannerStats(ctx, 1, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 32, stats[0].Shows)
+	})
+}
+
+func TestMemoryStorage_Catalog(t *testing.T) {
+	ctx := context.Background()
+	store := New()
+
+	banner, err := store.CreateBanner(ctx, storage.Banner{Description: "first"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, banner.ID)
+
+	_, err = store.CreateBanner(ctx, storage.Banner{ID: 10, Description: "explicit"})
+	require.NoError(t, err)
+	_, err = store.CreateBanner(ctx, storage.Banner{ID: 10, Description: "duplicate"})
+	assert.ErrorIs(t, err, storage.ErrConflict)
+
+	// Автоматический ID не пересекается с явно заданным
+	next, err := store.CreateBanner(ctx, storage.Banner{Description: "next"})
+	require.NoError(t, err)
+	assert.Equal(t, 11, next.ID)
+
+	require.NoError(t, store.UpdateBanner(ctx, storage.Banner{ID: 1, Description: "renamed"}))
+	banner, err = store.GetBanner(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", banner.Description)
+
+	banners, err := store.ListBanners(ctx)
+	require.NoError(t, err)
+	require.Len(t, banners, 3)
+	assert.Equal(t, []int{1, 10, 11}, []int{banners[0].ID, banners[1].ID, banners[2].ID})
+
+	_, err = store.GetBanner(ctx, 100)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	assert.ErrorIs(t, store.UpdateBanner(ctx, storage.Banner{ID: 100}), storage.ErrNotFound)
+	assert.ErrorIs(t, store.DeleteBanner(ctx, 100), storage.ErrNotFound)
+
+	slot, err := store.CreateSlot(ctx, storage.Slot{Description: "slot"})
+	require.NoError(t, err)
+	group, err := store.CreateGroup(ctx, storage.Group{Description: "group"})
+	require.NoError(t, err)
+
+	require.NoError(t, store.AddBannerToSlot(ctx, slot.ID, banner.ID))
+	require.NoError(t, store.RecordShow(ctx, slot.ID, banner.ID, group.ID))
+
+	// Сущности, на которые ссылаются ротация и статистика, не удаляются
+	assert.ErrorIs(t, store.DeleteBanner(ctx, banner.ID), storage.ErrConflict)
+	assert.ErrorIs(t, store.DeleteSlot(ctx, slot.ID), storage.ErrConflict)
+	assert.ErrorIs(t, store.DeleteGroup(ctx, group.ID), storage.ErrConflict)
+
+	require.NoError(t, store.RemoveBannerFromSlot(ctx, slot.ID, banner.ID))
+	require.NoError(t, store.DeleteBanner(ctx, banner.ID))
+	require.NoError(t, store.DeleteSlot(ctx, slot.ID))
+	require.NoError(t, store.DeleteGroup(ctx, group.ID))
+
+	_, err = store.GetSlot(ctx, slot.ID)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	groups, err := store.ListGroups(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+}
+
+func TestMemoryStorage_CreativeAndSizes(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	store, err := NewWithSnapshot(path)
+	require.NoError(t, err)
+
+	creative := storage.Creative{
+		ImageURL: "https://cdn.example.com/728x90.png",
+		ClickURL: "https://example.com/landing",
+		Width:    728,
+		Height:   90,
+		AltText:  "sale",
+	}
+	banner, err := store.CreateBanner(ctx, storage.Banner{Description: "leaderboard", Creative: creative})
+	require.NoError(t, err)
+	sizes := []storage.Size{{Width: 728, Height: 90}}
+	slot, err := store.CreateSlot(ctx, storage.Slot{Description: "header", Sizes: sizes})
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	// Креатив и размеры слота переживают перезапуск через снимок
+	restored, err := NewWithSnapshot(path)
+	require.NoError(t, err)
+	got, err := restored.GetBanner(ctx, banner.ID)
+	require.NoError(t, err)
+	assert.Equal(t, creative, got.Creative)
+	restoredSlot, err := restored.GetSlot(ctx, slot.ID)
+	require.NoError(t, err)
+	assert.Equal(t, sizes, restoredSlot.Sizes)
+
+	require.NoError(t, restored.UpdateBanner(ctx, storage.Banner{ID: banner.ID, Description: "plain"}))
+	banners, err := restored.ListBanners(ctx)
+	require.NoError(t, err)
+	require.Len(t, banners, 1)
+	assert.Zero(t, banners[0].Creative)
+}
+
+func TestMemoryStorage_Campaigns(t *testing.T) {
+	ctx := context.Background()
+	store := New()
+
+	_, err := store.CreateCampaign(ctx, storage.Campaign{AdvertiserID: 1, Name: "orphan"})
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	advertiser, err := store.CreateAdvertiser(ctx, storage.Advertiser{Name: "acme"})
+	require.NoError(t, err)
+
+	campaign := storage.Campaign{
+		AdvertiserID: advertiser.ID,
+		Name:         "winter",
+		Status:       storage.CampaignPaused,
+		Budget:       100000,
+		StartAt:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	campaign, err = store.CreateCampaign(ctx, campaign)
+	require.NoError(t, err)
+
+	got, err := store.GetCampaign(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, campaign, got)
+
+	campaign.Status = storage.CampaignActive
+	campaign.EndAt = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, store.UpdateCampaign(ctx, campaign))
+	campaigns, err := store.ListCampaigns(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.Campaign{campaign}, campaigns)
+
+	_, err = store.CreateBanner(ctx, storage.Banner{Description: "orphan", CampaignID: 100})
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	banner, err := store.CreateBanner(ctx, storage.Banner{Description: "snow", CampaignID: campaign.ID})
+	require.NoError(t, err)
+	gotBanner, err := store.GetBanner(ctx, banner.ID)
+	require.NoError(t, err)
+	assert.Equal(t, campaign.ID, gotBanner.CampaignID)
+
+	// Пакетное чтение пропускает отсутствующие ID и повторы
+	banners, err := store.GetBanners(ctx, []int{banner.ID, 100, banner.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []storage.Banner{gotBanner}, banners)
+	campaigns, err = store.GetCampaigns(ctx, []int{100, campaign.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []storage.Campaign{campaign}, campaigns)
+
+	// Кампания с баннерами и рекламодатель с кампаниями не удаляются
+	assert.ErrorIs(t, store.DeleteCampaign(ctx, campaign.ID), storage.ErrConflict)
+	assert.ErrorIs(t, store.DeleteAdvertiser(ctx, advertiser.ID), storage.ErrConflict)
+
+	require.NoError(t, store.UpdateBanner(ctx, storage.Banner{ID: banner.ID, Description: "snow"}))
+	require.NoError(t, store.DeleteCampaign(ctx, campaign.ID))
+	require.NoError(t, store.DeleteAdvertiser(ctx, advertiser.ID))
+
+	_, err = store.GetCampaign(ctx, campaign.ID)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	advertisers, err := store.ListAdvertisers(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, advertisers)
+}
+
+func TestMemoryStorage_UpdateSlotRotation(t *testing.T) {
+	ctx := context.Background()
+	store := New()
+	seedCatalog(t, store, 1, 5)
+
+	// Изменение с неизвестным баннером или слотом не применяется
+	err := store.UpdateSlotRotation(ctx, 1, storage.RotationChange{Add: []int{1, 100}})
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	err = store.UpdateSlotRotation(ctx, 100, storage.RotationChange{Add: []int{1}})
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	banners, err := store.GetBannersForSlot(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, banners)
+
+	require.NoError(t, store.UpdateSlotRotation(ctx, 1, storage.RotationChange{Add: []int{1, 2, 3}}))
+	require.NoError(t, store.RecordShow(ctx, 1, 1, 1))
+	require.NoError(t, store.RecordShow(ctx, 1, 2, 1))
+
+	require.NoError(t, store.UpdateSlotRotation(ctx, 1, storage.RotationChange{Add: []int{4}, Remove: []int{1}}))
+	banners, err = store.GetBannersForSlot(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 3, 4}, banners)
+
+	stats, err := store.GetBannerStats(ctx, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 2, stats[0].BannerID)
+
+	require.NoError(t, store.UpdateSlotRotation(ctx, 1, storage.RotationChange{Add: []int{3, 5}, Replace: true}))
+	banners, err = store.GetBannersForSlot(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 5}, banners)
+
+	stats, err = store.GetBannerStats(ctx, 1, 1)
+	require.NoError(t, err)
+	assert.Empty(t, stats)
+
+	require.NoError(t, store.UpdateSlotRotation(ctx, 1, storage.RotationChange{Replace: true}))
+	banners, err = store.GetBannersForSlot(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, banners)
+}
+
+func TestMemoryStorage_GroupRules(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	store, err := NewWithSnapshot(path)
+	require.NoError(t, err)
+
+	rule := &storage.GroupRule{Priority: 1, MinAge: 18, Genders: []string{"f"}}
+	group, err := store.CreateGroup(ctx, storage.Group{Description: "women", Rule: rule})
+	require.NoError(t, err)
+
+	// Хранилище держит копию правила
+	rule.Genders[0] = "m"
+	got, err := store.GetGroup(ctx, group.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"f"}, got.Rule.Genders)
+
+	require.NoError(t, store.Close())
+	restored, err := NewWithSnapshot(path)
+	require.NoError(t, err)
+	groups, err := restored.ListGroups(ctx)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, &storage.GroupRule{Priority: 1, MinAge: 18, Genders: []string{"f"}}, groups[0].Rule)
+
+	require.NoError(t, restored.UpdateGroup(ctx, storage.Group{ID: group.ID, Description: "everyone"}))
+	got, err = restored.GetGroup(ctx, group.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got.Rule)
+}
+
+func TestMemoryStorage_Targeting(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	store, err := NewWithSnapshot(path)
+	require.NoError(t, err)
+	seedCatalog(t, store, 1, 3)
+
+	require.NoError(t, store.AddBannerToSlot(ctx, 1, 1))
+	require.NoError(t, store.AddBannerToSlot(ctx, 1, 2))
+	assert.ErrorIs(t, store.SetBannerTargeting(ctx, 1, 3, storage.Targeting{Countries: []string{"RU"}}), storage.ErrNotFound)
+
+	targeting := storage.Targeting{Countries: []string{"RU"}, Devices: []string{"mobile"}}
+	require.NoError(t, store.SetBannerTargeting(ctx, 1, 1, targeting))
+	require.NoError(t, store.Close())
+
+	store, err = NewWithSnapshot(path)
+	require.NoError(t, err)
+	got, err := store.GetSlotTargeting(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]storage.Targeting{1: targeting}, got)
+
+	require.NoError(t, store.RemoveBannerFromSlot(ctx, 1, 1))
+	require.NoError(t, store.AddBannerToSlot(ctx, 1, 1))
+	got, err = store.GetSlotTargeting(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, got, "targeting is removed with the banner")
+}
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"banner-rotation/internal/auth"
+	"banner-rotation/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var _ auth.KeyStore = (*KeyStore)(nil)
+
+// KeyStore - API-ключи в PostgreSQL. Использует пул соединений хранилища.
+type KeyStore struct {
+	db *pgxpool.Pool
+}
+
+func NewKeyStore(store *PostgresStorage) *KeyStore {
+	return &KeyStore{db: store.db}
+}
+
+const keyColumns = `id, name, scopes, created_at, revoked_at`
+
+func scanKey(row pgx.Row) (auth.Key, error) {
+	var (
+		key    auth.Key
+		scopes []string
+	)
+	if err := row.Scan(&key.ID, &key.Name, &scopes, &key.CreatedAt, &key.RevokedAt); err != nil {
+		return auth.Key{}, err
+	}
+	for _, scope := range scopes {
+		key.Scopes = append(key.Scopes, auth.Scope(scope))
+	}
+	return key, nil
+}
+
+// CreateKey сохраняет хеш нового ключа
+func (s *KeyStore) CreateKey(ctx context.Context, name, hash string, scopes []auth.Scope) (auth.Key, error) {
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+
+	key, err := scanKey(s.db.QueryRow(ctx, `
+        INSERT INTO api_keys (name, key_hash, scopes)
+        VALUES ($1, $2, $3)
+        RETURNING `+keyColumns,
+		name, hash, names,
+	))
+	if err != nil {
+		return auth.Key{}, fmt.Errorf("failed to create api key: %w", err)
+	}
+	return key, nil
+}
+
+// ListKeys возвращает все ключи, включая отозванные
+func (s *KeyStore) ListKeys(ctx context.Context) ([]auth.Key, error) {
+	rows, err := s.db.Query(ctx, `SELECT `+keyColumns+` FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []auth.Key
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeKey отзывает ключ. Возвращает storage.ErrNotFound, если действующего
+// ключа с таким ID нет.
+func (s *KeyStore) RevokeKey(ctx context.Context, id int) error {
+	tag, err := s.db.Exec(ctx, `
+        UPDATE api_keys SET revoked_at = now()
+        WHERE id = $1 AND revoked_at IS NULL`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("api key %d: %w", id, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *KeyStore) LookupKey(ctx context.Context, hash string) (auth.Key, error) {
+	key, err := scanKey(s.db.QueryRow(ctx, `
+        SELECT `+keyColumns+` FROM api_keys
+        WHERE key_hash = $1 AND revoked_at IS NULL`,
+		hash,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return auth.Key{}, storage.ErrNotFound
+	}
+	return key, err
+}
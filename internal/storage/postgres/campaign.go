@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"banner-rotation/internal/storage"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	advertiserColumns = []string{"name"}
+	campaignColumns   = []string{"advertiser_id", "name", "status", "budget", "start_at", "end_at"}
+)
+
+func campaignValues(c storage.Campaign) []any {
+	return []any{c.AdvertiserID, c.Name, string(c.Status), c.Budget, nullTime(c.StartAt), nullTime(c.EndAt)}
+}
+
+func campaignDest(c *storage.Campaign) []any {
+	return []any{&c.AdvertiserID, &c.Name, &c.Status, &c.Budget, &nullableTime{&c.StartAt}, &nullableTime{&c.EndAt}}
+}
+
+func (s *PostgresStorage) CreateAdvertiser(ctx context.Context, advertiser storage.Advertiser) (storage.Advertiser, error) {
+	id, err := s.createEntity(ctx, "advertisers", advertiser.ID, advertiserColumns, []any{advertiser.Name})
+	if err != nil {
+		return storage.Advertiser{}, err
+	}
+	advertiser.ID = id
+	return advertiser, nil
+}
+
+func (s *PostgresStorage) GetAdvertiser(ctx context.Context, id int) (storage.Advertiser, error) {
+	advertiser := storage.Advertiser{ID: id}
+	if err := s.getEntity(ctx, "advertisers", id, advertiserColumns, &advertiser.Name); err != nil {
+		return storage.Advertiser{}, err
+	}
+	return advertiser, nil
+}
+
+func (s *PostgresStorage) ListAdvertisers(ctx context.Context) ([]storage.Advertiser, error) {
+	var advertisers []storage.Advertiser
+	err := s.listEntities(ctx, "advertisers", advertiserColumns, func(rows pgx.Rows) error {
+		var advertiser storage.Advertiser
+		if err := rows.Scan(&advertiser.ID, &advertiser.Name); err != nil {
+			return err
+		}
+		advertisers = append(advertisers, advertiser)
+		return nil
+	})
+	return advertisers, err
+}
+
+func (s *PostgresStorage) UpdateAdvertiser(ctx context.Context, advertiser storage.Advertiser) error {
+	return s.updateEntity(ctx, "advertisers", advertiser.ID, advertiserColumns, []any{advertiser.Name})
+}
+
+func (s *PostgresStorage) DeleteAdvertiser(ctx context.Context, id int) error {
+	return s.deleteEntity(ctx, "advertisers", id)
+}
+
+func (s *PostgresStorage) CreateCampaign(ctx context.Context, campaign storage.Campaign) (storage.Campaign, error) {
+	id, err := s.createEntity(ctx, "campaigns", campaign.ID, campaignColumns, campaignValues(campaign))
+	if err != nil {
+		return storage.Campaign{}, err
+	}
+	campaign.ID = id
+	return campaign, nil
+}
+
+func (s *PostgresStorage) GetCampaign(ctx context.Context, id int) (storage.Campaign, error) {
+	campaign := storage.Campaign{ID: id}
+	if err := s.getEntity(ctx, "campaigns", id, campaignColumns, campaignDest(&campaign)...); err != nil {
+		return storage.Campaign{}, err
+	}
+	return campaign, nil
+}
+
+func (s *PostgresStorage) GetCampaigns(ctx context.Context, ids []int) ([]storage.Campaign, error) {
+	return s.selectCampaigns(ctx, ids)
+}
+
+func (s *PostgresStorage) ListCampaigns(ctx context.Context) ([]storage.Campaign, error) {
+	return s.selectCampaigns(ctx, nil)
+}
+
+// selectCampaigns выбирает кампании с ID из ids, nil - все кампании
+func (s *PostgresStorage) selectCampaigns(ctx context.Context, ids []int) ([]storage.Campaign, error) {
+	var campaigns []storage.Campaign
+	err := s.selectEntities(ctx, "campaigns", campaignColumns, ids, func(rows pgx.Rows) error {
+		var campaign storage.Campaign
+		if err := rows.Scan(append([]any{&campaign.ID}, campaignDest(&campaign)...)...); err != nil {
+			return err
+		}
+		campaigns = append(campaigns, campaign)
+		return nil
+	})
+	return campaigns, err
+}
+
+func (s *PostgresStorage) UpdateCampaign(ctx context.Context, campaign storage.Campaign) error {
+	return s.updateEntity(ctx, "campaigns", campaign.ID, campaignColumns, campaignValues(campaign))
+}
+
+func (s *PostgresStorage) DeleteCampaign(ctx context.Context, id int) error {
+	return s.deleteEntity(ctx, "campaigns", id)
+}
+
+// Необязательные столбцы (campaign_id баннера, период кампании) хранят NULL
+// там, где в модели нулевое значение.
+
+func nullID(id int) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// nullableID читает столбец с ID, превращая NULL в 0
+type nullableID struct{ dest *int }
+
+func (n *nullableID) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*n.dest = 0
+	case int64:
+		*n.dest = int(v)
+	case int32:
+		*n.dest = int(v)
+	default:
+		return fmt.Errorf("cannot scan %T into id", src)
+	}
+	return nil
+}
+
+// nullableTime читает столбец TIMESTAMPTZ, превращая NULL в нулевое время
+type nullableTime struct{ dest *time.Time }
+
+func (n *nullableTime) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*n.dest = time.Time{}
+	case time.Time:
+		*n.dest = v
+	default:
+		return fmt.Errorf("cannot scan %T into time", src)
+	}
+	return nil
+}
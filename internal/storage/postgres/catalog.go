@@ -0,0 +1,314 @@
+package postgres
+
+import (
+	"banner-rotation/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// uniqueViolation - код ошибки PostgreSQL при нарушении уникальности
+const uniqueViolation = "23505"
+
+var (
+	groupColumns  = []string{"description", "rule"}
+	bannerColumns = []string{"description", "campaign_id", "image_url", "html", "click_url", "width", "height", "alt_text"}
+	slotColumns   = []string{"description", "sizes"}
+)
+
+func bannerValues(b storage.Banner) []any {
+	return []any{b.Description, nullID(b.CampaignID), b.ImageURL, b.HTML, b.ClickURL, b.Width, b.Height, b.AltText}
+}
+
+func bannerDest(b *storage.Banner) []any {
+	return []any{&b.Description, &nullableID{&b.CampaignID}, &b.ImageURL, &b.HTML, &b.ClickURL, &b.Width, &b.Height, &b.AltText}
+}
+
+// slotValues не передает nil вместо списка размеров: столбец sizes NOT NULL
+func slotValues(slot storage.Slot) []any {
+	sizes := slot.Sizes
+	if sizes == nil {
+		sizes = []storage.Size{}
+	}
+	return []any{slot.Description, sizes}
+}
+
+func (s *PostgresStorage) CreateBanner(ctx context.Context, banner storage.Banner) (storage.Banner, error) {
+	id, err := s.createEntity(ctx, "banners", banner.ID, bannerColumns, bannerValues(banner))
+	if err != nil {
+		return storage.Banner{}, err
+	}
+	banner.ID = id
+	return banner, nil
+}
+
+func (s *PostgresStorage) GetBanner(ctx context.Context, id int) (storage.Banner, error) {
+	banner := storage.Banner{ID: id}
+	if err := s.getEntity(ctx, "banners", id, bannerColumns, bannerDest(&banner)...); err != nil {
+		return storage.Banner{}, err
+	}
+	return banner, nil
+}
+
+func (s *PostgresStorage) GetBanners(ctx context.Context, ids []int) ([]storage.Banner, error) {
+	return s.selectBanners(ctx, ids)
+}
+
+func (s *PostgresStorage) ListBanners(ctx context.Context) ([]storage.Banner, error) {
+	return s.selectBanners(ctx, nil)
+}
+
+// selectBanners выбирает баннеры с ID из ids, nil - все баннеры
+func (s *PostgresStorage) selectBanners(ctx context.Context, ids []int) ([]storage.Banner, error) {
+	var banners []storage.Banner
+	err := s.selectEntities(ctx, "banners", bannerColumns, ids, func(rows pgx.Rows) error {
+		var banner storage.Banner
+		if err := rows.Scan(append([]any{&banner.ID}, bannerDest(&banner)...)...); err != nil {
+			return err
+		}
+		banners = append(banners, banner)
+		return nil
+	})
+	return banners, err
+}
+
+func (s *PostgresStorage) UpdateBanner(ctx context.Context, banner storage.Banner) error {
+	return s.updateEntity(ctx, "banners", banner.ID, bannerColumns, bannerValues(banner))
+}
+
+func (s *PostgresStorage) DeleteBanner(ctx context.Context, id int) error {
+	return s.deleteEntity(ctx, "banners", id)
+}
+
+func (s *PostgresStorage) CreateSlot(ctx context.Context, slot storage.Slot) (storage.Slot, error) {
+	id, err := s.createEntity(ctx, "slots", slot.ID, slotColumns, slotValues(slot))
+	if err != nil {
+		return storage.Slot{}, err
+	}
+	slot.ID = id
+	return slot, nil
+}
+
+func (s *PostgresStorage) GetSlot(ctx context.Context, id int) (storage.Slot, error) {
+	slot := storage.Slot{ID: id}
+	if err := s.getEntity(ctx, "slots", id, slotColumns, &slot.Description, &slot.Sizes); err != nil {
+		return storage.Slot{}, err
+	}
+	return slot, nil
+}
+
+func (s *PostgresStorage) ListSlots(ctx context.Context) ([]storage.Slot, error) {
+	var slots []storage.Slot
+	err := s.listEntities(ctx, "slots", slotColumns, func(rows pgx.Rows) error {
+		var slot storage.Slot
+		if err := rows.Scan(&slot.ID, &slot.Description, &slot.Sizes); err != nil {
+			return err
+		}
+		slots = append(slots, slot)
+		return nil
+	})
+	return slots, err
+}
+
+func (s *PostgresStorage) UpdateSlot(ctx context.Context, slot storage.Slot) error {
+	return s.updateEntity(ctx, "slots", slot.ID, slotColumns, slotValues(slot))
+}
+
+func (s *PostgresStorage) DeleteSlot(ctx context.Context, id int) error {
+	return s.deleteEntity(ctx, "slots", id)
+}
+
+func (s *PostgresStorage) CreateGroup(ctx context.Context, group storage.Group) (storage.Group, error) {
+	id, err := s.createEntity(ctx, "groups", group.ID, groupColumns, []any{group.Description, group.Rule})
+	if err != nil {
+		return storage.Group{}, err
+	}
+	group.ID = id
+	return group, nil
+}
+
+func (s *PostgresStorage) GetGroup(ctx context.Context, id int) (storage.Group, error) {
+	group := storage.Group{ID: id}
+	if err := s.getEntity(ctx, "groups", id, groupColumns, &group.Description, &group.Rule); err != nil {
+		return storage.Group{}, err
+	}
+	return group, nil
+}
+
+func (s *PostgresStorage) ListGroups(ctx context.Context) ([]storage.Group, error) {
+	var groups []storage.Group
+	err := s.listEntities(ctx, "groups", groupColumns, func(rows pgx.Rows) error {
+		var group storage.Group
+		if err := rows.Scan(&group.ID, &group.Description, &group.Rule); err != nil {
+			return err
+		}
+		groups = append(groups, group)
+		return nil
+	})
+	return groups, err
+}
+
+func (s *PostgresStorage) UpdateGroup(ctx context.Context, group storage.Group) error {
+	return s.updateEntity(ctx, "groups", group.ID, groupColumns, []any{group.Description, group.Rule})
+}
+
+func (s *PostgresStorage) DeleteGroup(ctx context.Context, id int) error {
+	return s.deleteEntity(ctx, "groups", id)
+}
+
+// Справочники banners, slots и groups устроены одинаково (id SERIAL плюс
+// набор столбцов), поэтому работа с ними сведена к общим функциям.
+// Имена таблиц и столбцов всегда константы.
+
+// createEntity вставляет запись и возвращает ее ID. При явном ID сдвигает
+// последовательность SERIAL, чтобы следующие автоматические ID не пересеклись.
+func (s *PostgresStorage) createEntity(ctx context.Context, table string, id int, columns []string, values []any) (int, error) {
+	if id == 0 {
+		err := s.db.QueryRow(ctx,
+			fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s) RETURNING id`,
+				table, strings.Join(columns, ", "), placeholders(1, len(columns))),
+			values...,
+		).Scan(&id)
+		if err != nil {
+			return 0, referenceError("failed to create", table, err)
+		}
+		return id, nil
+	}
+
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx,
+			fmt.Sprintf(`INSERT INTO %s (id, %s) VALUES ($1, %s)`,
+				table, strings.Join(columns, ", "), placeholders(2, len(columns))),
+			append([]any{id}, values...)...,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, fmt.Sprintf(
+			`SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), GREATEST((SELECT MAX(id) FROM %[1]s), 1))`,
+			table,
+		))
+		return err
+	})
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return 0, fmt.Errorf("%s %d already exists: %w", entityName(table), id, storage.ErrConflict)
+	}
+	if err != nil {
+		return 0, referenceError("failed to create", table, err)
+	}
+	return id, nil
+}
+
+func (s *PostgresStorage) getEntity(ctx context.Context, table string, id int, columns []string, dest ...any) error {
+	err := s.db.QueryRow(ctx,
+		fmt.Sprintf(`SELECT %s FROM %s WHERE id = $1`, strings.Join(columns, ", "), table), id,
+	).Scan(dest...)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%s %d: %w", entityName(table), id, storage.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %w", entityName(table), err)
+	}
+	return nil
+}
+
+// listEntities выбирает id и перечисленные столбцы всех записей по порядку ID
+func (s *PostgresStorage) listEntities(ctx context.Context, table string, columns []string, scan func(rows pgx.Rows) error) error {
+	return s.selectEntities(ctx, table, columns, nil, scan)
+}
+
+// selectEntities выбирает id и перечисленные столбцы записей с ID из ids
+// по порядку ID одним запросом, nil - все записи
+func (s *PostgresStorage) selectEntities(ctx context.Context, table string, columns []string, ids []int, scan func(rows pgx.Rows) error) error {
+	query := fmt.Sprintf(`SELECT id, %s FROM %s`, strings.Join(columns, ", "), table)
+	var args []any
+	if ids != nil {
+		query += ` WHERE id = ANY($1)`
+		args = append(args, ids)
+	}
+	rows, err := s.db.Query(ctx, query+` ORDER BY id`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return fmt.Errorf("failed to scan %s: %w", table, err)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStorage) updateEntity(ctx context.Context, table string, id int, columns []string, values []any) error {
+	set := make([]string, len(columns))
+	for i, column := range columns {
+		set[i] = fmt.Sprintf("%s = $%d", column, i+2)
+	}
+
+	tag, err := s.db.Exec(ctx,
+		fmt.Sprintf(`UPDATE %s SET %s WHERE id = $1`, table, strings.Join(set, ", ")),
+		append([]any{id}, values...)...,
+	)
+	if err != nil {
+		return referenceError("failed to update", table, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s %d: %w", entityName(table), id, storage.ErrNotFound)
+	}
+	return nil
+}
+
+// deleteEntity удаляет запись. Внешние ключи с ON DELETE RESTRICT не дают
+// удалить баннер или слот в ротации и группу со статистикой.
+func (s *PostgresStorage) deleteEntity(ctx context.Context, table string, id int) error {
+	tag, err := s.db.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, table), id)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return fmt.Errorf("%s %d is in use: %w", entityName(table), id, storage.ErrConflict)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", entityName(table), err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s %d: %w", entityName(table), id, storage.ErrNotFound)
+	}
+	return nil
+}
+
+// referenceError оборачивает ошибку записи; ссылка на несуществующую
+// запись другого справочника (например, кампанию баннера) дает ErrNotFound
+func referenceError(action, table string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return fmt.Errorf("%s %s: %s: %w", action, entityName(table), pgErr.Detail, storage.ErrNotFound)
+	}
+	return fmt.Errorf("%s %s: %w", action, entityName(table), err)
+}
+
+// entityName возвращает имя сущности для сообщений об ошибках
+func entityName(table string) string {
+	return strings.TrimSuffix(table, "s")
+}
+
+// placeholders возвращает список параметров запроса "$from, ..., $from+n-1"
+func placeholders(from, n int) string {
+	params := make([]string, n)
+	for i := range params {
+		params[i] = fmt.Sprintf("$%d", from+i)
+	}
+	return strings.Join(params, ", ")
+}
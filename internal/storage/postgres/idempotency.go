@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"banner-rotation/internal/idempotency"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// purgeInterval - как часто из таблицы удаляются истекшие ключи
+const purgeInterval = time.Minute
+
+var _ idempotency.Store = (*IdempotencyStore)(nil)
+
+// IdempotencyStore - ключи идемпотентности в PostgreSQL, общие для всех
+// экземпляров сервиса. Использует пул соединений хранилища.
+type IdempotencyStore struct {
+	db *pgxpool.Pool
+
+	mu        sync.Mutex
+	lastPurge time.Time
+}
+
+func NewIdempotencyStore(store *PostgresStorage) *IdempotencyStore {
+	return &IdempotencyStore{db: store.db}
+}
+
+// Begin занимает ключ одним запросом: истекший ключ перезаписывается,
+// занятый остается как есть, и тогда читается его запись
+func (s *IdempotencyStore) Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (idempotency.Record, bool, error) {
+	s.purge(ctx)
+
+	var created bool
+	err := s.db.QueryRow(ctx, `
+        INSERT INTO idempotency_keys (key, fingerprint, expires_at)
+        VALUES ($1, $2, now() + $3::interval)
+        ON CONFLICT (key) DO UPDATE
+        SET fingerprint = EXCLUDED.fingerprint, status = NULL, content_type = NULL,
+            body = NULL, expires_at = EXCLUDED.expires_at
+        WHERE idempotency_keys.expires_at <= now()
+        RETURNING true`,
+		key, fingerprint, ttl,
+	).Scan(&created)
+	if err == nil {
+		return idempotency.Record{}, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return idempotency.Record{}, false, fmt.Errorf("failed to begin idempotent request: %w", err)
+	}
+
+	var (
+		rec         idempotency.Record
+		status      *int
+		contentType *string
+		body        []byte
+	)
+	err = s.db.QueryRow(ctx, `
+        SELECT fingerprint, status, content_type, body
+        FROM idempotency_keys WHERE key = $1`,
+		key,
+	).Scan(&rec.Fingerprint, &status, &contentType, &body)
+	if err != nil {
+		return idempotency.Record{}, false, fmt.Errorf("failed to read idempotency key: %w", err)
+	}
+	if status != nil {
+		rec.Response = &idempotency.Response{Status: *status, Body: body}
+		if contentType != nil {
+			rec.Response.ContentType = *contentType
+		}
+	}
+	return rec, false, nil
+}
+
+func (s *IdempotencyStore) Complete(ctx context.Context, key string, resp idempotency.Response, ttl time.Duration) error {
+	_, err := s.db.Exec(ctx, `
+        UPDATE idempotency_keys
+        SET status = $2, content_type = $3, body = $4, expires_at = now() + $5::interval
+        WHERE key = $1`,
+		key, resp.Status, resp.ContentType, resp.Body, ttl,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save idempotent response: %w", err)
+	}
+	return nil
+}
+
+func (s *IdempotencyStore) Release(ctx context.Context, key string) error {
+	if _, err := s.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE key = $1`, key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// purge удаляет истекшие ключи не чаще раза в purgeInterval. Ошибка
+// не мешает запросу: истекшие ключи все равно считаются свободными.
+func (s *IdempotencyStore) purge(ctx context.Context) {
+	s.mu.Lock()
+	if time.Since(s.lastPurge) < purgeInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastPurge = time.Now()
+	s.mu.Unlock()
+
+	if _, err := s.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= now()`); err != nil {
+		log.Printf("Failed to purge idempotency keys: %v", err)
+	}
+}
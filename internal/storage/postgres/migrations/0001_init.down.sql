@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS statistics;
+DROP TABLE IF EXISTS banner_slots;
+DROP TABLE IF EXISTS banners;
+DROP TABLE IF EXISTS slots;
+DROP TABLE IF EXISTS groups;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS statistics_daily;
+DROP TABLE IF EXISTS statistics_hourly;
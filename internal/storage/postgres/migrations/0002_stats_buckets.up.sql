@@ -0,0 +1,24 @@
+-- Часовые корзины статистики. Старые корзины сворачиваются в дневные.
+CREATE TABLE statistics_hourly (
+    slot_id INT NOT NULL,
+    banner_id INT NOT NULL,
+    group_id INT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    bucket TIMESTAMPTZ NOT NULL,
+    shows INT NOT NULL DEFAULT 0,
+    clicks INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (slot_id, group_id, bucket, banner_id),
+    FOREIGN KEY (slot_id, banner_id) REFERENCES banner_slots(slot_id, banner_id) ON DELETE CASCADE
+);
+
+CREATE INDEX statistics_hourly_bucket_idx ON statistics_hourly (bucket);
+
+CREATE TABLE statistics_daily (
+    slot_id INT NOT NULL,
+    banner_id INT NOT NULL,
+    group_id INT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    bucket TIMESTAMPTZ NOT NULL,
+    shows INT NOT NULL DEFAULT 0,
+    clicks INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (slot_id, group_id, bucket, banner_id),
+    FOREIGN KEY (slot_id, banner_id) REFERENCES banner_slots(slot_id, banner_id) ON DELETE CASCADE
+);
@@ -0,0 +1,22 @@
+ALTER TABLE statistics_daily
+    DROP CONSTRAINT statistics_daily_group_id_fkey,
+    ADD CONSTRAINT statistics_daily_group_id_fkey
+        FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE;
+
+ALTER TABLE statistics_hourly
+    DROP CONSTRAINT statistics_hourly_group_id_fkey,
+    ADD CONSTRAINT statistics_hourly_group_id_fkey
+        FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE;
+
+ALTER TABLE statistics
+    DROP CONSTRAINT statistics_group_id_fkey,
+    ADD CONSTRAINT statistics_group_id_fkey
+        FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE;
+
+ALTER TABLE banner_slots
+    DROP CONSTRAINT banner_slots_slot_id_fkey,
+    ADD CONSTRAINT banner_slots_slot_id_fkey
+        FOREIGN KEY (slot_id) REFERENCES slots(id) ON DELETE CASCADE,
+    DROP CONSTRAINT banner_slots_banner_id_fkey,
+    ADD CONSTRAINT banner_slots_banner_id_fkey
+        FOREIGN KEY (banner_id) REFERENCES banners(id) ON DELETE CASCADE;
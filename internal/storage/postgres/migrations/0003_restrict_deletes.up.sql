@@ -0,0 +1,25 @@
+-- Справочники управляются через API: удаление баннера, слота или группы,
+-- на которые ссылаются ротация или статистика, должно завершаться ошибкой,
+-- а не каскадно стирать данные.
+ALTER TABLE banner_slots
+    DROP CONSTRAINT banner_slots_slot_id_fkey,
+    ADD CONSTRAINT banner_slots_slot_id_fkey
+        FOREIGN KEY (slot_id) REFERENCES slots(id) ON DELETE RESTRICT,
+    DROP CONSTRAINT banner_slots_banner_id_fkey,
+    ADD CONSTRAINT banner_slots_banner_id_fkey
+        FOREIGN KEY (banner_id) REFERENCES banners(id) ON DELETE RESTRICT;
+
+ALTER TABLE statistics
+    DROP CONSTRAINT statistics_group_id_fkey,
+    ADD CONSTRAINT statistics_group_id_fkey
+        FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE RESTRICT;
+
+ALTER TABLE statistics_hourly
+    DROP CONSTRAINT statistics_hourly_group_id_fkey,
+    ADD CONSTRAINT statistics_hourly_group_id_fkey
+        FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE RESTRICT;
+
+ALTER TABLE statistics_daily
+    DROP CONSTRAINT statistics_daily_group_id_fkey,
+    ADD CONSTRAINT statistics_daily_group_id_fkey
+        FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE RESTRICT;
@@ -0,0 +1,7 @@
+ALTER TABLE banners
+    DROP COLUMN image_url,
+    DROP COLUMN html,
+    DROP COLUMN click_url,
+    DROP COLUMN width,
+    DROP COLUMN height,
+    DROP COLUMN alt_text;
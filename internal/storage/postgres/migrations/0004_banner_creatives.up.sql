@@ -0,0 +1,7 @@
+ALTER TABLE banners
+    ADD COLUMN image_url TEXT NOT NULL DEFAULT '',
+    ADD COLUMN html TEXT NOT NULL DEFAULT '',
+    ADD COLUMN click_url TEXT NOT NULL DEFAULT '',
+    ADD COLUMN width INT NOT NULL DEFAULT 0,
+    ADD COLUMN height INT NOT NULL DEFAULT 0,
+    ADD COLUMN alt_text TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- Допустимые размеры креативов слота: JSON-массив [{"Width": 728, "Height": 90}].
+-- Пустой массив - слот принимает баннеры любого размера.
+ALTER TABLE slots ADD COLUMN sizes JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,4 @@
+ALTER TABLE banners DROP COLUMN campaign_id;
+
+DROP TABLE campaigns;
+DROP TABLE advertisers;
@@ -0,0 +1,21 @@
+CREATE TABLE advertisers (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+CREATE TABLE campaigns (
+    id SERIAL PRIMARY KEY,
+    advertiser_id INT NOT NULL REFERENCES advertisers(id) ON DELETE RESTRICT,
+    name TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused', 'stopped')),
+    budget BIGINT NOT NULL DEFAULT 0,
+    -- NULL - период показа не ограничен с этой стороны
+    start_at TIMESTAMPTZ,
+    end_at TIMESTAMPTZ
+);
+
+CREATE INDEX campaigns_advertiser_id_idx ON campaigns(advertiser_id);
+
+ALTER TABLE banners ADD COLUMN campaign_id INT REFERENCES campaigns(id) ON DELETE RESTRICT;
+
+CREATE INDEX banners_campaign_id_idx ON banners(campaign_id);
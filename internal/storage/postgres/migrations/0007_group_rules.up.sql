@@ -0,0 +1,4 @@
+-- Правило подбора группы по атрибутам пользователя: JSON-объект
+-- {"Priority": 10, "MinAge": 18, "MaxAge": 25, "Genders": ["f"], "Interests": ["sport"]}.
+-- NULL - группа не подбирается автоматически.
+ALTER TABLE groups ADD COLUMN rule JSONB;
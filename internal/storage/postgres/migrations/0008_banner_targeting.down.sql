@@ -0,0 +1 @@
+ALTER TABLE banner_slots DROP COLUMN targeting;
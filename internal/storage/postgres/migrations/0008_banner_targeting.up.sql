@@ -0,0 +1,4 @@
+-- Ограничения показа баннера в слоте:
+-- {"Countries": ["RU"], "Devices": ["mobile"], "OS": ["android"]}.
+-- NULL - без ограничений. Удаляются вместе со связью баннера и слота.
+ALTER TABLE banner_slots ADD COLUMN targeting JSONB;
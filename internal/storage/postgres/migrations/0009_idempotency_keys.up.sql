@@ -0,0 +1,12 @@
+-- Ключи идемпотентности запросов. status, content_type и body пусты,
+-- пока первый запрос с ключом выполняется.
+CREATE TABLE idempotency_keys (
+    key TEXT PRIMARY KEY,
+    fingerprint TEXT NOT NULL,
+    status INT,
+    content_type TEXT,
+    body BYTEA,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
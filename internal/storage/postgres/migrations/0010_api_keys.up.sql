@@ -0,0 +1,10 @@
+-- API-ключи. Хранится только SHA-256 ключа, сам ключ показывается один раз
+-- при создании. Отозванные ключи остаются для истории.
+CREATE TABLE api_keys (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMPTZ
+);
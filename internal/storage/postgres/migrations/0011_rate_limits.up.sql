@@ -0,0 +1,10 @@
+-- Корзины токенов для ограничения частоты запросов. expires_at - момент,
+-- к которому корзина заведомо заполнится и строку можно удалить.
+CREATE TABLE rate_limits (
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_rate_limits_expires_at ON rate_limits (expires_at);
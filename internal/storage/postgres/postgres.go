@@ -3,15 +3,26 @@ package postgres
 import (
 	"banner-rotation/internal/storage"
 	"context"
+	"errors"
 	"fmt"
-	"sync"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// foreignKeyViolation - код ошибки PostgreSQL при нарушении внешнего ключа
+const foreignKeyViolation = "23503"
+
+var _ storage.BatchRecorder = (*PostgresStorage)(nil)
+
+// PostgresStorage - хранилище на PostgreSQL. Синхронизация не нужна:
+// pgxpool безопасен для конкурентного использования, а каждая операция
+// выполняется одним атомарным запросом или транзакцией.
 type PostgresStorage struct {
 	db *pgxpool.Pool
-	mu sync.RWMutex
 }
 
 func New(connString string) (*PostgresStorage, error) {
@@ -29,36 +40,26 @@ func New(connString string) (*PostgresStorage, error) {
 }
 
 func (s *PostgresStorage) AddBannerToSlot(ctx context.Context, slotID, bannerID int) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	// Проверка существования баннера и слота
-	var exists bool
-	err := s.db.QueryRow(ctx,
-		`SELECT EXISTS(SELECT 1 FROM banners WHERE id = $1)`, bannerID).Scan(&exists)
-	if err != nil || !exists {
-		return fmt.Errorf("banner not found: %w", err)
-	}
-
-	err = s.db.QueryRow(ctx,
-		`SELECT EXISTS(SELECT 1 FROM slots WHERE id = $1)`, slotID).Scan(&exists)
-	if err != nil || !exists {
-		return fmt.Errorf("slot not found: %w", err)
-	}
-
-	_, err = s.db.Exec(ctx, `
+	// Существование баннера и слота проверяют внешние ключи banner_slots,
+	// поэтому проверка и вставка атомарны и занимают один запрос
+	_, err := s.db.Exec(ctx, `
         INSERT INTO banner_slots (slot_id, banner_id)
         VALUES ($1, $2)
         ON CONFLICT (slot_id, banner_id) DO NOTHING`,
 		slotID, bannerID,
 	)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		if strings.Contains(pgErr.ConstraintName, "banner_id") {
+			return fmt.Errorf("banner %d: %w", bannerID, storage.ErrNotFound)
+		}
+		return fmt.Errorf("slot %d: %w", slotID, storage.ErrNotFound)
+	}
 	return err
 }
 
 func (s *PostgresStorage) RemoveBannerFromSlot(ctx context.Context, slotID, bannerID int) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	_, err := s.db.Exec(ctx, `
 		DELETE FROM banner_slots 
 		WHERE slot_id = $1 AND banner_id = $2`,
@@ -68,40 +69,175 @@ func (s *PostgresStorage) RemoveBannerFromSlot(ctx context.Context, slotID, bann
 	return err
 }
 
-func (s *PostgresStorage) RecordShow(ctx context.Context, slotID, bannerID, groupID int) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// UpdateSlotRotation применяет изменение одной транзакцией. Статистику
+// удаленных баннеров удаляет ON DELETE CASCADE, существование добавляемых
+// баннеров и слота проверяют внешние ключи banner_slots.
+func (s *PostgresStorage) UpdateSlotRotation(ctx context.Context, slotID int, change storage.RotationChange) error {
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var err error
+		switch {
+		case change.Replace:
+			_, err = tx.Exec(ctx, `
+				DELETE FROM banner_slots
+				WHERE slot_id = $1 AND NOT (banner_id = ANY($2::int[]))`,
+				slotID, nonNilIDs(change.Add),
+			)
+		case len(change.Remove) > 0:
+			_, err = tx.Exec(ctx, `
+				DELETE FROM banner_slots
+				WHERE slot_id = $1 AND banner_id = ANY($2::int[])`,
+				slotID, change.Remove,
+			)
+		}
+		if err != nil || len(change.Add) == 0 {
+			return err
+		}
 
-	_, err := s.db.Exec(ctx, `
-		INSERT INTO statistics (slot_id, banner_id, group_id, shows)
-		VALUES ($1, $2, $3, 1)
-		ON CONFLICT (slot_id, banner_id, group_id)
-		DO UPDATE SET shows = statistics.shows + 1`,
-		slotID, bannerID, groupID,
-	)
+		_, err = tx.Exec(ctx, `
+			INSERT INTO banner_slots (slot_id, banner_id)
+			SELECT $1, unnest($2::int[])
+			ON CONFLICT (slot_id, banner_id) DO NOTHING`,
+			slotID, change.Add,
+		)
+		return err
+	})
 
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return fmt.Errorf("failed to update rotation of slot %d: %s: %w", slotID, pgErr.Detail, storage.ErrNotFound)
+	}
 	return err
 }
 
-func (s *PostgresStorage) RecordClick(ctx context.Context, slotID, bannerID, groupID int) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// nonNilIDs заменяет nil пустым срезом: nil передается в запрос как NULL,
+// а "banner_id = ANY(NULL)" не истинно и не ложно
+func nonNilIDs(ids []int) []int {
+	if ids == nil {
+		return []int{}
+	}
+	return ids
+}
 
-	_, err := s.db.Exec(ctx, `
-		INSERT INTO statistics (slot_id, banner_id, group_id, clicks)
-		VALUES ($1, $2, $3, 1)
+func (s *PostgresStorage) SetBannerTargeting(ctx context.Context, slotID, bannerID int, targeting storage.Targeting) error {
+	var value *storage.Targeting
+	if !targeting.IsZero() {
+		value = &targeting
+	}
+
+	tag, err := s.db.Exec(ctx, `
+		UPDATE banner_slots SET targeting = $3
+		WHERE slot_id = $1 AND banner_id = $2`,
+		slotID, bannerID, value,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set targeting: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("banner %d is not in slot %d: %w", bannerID, slotID, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *PostgresStorage) GetSlotTargeting(ctx context.Context, slotID int) (map[int]storage.Targeting, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT banner_id, targeting
+		FROM banner_slots
+		WHERE slot_id = $1 AND targeting IS NOT NULL`,
+		slotID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query targeting: %w", err)
+	}
+	defer rows.Close()
+
+	targeting := make(map[int]storage.Targeting)
+	for rows.Next() {
+		var bannerID int
+		var t storage.Targeting
+		if err := rows.Scan(&bannerID, &t); err != nil {
+			return nil, fmt.Errorf("failed to scan targeting: %w", err)
+		}
+		targeting[bannerID] = t
+	}
+	return targeting, rows.Err()
+}
+
+// recordStatQuery увеличивает общие счетчики и счетчики часовой корзины
+// одним запросом: $4 - начало корзины, $5 - показы, $6 - клики
+const recordStatQuery = `
+	WITH lifetime AS (
+		INSERT INTO statistics (slot_id, banner_id, group_id, shows, clicks)
+		VALUES ($1, $2, $3, $5, $6)
 		ON CONFLICT (slot_id, banner_id, group_id)
-		DO UPDATE SET clicks = statistics.clicks + 1`,
-		slotID, bannerID, groupID,
+		DO UPDATE SET shows = statistics.shows + EXCLUDED.shows,
+		              clicks = statistics.clicks + EXCLUDED.clicks
+	)
+	INSERT INTO statistics_hourly (slot_id, banner_id, group_id, bucket, shows, clicks)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (slot_id, group_id, bucket, banner_id)
+	DO UPDATE SET shows = statistics_hourly.shows + EXCLUDED.shows,
+	              clicks = statistics_hourly.clicks + EXCLUDED.clicks`
+
+func (s *PostgresStorage) RecordShow(ctx context.Context, slotID, bannerID, groupID int) error {
+	_, err := s.db.Exec(ctx, recordStatQuery,
+		slotID, bannerID, groupID, storage.HourBucket(time.Now()), 1, 0,
+	)
+
+	return recordError(err, slotID, bannerID, groupID)
+}
+
+func (s *PostgresStorage) RecordClick(ctx context.Context, slotID, bannerID, groupID int) error {
+	_, err := s.db.Exec(ctx, recordStatQuery,
+		slotID, bannerID, groupID, storage.HourBucket(time.Now()), 0, 1,
 	)
 
+	return recordError(err, slotID, bannerID, groupID)
+}
+
+// recordError возвращает ErrNotFound, если статистика ссылается на баннер
+// вне ротации слота или на несуществующую группу
+func recordError(err error, slotID, bannerID, groupID int) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return fmt.Errorf("banner %d in slot %d, group %d: %s: %w", bannerID, slotID, groupID, pgErr.Detail, storage.ErrNotFound)
+	}
 	return err
 }
 
-func (s *PostgresStorage) GetBannerStats(ctx context.Context, slotID, groupID int) ([]storage.BannerStat, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// RecordBatch применяет приращения счетчиков одной транзакцией через pgx.Batch
+func (s *PostgresStorage) RecordBatch(ctx context.Context, deltas []storage.StatDelta) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	now := time.Now()
+	batch := &pgx.Batch{}
+	for _, d := range deltas {
+		hour := d.Hour
+		if hour.IsZero() {
+			hour = now
+		}
+		batch.Queue(recordStatQuery,
+			d.SlotID, d.BannerID, d.GroupID, storage.HourBucket(hour), d.Shows, d.Clicks,
+		)
+	}
 
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to send batch: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *PostgresStorage) GetBannerStats(ctx context.Context, slotID, groupID int) ([]storage.BannerStat, error) {
 	rows, err := s.db.Query(ctx, `
 		SELECT banner_id, shows, clicks
 		FROM statistics
@@ -126,15 +262,82 @@ func (s *PostgresStorage) GetBannerStats(ctx context.Context, slotID, groupID in
 	return stats, nil
 }
 
+func (s *PostgresStorage) GetBannerStatsRange(ctx context.Context, slotID, groupID int, from, to time.Time) ([]storage.BannerStat, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT banner_id, SUM(shows), SUM(clicks)
+		FROM (
+			SELECT banner_id, shows, clicks
+			FROM statistics_hourly
+			WHERE slot_id = $1 AND group_id = $2 AND bucket >= $3 AND bucket < $4
+			UNION ALL
+			SELECT banner_id, shows, clicks
+			FROM statistics_daily
+			WHERE slot_id = $1 AND group_id = $2 AND bucket >= $3 AND bucket < $4
+		) buckets
+		GROUP BY banner_id
+		ORDER BY banner_id`,
+		slotID, groupID, from, to,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stats range: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []storage.BannerStat
+	for rows.Next() {
+		var stat storage.BannerStat
+		if err := rows.Scan(&stat.BannerID, &stat.Shows, &stat.Clicks); err != nil {
+			return nil, fmt.Errorf("failed to scan stats: %w", err)
+		}
+		stats = append(stats, stat)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return stats, nil
+}
+
+// RollupHourlyStats переносит часовые корзины в дневные одним запросом,
+// поэтому параллельные показы не теряются и не учитываются дважды
+func (s *PostgresStorage) RollupHourlyStats(ctx context.Context, before time.Time) error {
+	_, err := s.db.Exec(ctx, `
+		WITH moved AS (
+			DELETE FROM statistics_hourly
+			WHERE bucket < $1
+			RETURNING slot_id, banner_id, group_id, bucket, shows, clicks
+		)
+		INSERT INTO statistics_daily (slot_id, banner_id, group_id, bucket, shows, clicks)
+		SELECT slot_id, banner_id, group_id, date_trunc('day', bucket, 'UTC'), SUM(shows), SUM(clicks)
+		FROM moved
+		GROUP BY slot_id, banner_id, group_id, date_trunc('day', bucket, 'UTC')
+		ON CONFLICT (slot_id, group_id, bucket, banner_id)
+		DO UPDATE SET shows = statistics_daily.shows + EXCLUDED.shows,
+		              clicks = statistics_daily.clicks + EXCLUDED.clicks`,
+		before,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to roll up hourly stats: %w", err)
+	}
+
+	return nil
+}
+
+// Ping проверяет соединение с базой
+func (s *PostgresStorage) Ping(ctx context.Context) error {
+	if err := s.db.Ping(ctx); err != nil {
+		return fmt.Errorf("failed to ping postgres: %w", err)
+	}
+	return nil
+}
+
 func (s *PostgresStorage) Close() error {
 	s.db.Close()
 	return nil
 }
 
 func (s *PostgresStorage) GetBannersForSlot(ctx context.Context, slotID int) ([]int, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
 	rows, err := s.db.Query(ctx, `
         SELECT banner_id 
         FROM banner_slots
@@ -0,0 +1,222 @@
+package postgres
+
+import (
+	"banner-rotation/internal/app"
+	"banner-rotation/internal/storage"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Бенчмарк работает в собственной схеме, которая удаляется после прогона,
+// поэтому ID не пересекаются с данными базы DB_URL
+const (
+	benchSlotID  = 1
+	benchGroupID = 1
+	benchBanners = 100
+)
+
+// serializedStorage воспроизводит прежнее поведение PostgresStorage,
+// где запросы выполнялись под общим RWMutex: записи под Lock, чтения под RLock
+type serializedStorage struct {
+	storage.Storage
+	mu sync.RWMutex
+}
+
+func (s *serializedStorage) AddBannerToSlot(ctx context.Context, slotID, bannerID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Storage.AddBannerToSlot(ctx, slotID, bannerID)
+}
+
+func (s *serializedStorage) RemoveBannerFromSlot(ctx context.Context, slotID, bannerID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Storage.RemoveBannerFromSlot(ctx, slotID, bannerID)
+}
+
+func (s *serializedStorage) RecordShow(ctx context.Context, slotID, bannerID, groupID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Storage.RecordShow(ctx, slotID, bannerID, groupID)
+}
+
+func (s *serializedStorage) RecordClick(ctx context.Context, slotID, bannerID, groupID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Storage.RecordClick(ctx, slotID, bannerID, groupID)
+}
+
+func (s *serializedStorage) GetBannerStats(ctx context.Context, slotID, groupID int) ([]storage.BannerStat, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Storage.GetBannerStats(ctx, slotID, groupID)
+}
+
+func (s *serializedStorage) GetBannersForSlot(ctx context.Context, slotID int) ([]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Storage.GetBannersForSlot(ctx, slotID)
+}
+
+// newSchemaStorage создает пустую временную схему в базе DB_URL и удаляет
+// ее после теста
+func newSchemaStorage(tb testing.TB) *PostgresStorage {
+	dbURL := os.Getenv("DB_URL")
+	if dbURL == "" {
+		tb.Skip("DB_URL is not set")
+	}
+
+	ctx := context.Background()
+	admin, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(admin.Close)
+
+	schema := pgx.Identifier{fmt.Sprintf("test_%d", time.Now().UnixNano())}.Sanitize()
+	if _, err := admin.Exec(ctx, `CREATE SCHEMA `+schema); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		if _, err := admin.Exec(ctx, `DROP SCHEMA `+schema+` CASCADE`); err != nil {
+			tb.Errorf("failed to drop test schema: %v", err)
+		}
+	})
+
+	config, err := pgxpool.ParseConfig(dbURL)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	config.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	store := &PostgresStorage{db: pool}
+	tb.Cleanup(func() { store.Close() })
+	return store
+}
+
+// newBenchStorage создает временную схему с миграциями и баннерами бенчмарка
+func newBenchStorage(b *testing.B) *PostgresStorage {
+	ctx := context.Background()
+	store := newSchemaStorage(b)
+
+	if err := store.Migrate(ctx); err != nil {
+		b.Fatal(err)
+	}
+	if _, err := store.CreateSlot(ctx, storage.Slot{ID: benchSlotID, Description: "bench"}); err != nil {
+		b.Fatal(err)
+	}
+	if _, err := store.CreateGroup(ctx, storage.Group{ID: benchGroupID, Description: "bench"}); err != nil {
+		b.Fatal(err)
+	}
+
+	// Сценарий TestBandit_Performance: 100 баннеров в одном слоте
+	for bannerID := 1; bannerID <= benchBanners; bannerID++ {
+		if _, err := store.CreateBanner(ctx, storage.Banner{ID: bannerID, Description: "bench"}); err != nil {
+			b.Fatal(err)
+		}
+		if err := store.AddBannerToSlot(ctx, benchSlotID, bannerID); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	return store
+}
+
+// BenchmarkChooseBanner сравнивает пропускную способность ChooseBanner
+// при параллельных запросах с общим мьютексом и без него:
+//
+//	DB_URL=postgres://... go test -run=^$ -bench=ChooseBanner -cpu=1,8 ./internal/storage/postgres
+func BenchmarkChooseBanner(b *testing.B) {
+	store := newBenchStorage(b)
+
+	cases := []struct {
+		name  string
+		store storage.Storage
+	}{
+		{"serialized", &serializedStorage{Storage: store}},
+		{"parallel", store},
+	}
+
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			bandit := app.NewBandit(tc.store, nil)
+			ctx := context.Background()
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := bandit.ChooseBanner(ctx, benchSlotID, benchGroupID, storage.Visitor{}); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no embedded migrations")
+	}
+
+	for i, m := range migrations {
+		if i > 0 && m.Version <= migrations[i-1].Version {
+			t.Errorf("migration %d_%s is out of order", m.Version, m.Name)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			t.Errorf("migration %d_%s has empty up or down", m.Version, m.Name)
+		}
+	}
+}
+
+func TestMigrationStatus(t *testing.T) {
+	ctx := context.Background()
+	store := newSchemaStorage(t)
+
+	status, err := store.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range status {
+		if st.AppliedAt != nil {
+			t.Errorf("migration %d_%s is applied in empty schema", st.Version, st.Name)
+		}
+	}
+
+	var exists bool
+	if err := store.db.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Error("status created schema_migrations")
+	}
+
+	if err := store.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	status, err = store.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range status {
+		if st.AppliedAt == nil {
+			t.Errorf("migration %d_%s is pending after migrate", st.Version, st.Name)
+		}
+	}
+}
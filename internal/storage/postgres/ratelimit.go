@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"banner-rotation/internal/ratelimit"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var _ ratelimit.Limiter = (*RateLimiter)(nil)
+
+// RateLimiter - корзины токенов в PostgreSQL, общие для всех экземпляров
+// сервиса. Использует пул соединений хранилища.
+type RateLimiter struct {
+	db *pgxpool.Pool
+
+	mu        sync.Mutex
+	lastPurge time.Time
+}
+
+func NewRateLimiter(store *PostgresStorage) *RateLimiter {
+	return &RateLimiter{db: store.db}
+}
+
+// Allow списывает токен одним запросом: строка обновляется, только если
+// в пополненной корзине есть целый токен, иначе читается ее состояние
+func (l *RateLimiter) Allow(ctx context.Context, key string, limit ratelimit.Limit) (bool, time.Duration, error) {
+	l.purge(ctx)
+
+	rate, burst := limit.Rate, float64(limit.Burst)
+	var allowed bool
+	err := l.db.QueryRow(ctx, `
+        INSERT INTO rate_limits AS r (key, tokens, updated_at, expires_at)
+        VALUES ($1, $3::float8 - 1, now(), now() + make_interval(secs => $3::float8 / $2::float8))
+        ON CONFLICT (key) DO UPDATE
+        SET tokens = LEAST($3::float8, r.tokens + $2::float8 * EXTRACT(EPOCH FROM now() - r.updated_at)::float8) - 1,
+            updated_at = now(), expires_at = EXCLUDED.expires_at
+        WHERE LEAST($3::float8, r.tokens + $2::float8 * EXTRACT(EPOCH FROM now() - r.updated_at)::float8) >= 1
+        RETURNING true`,
+		key, rate, burst,
+	).Scan(&allowed)
+	if err == nil {
+		return true, 0, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return false, 0, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+
+	var tokens float64
+	err = l.db.QueryRow(ctx, `
+        SELECT LEAST($3::float8, tokens + $2::float8 * EXTRACT(EPOCH FROM now() - updated_at)::float8)
+        FROM rate_limits WHERE key = $1`,
+		key, rate, burst,
+	).Scan(&tokens)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Строку только что удалила очистка: корзина уже полна
+		return true, 0, nil
+	}
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to read rate limit: %w", err)
+	}
+	return false, limit.Wait(tokens), nil
+}
+
+// purge удаляет заполнившиеся корзины не чаще раза в purgeInterval
+func (l *RateLimiter) purge(ctx context.Context) {
+	l.mu.Lock()
+	if time.Since(l.lastPurge) < purgeInterval {
+		l.mu.Unlock()
+		return
+	}
+	l.lastPurge = time.Now()
+	l.mu.Unlock()
+
+	if _, err := l.db.Exec(ctx, `DELETE FROM rate_limits WHERE expires_at <= now()`); err != nil {
+		log.Printf("Failed to purge rate limits: %v", err)
+	}
+}
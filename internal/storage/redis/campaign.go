@@ -0,0 +1,133 @@
+package redis
+
+import (
+	"banner-rotation/internal/storage"
+	"context"
+	"encoding/json"
+)
+
+func (s *RedisStorage) CreateAdvertiser(ctx context.Context, advertiser storage.Advertiser) (storage.Advertiser, error) {
+	id, err := s.createEntity(ctx, "advertisers", advertiser.ID, advertiser.Name, "")
+	if err != nil {
+		return storage.Advertiser{}, err
+	}
+	advertiser.ID = id
+	return advertiser, nil
+}
+
+func (s *RedisStorage) GetAdvertiser(ctx context.Context, id int) (storage.Advertiser, error) {
+	name, _, err := s.getEntity(ctx, "advertisers", id)
+	if err != nil {
+		return storage.Advertiser{}, err
+	}
+	return storage.Advertiser{ID: id, Name: name}, nil
+}
+
+func (s *RedisStorage) ListAdvertisers(ctx context.Context) ([]storage.Advertiser, error) {
+	var advertisers []storage.Advertiser
+	err := s.listEntities(ctx, "advertisers", func(id int, name, _ string) error {
+		advertisers = append(advertisers, storage.Advertiser{ID: id, Name: name})
+		return nil
+	})
+	return advertisers, err
+}
+
+func (s *RedisStorage) UpdateAdvertiser(ctx context.Context, advertiser storage.Advertiser) error {
+	return s.updateEntity(ctx, "advertisers", advertiser.ID, advertiser.Name, "")
+}
+
+// DeleteAdvertiser удаляет рекламодателя, если у него нет кампаний
+func (s *RedisStorage) DeleteAdvertiser(ctx context.Context, id int) error {
+	inUse, err := s.referencedBy(ctx, "campaigns", id, func(attrs string) int {
+		var campaign storage.Campaign
+		_ = json.Unmarshal([]byte(attrs), &campaign)
+		return campaign.AdvertiserID
+	})
+	if err != nil {
+		return err
+	}
+	return s.deleteEntity(ctx, "advertisers", id, inUse)
+}
+
+// Кампания хранится целиком в атрибутах записи, имя - в основном hash справочника
+
+func (s *RedisStorage) CreateCampaign(ctx context.Context, campaign storage.Campaign) (storage.Campaign, error) {
+	if err := s.checkExists(ctx, "advertisers", campaign.AdvertiserID); err != nil {
+		return storage.Campaign{}, err
+	}
+	attrs, err := encodeAttrs(campaign, false)
+	if err != nil {
+		return storage.Campaign{}, err
+	}
+	id, err := s.createEntity(ctx, "campaigns", campaign.ID, campaign.Name, attrs)
+	if err != nil {
+		return storage.Campaign{}, err
+	}
+	campaign.ID = id
+	return campaign, nil
+}
+
+func (s *RedisStorage) GetCampaign(ctx context.Context, id int) (storage.Campaign, error) {
+	name, attrs, err := s.getEntity(ctx, "campaigns", id)
+	if err != nil {
+		return storage.Campaign{}, err
+	}
+	var campaign storage.Campaign
+	if err := decodeAttrs("campaigns", id, attrs, &campaign); err != nil {
+		return storage.Campaign{}, err
+	}
+	campaign.ID, campaign.Name = id, name
+	return campaign, nil
+}
+
+func (s *RedisStorage) GetCampaigns(ctx context.Context, ids []int) ([]storage.Campaign, error) {
+	var campaigns []storage.Campaign
+	err := s.someEntities(ctx, "campaigns", ids, func(id int, name, attrs string) error {
+		var campaign storage.Campaign
+		if err := decodeAttrs("campaigns", id, attrs, &campaign); err != nil {
+			return err
+		}
+		campaign.ID, campaign.Name = id, name
+		campaigns = append(campaigns, campaign)
+		return nil
+	})
+	return campaigns, err
+}
+
+func (s *RedisStorage) ListCampaigns(ctx context.Context) ([]storage.Campaign, error) {
+	var campaigns []storage.Campaign
+	err := s.listEntities(ctx, "campaigns", func(id int, name, attrs string) error {
+		var campaign storage.Campaign
+		if err := decodeAttrs("campaigns", id, attrs, &campaign); err != nil {
+			return err
+		}
+		campaign.ID, campaign.Name = id, name
+		campaigns = append(campaigns, campaign)
+		return nil
+	})
+	return campaigns, err
+}
+
+func (s *RedisStorage) UpdateCampaign(ctx context.Context, campaign storage.Campaign) error {
+	if err := s.checkExists(ctx, "advertisers", campaign.AdvertiserID); err != nil {
+		return err
+	}
+	attrs, err := encodeAttrs(campaign, false)
+	if err != nil {
+		return err
+	}
+	return s.updateEntity(ctx, "campaigns", campaign.ID, campaign.Name, attrs)
+}
+
+// DeleteCampaign удаляет кампанию, если в ней нет баннеров
+func (s *RedisStorage) DeleteCampaign(ctx context.Context, id int) error {
+	inUse, err := s.referencedBy(ctx, "banners", id, func(attrs string) int {
+		var decoded bannerAttrs
+		_ = json.Unmarshal([]byte(attrs), &decoded)
+		return decoded.CampaignID
+	})
+	if err != nil {
+		return err
+	}
+	return s.deleteEntity(ctx, "campaigns", id, inUse)
+}
@@ -0,0 +1,474 @@
+package redis
+
+import (
+	"banner-rotation/internal/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Справочники хранятся в hash rotation:catalog:<name> (id -> description),
+// последний выданный ID - в rotation:catalog:<name>:seq, дополнительные
+// атрибуты записи (креатив баннера, размеры слота) в JSON - в rotation:catalog:<name>:attrs.
+func catalogKey(name string) string {
+	return "rotation:catalog:" + name
+}
+
+func catalogSeqKey(name string) string {
+	return catalogKey(name) + ":seq"
+}
+
+func catalogAttrsKey(name string) string {
+	return catalogKey(name) + ":attrs"
+}
+
+// createEntityScript сохраняет запись справочника. ARGV[1] = 0 выдает новый ID,
+// иначе используется переданный; -1 означает, что такой ID уже занят.
+var createEntityScript = goredis.NewScript(`
+local id = tonumber(ARGV[1])
+if id == 0 then
+  repeat
+    id = redis.call('INCR', KEYS[2])
+  until redis.call('HEXISTS', KEYS[1], id) == 0
+else
+  if redis.call('HEXISTS', KEYS[1], id) == 1 then
+    return -1
+  end
+  if id > tonumber(redis.call('GET', KEYS[2]) or '0') then
+    redis.call('SET', KEYS[2], id)
+  end
+end
+redis.call('HSET', KEYS[1], id, ARGV[2])
+if ARGV[3] == '' then
+  redis.call('HDEL', KEYS[3], id)
+else
+  redis.call('HSET', KEYS[3], id, ARGV[3])
+end
+return id
+`)
+
+// updateEntityScript обновляет описание и атрибуты существующей записи, 0 - записи нет
+var updateEntityScript = goredis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 0 then
+  return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+if ARGV[3] == '' then
+  redis.call('HDEL', KEYS[2], ARGV[1])
+else
+  redis.call('HSET', KEYS[2], ARGV[1], ARGV[3])
+end
+return 1
+`)
+
+// bannerAttrs - атрибуты баннера. Креатив встроен, поэтому атрибуты,
+// где хранился только креатив, читаются как есть.
+type bannerAttrs struct {
+	storage.Creative
+	CampaignID int `json:",omitempty"`
+}
+
+func (s *RedisStorage) encodeBannerAttrs(ctx context.Context, banner storage.Banner) (string, error) {
+	if banner.CampaignID != 0 {
+		if err := s.checkExists(ctx, "campaigns", banner.CampaignID); err != nil {
+			return "", err
+		}
+	}
+	attrs := bannerAttrs{Creative: banner.Creative, CampaignID: banner.CampaignID}
+	return encodeAttrs(attrs, attrs == bannerAttrs{})
+}
+
+func decodeBannerAttrs(attrs string, banner *storage.Banner) error {
+	var decoded bannerAttrs
+	if err := decodeAttrs("banners", banner.ID, attrs, &decoded); err != nil {
+		return err
+	}
+	banner.Creative = decoded.Creative
+	banner.CampaignID = decoded.CampaignID
+	return nil
+}
+
+func (s *RedisStorage) CreateBanner(ctx context.Context, banner storage.Banner) (storage.Banner, error) {
+	attrs, err := s.encodeBannerAttrs(ctx, banner)
+	if err != nil {
+		return storage.Banner{}, err
+	}
+	id, err := s.createEntity(ctx, "banners", banner.ID, banner.Description, attrs)
+	if err != nil {
+		return storage.Banner{}, err
+	}
+	banner.ID = id
+	return banner, nil
+}
+
+func (s *RedisStorage) GetBanner(ctx context.Context, id int) (storage.Banner, error) {
+	description, attrs, err := s.getEntity(ctx, "banners", id)
+	if err != nil {
+		return storage.Banner{}, err
+	}
+	banner := storage.Banner{ID: id, Description: description}
+	if err := decodeBannerAttrs(attrs, &banner); err != nil {
+		return storage.Banner{}, err
+	}
+	return banner, nil
+}
+
+func (s *RedisStorage) GetBanners(ctx context.Context, ids []int) ([]storage.Banner, error) {
+	var banners []storage.Banner
+	err := s.someEntities(ctx, "banners", ids, func(id int, description, attrs string) error {
+		banner := storage.Banner{ID: id, Description: description}
+		if err := decodeBannerAttrs(attrs, &banner); err != nil {
+			return err
+		}
+		banners = append(banners, banner)
+		return nil
+	})
+	return banners, err
+}
+
+func (s *RedisStorage) ListBanners(ctx context.Context) ([]storage.Banner, error) {
+	var banners []storage.Banner
+	err := s.listEntities(ctx, "banners", func(id int, description, attrs string) error {
+		banner := storage.Banner{ID: id, Description: description}
+		if err := decodeBannerAttrs(attrs, &banner); err != nil {
+			return err
+		}
+		banners = append(banners, banner)
+		return nil
+	})
+	return banners, err
+}
+
+func (s *RedisStorage) UpdateBanner(ctx context.Context, banner storage.Banner) error {
+	attrs, err := s.encodeBannerAttrs(ctx, banner)
+	if err != nil {
+		return err
+	}
+	return s.updateEntity(ctx, "banners", banner.ID, banner.Description, attrs)
+}
+
+// DeleteBanner удаляет баннер, если его нет в ротации ни одного слота.
+// Проверка и удаление не атомарны: слоты лежат в разных hash slot кластера.
+func (s *RedisStorage) DeleteBanner(ctx context.Context, id int) error {
+	inUse, err := s.memberOfAny(ctx, "rotation:{*}:banners", id)
+	if err != nil {
+		return err
+	}
+	return s.deleteEntity(ctx, "banners", id, inUse)
+}
+
+func (s *RedisStorage) CreateSlot(ctx context.Context, slot storage.Slot) (storage.Slot, error) {
+	attrs, err := encodeAttrs(slot.Sizes, len(slot.Sizes) == 0)
+	if err != nil {
+		return storage.Slot{}, err
+	}
+	id, err := s.createEntity(ctx, "slots", slot.ID, slot.Description, attrs)
+	if err != nil {
+		return storage.Slot{}, err
+	}
+	slot.ID = id
+	return slot, nil
+}
+
+func (s *RedisStorage) GetSlot(ctx context.Context, id int) (storage.Slot, error) {
+	description, attrs, err := s.getEntity(ctx, "slots", id)
+	if err != nil {
+		return storage.Slot{}, err
+	}
+	slot := storage.Slot{ID: id, Description: description}
+	if err := decodeAttrs("slots", id, attrs, &slot.Sizes); err != nil {
+		return storage.Slot{}, err
+	}
+	return slot, nil
+}
+
+func (s *RedisStorage) ListSlots(ctx context.Context) ([]storage.Slot, error) {
+	var slots []storage.Slot
+	err := s.listEntities(ctx, "slots", func(id int, description, attrs string) error {
+		slot := storage.Slot{ID: id, Description: description}
+		if err := decodeAttrs("slots", id, attrs, &slot.Sizes); err != nil {
+			return err
+		}
+		slots = append(slots, slot)
+		return nil
+	})
+	return slots, err
+}
+
+func (s *RedisStorage) UpdateSlot(ctx context.Context, slot storage.Slot) error {
+	attrs, err := encodeAttrs(slot.Sizes, len(slot.Sizes) == 0)
+	if err != nil {
+		return err
+	}
+	return s.updateEntity(ctx, "slots", slot.ID, slot.Description, attrs)
+}
+
+// DeleteSlot удаляет слот, если в его ротации нет баннеров
+func (s *RedisStorage) DeleteSlot(ctx context.Context, id int) error {
+	n, err := s.client.SCard(ctx, bannersKey(id)).Result()
+	if err != nil {
+		return err
+	}
+	return s.deleteEntity(ctx, "slots", id, n > 0)
+}
+
+func (s *RedisStorage) CreateGroup(ctx context.Context, group storage.Group) (storage.Group, error) {
+	attrs, err := encodeAttrs(group.Rule, group.Rule == nil)
+	if err != nil {
+		return storage.Group{}, err
+	}
+	id, err := s.createEntity(ctx, "groups", group.ID, group.Description, attrs)
+	if err != nil {
+		return storage.Group{}, err
+	}
+	group.ID = id
+	return group, nil
+}
+
+func (s *RedisStorage) GetGroup(ctx context.Context, id int) (storage.Group, error) {
+	description, attrs, err := s.getEntity(ctx, "groups", id)
+	if err != nil {
+		return storage.Group{}, err
+	}
+	group := storage.Group{ID: id, Description: description}
+	if err := decodeAttrs("groups", id, attrs, &group.Rule); err != nil {
+		return storage.Group{}, err
+	}
+	return group, nil
+}
+
+func (s *RedisStorage) ListGroups(ctx context.Context) ([]storage.Group, error) {
+	var groups []storage.Group
+	err := s.listEntities(ctx, "groups", func(id int, description, attrs string) error {
+		group := storage.Group{ID: id, Description: description}
+		if err := decodeAttrs("groups", id, attrs, &group.Rule); err != nil {
+			return err
+		}
+		groups = append(groups, group)
+		return nil
+	})
+	return groups, err
+}
+
+func (s *RedisStorage) UpdateGroup(ctx context.Context, group storage.Group) error {
+	attrs, err := encodeAttrs(group.Rule, group.Rule == nil)
+	if err != nil {
+		return err
+	}
+	return s.updateEntity(ctx, "groups", group.ID, group.Description, attrs)
+}
+
+// DeleteGroup удаляет группу, если ни в одном слоте по ней нет статистики
+func (s *RedisStorage) DeleteGroup(ctx context.Context, id int) error {
+	inUse, err := s.memberOfAny(ctx, "rotation:{*}:groups", id)
+	if err != nil {
+		return err
+	}
+	return s.deleteEntity(ctx, "groups", id, inUse)
+}
+
+// checkExists проверяет, что запись справочника заведена. Проверка не атомарна
+// с последующей записью, как и проверки ссылок при удалении.
+func (s *RedisStorage) checkExists(ctx context.Context, name string, id int) error {
+	exists, err := s.client.HExists(ctx, catalogKey(name), strconv.Itoa(id)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check %s: %w", entityName(name), err)
+	}
+	if !exists {
+		return fmt.Errorf("%s %d: %w", entityName(name), id, storage.ErrNotFound)
+	}
+	return nil
+}
+
+// referencedBy проверяет, ссылается ли на id хотя бы одна запись справочника
+// name; ref извлекает ссылку из атрибутов записи
+func (s *RedisStorage) referencedBy(ctx context.Context, name string, id int, ref func(attrs string) int) (bool, error) {
+	found := false
+	err := s.listEntities(ctx, name, func(_ int, _, attrs string) error {
+		if ref(attrs) == id {
+			found = true
+		}
+		return nil
+	})
+	return found, err
+}
+
+// memberOfAny проверяет, входит ли id хотя бы в один set, подходящий под pattern
+func (s *RedisStorage) memberOfAny(ctx context.Context, pattern string, id int) (bool, error) {
+	iter := s.client.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		ok, err := s.client.SIsMember(ctx, iter.Val(), id).Result()
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, iter.Err()
+}
+
+func (s *RedisStorage) createEntity(ctx context.Context, name string, id int, description, attrs string) (int, error) {
+	res, err := createEntityScript.Run(ctx, s.client,
+		[]string{catalogKey(name), catalogSeqKey(name), catalogAttrsKey(name)}, id, description, attrs,
+	).Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", entityName(name), err)
+	}
+	if res == -1 {
+		return 0, fmt.Errorf("%s %d already exists: %w", entityName(name), id, storage.ErrConflict)
+	}
+	return res, nil
+}
+
+func (s *RedisStorage) getEntity(ctx context.Context, name string, id int) (string, string, error) {
+	field := strconv.Itoa(id)
+	var description, attrs *goredis.StringCmd
+	_, err := s.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		description = pipe.HGet(ctx, catalogKey(name), field)
+		attrs = pipe.HGet(ctx, catalogAttrsKey(name), field)
+		return nil
+	})
+	if errors.Is(description.Err(), goredis.Nil) {
+		return "", "", fmt.Errorf("%s %d: %w", entityName(name), id, storage.ErrNotFound)
+	}
+	if err != nil && !errors.Is(err, goredis.Nil) {
+		return "", "", fmt.Errorf("failed to get %s: %w", entityName(name), err)
+	}
+	return description.Val(), attrs.Val(), nil
+}
+
+func (s *RedisStorage) listEntities(ctx context.Context, name string, fn func(id int, description, attrs string) error) error {
+	var items, attrs *goredis.MapStringStringCmd
+	_, err := s.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		items = pipe.HGetAll(ctx, catalogKey(name))
+		attrs = pipe.HGetAll(ctx, catalogAttrsKey(name))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", name, err)
+	}
+
+	ids := make([]int, 0, len(items.Val()))
+	for field := range items.Val() {
+		id, err := strconv.Atoi(field)
+		if err != nil {
+			return fmt.Errorf("invalid %s id %q: %w", name, field, err)
+		}
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		field := strconv.Itoa(id)
+		if err := fn(id, items.Val()[field], attrs.Val()[field]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// someEntities читает записи с ID из ids одним HMGET и вызывает fn по порядку
+// ID для найденных
+func (s *RedisStorage) someEntities(ctx context.Context, name string, ids []int, fn func(id int, description, attrs string) error) error {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	fields := make([]string, len(ids))
+	for i, id := range ids {
+		fields[i] = strconv.Itoa(id)
+	}
+	var items, attrs *goredis.SliceCmd
+	_, err := s.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		items = pipe.HMGet(ctx, catalogKey(name), fields...)
+		attrs = pipe.HMGet(ctx, catalogAttrsKey(name), fields...)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %w", name, err)
+	}
+
+	for i, id := range ids {
+		description, ok := items.Val()[i].(string)
+		if !ok {
+			continue
+		}
+		attr, _ := attrs.Val()[i].(string)
+		if err := fn(id, description, attr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *RedisStorage) updateEntity(ctx context.Context, name string, id int, description, attrs string) error {
+	res, err := updateEntityScript.Run(ctx, s.client,
+		[]string{catalogKey(name), catalogAttrsKey(name)}, id, description, attrs,
+	).Int()
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", entityName(name), err)
+	}
+	if res == 0 {
+		return fmt.Errorf("%s %d: %w", entityName(name), id, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *RedisStorage) deleteEntity(ctx context.Context, name string, id int, inUse bool) error {
+	exists, err := s.client.HExists(ctx, catalogKey(name), strconv.Itoa(id)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", entityName(name), err)
+	}
+	if !exists {
+		return fmt.Errorf("%s %d: %w", entityName(name), id, storage.ErrNotFound)
+	}
+	if inUse {
+		return fmt.Errorf("%s %d is in use: %w", entityName(name), id, storage.ErrConflict)
+	}
+	_, err = s.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.HDel(ctx, catalogKey(name), strconv.Itoa(id))
+		pipe.HDel(ctx, catalogAttrsKey(name), strconv.Itoa(id))
+		return nil
+	})
+	return err
+}
+
+// entityName возвращает имя сущности для сообщений об ошибках
+func entityName(name string) string {
+	return strings.TrimSuffix(name, "s")
+}
+
+// encodeAttrs кодирует дополнительные атрибуты записи в JSON;
+// пустые атрибуты (empty) не хранятся
+func encodeAttrs(attrs any, empty bool) (string, error) {
+	if empty {
+		return "", nil
+	}
+	data, err := json.Marshal(attrs)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode attributes: %w", err)
+	}
+	return string(data), nil
+}
+
+// decodeAttrs разбирает атрибуты записи в v; пустая строка v не меняет
+func decodeAttrs(name string, id int, attrs string, v any) error {
+	if attrs == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(attrs), v); err != nil {
+		return fmt.Errorf("failed to decode attributes of %s %d: %w", entityName(name), id, err)
+	}
+	return nil
+}
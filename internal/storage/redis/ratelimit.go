@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"banner-rotation/internal/ratelimit"
+	"context"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// rateLimitScript списывает токен из корзины KEYS[1] - hash tokens / ts.
+// ARGV - скорость в токенах в секунду, емкость и текущее время в миллисекундах.
+// Возвращает {1, 0}, если токен списан, иначе {0, миллисекунды до следующего токена}.
+// Ключ живет, пока корзина не заполнится снова.
+var rateLimitScript = goredis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+if now > ts then
+  tokens = math.min(burst, tokens + (now - ts) / 1000 * rate)
+end
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate * 1000)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', math.max(now, ts))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000))
+return {allowed, wait}
+`)
+
+var _ ratelimit.Limiter = (*RateLimiter)(nil)
+
+// RateLimiter - корзины токенов в Redis, общие для всех экземпляров
+// сервиса. Использует подключение хранилища.
+type RateLimiter struct {
+	client goredis.UniversalClient
+	now    func() time.Time
+}
+
+func NewRateLimiter(store *RedisStorage) *RateLimiter {
+	return &RateLimiter{client: store.client, now: time.Now}
+}
+
+func (l *RateLimiter) Allow(ctx context.Context, key string, limit ratelimit.Limit) (bool, time.Duration, error) {
+	res, err := rateLimitScript.Run(ctx, l.client, []string{"ratelimit:" + key},
+		limit.Rate, limit.Burst, l.now().UnixMilli(),
+	).Int64Slice()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
@@ -0,0 +1,43 @@
+package redis
+
+import (
+	"banner-rotation/internal/ratelimit"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(newTestStorage(t, 0))
+	limiter.now = func() time.Time { return now }
+	limit := ratelimit.Limit{Rate: 2, Burst: 3}
+
+	for i := 0; i < limit.Burst; i++ {
+		ok, _, err := limiter.Allow(ctx, "a", limit)
+		require.NoError(t, err)
+		assert.True(t, ok, "request %d", i)
+	}
+
+	ok, retryAfter, err := limiter.Allow(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+	ok, _, err = limiter.Allow(ctx, "b", limit)
+	require.NoError(t, err)
+	assert.True(t, ok, "other key has its own bucket")
+
+	now = now.Add(500 * time.Millisecond)
+	ok, _, err = limiter.Allow(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.True(t, ok, "token refilled")
+
+	ok, _, err = limiter.Allow(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
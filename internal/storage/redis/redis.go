@@ -0,0 +1,613 @@
+package redis
+
+import (
+	"banner-rotation/internal/storage"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Все ключи слота содержат hash tag {slotID}, поэтому в Redis Cluster
+// они попадают в один hash slot и Lua-скрипты остаются атомарными.
+// Скрипты получают все ключи, к которым обращаются, через KEYS.
+//
+//   - rotation:{slot}:banners      - set баннеров в ротации
+//   - rotation:{slot}:stats:group  - hash banner:shows / banner:clicks
+//   - rotation:{slot}:totals       - hash banner -> показы по всем группам (для лимита)
+//   - rotation:{slot}:groups       - set групп, по которым есть статистика
+//   - rotation:{slot}:hourly:group - hash banner:bucket:shows / banner:bucket:clicks
+//   - rotation:{slot}:daily:group  - то же для дневных корзин после свертки
+//   - rotation:{slot}:targeting    - hash banner -> ограничения показа в JSON
+func bannersKey(slotID int) string {
+	return fmt.Sprintf("rotation:{%d}:banners", slotID)
+}
+
+func statsPrefix(slotID int) string {
+	return fmt.Sprintf("rotation:{%d}:stats:", slotID)
+}
+
+func statsKey(slotID, groupID int) string {
+	return statsPrefix(slotID) + strconv.Itoa(groupID)
+}
+
+func hourlyPrefix(slotID int) string {
+	return fmt.Sprintf("rotation:{%d}:hourly:", slotID)
+}
+
+func dailyPrefix(slotID int) string {
+	return fmt.Sprintf("rotation:{%d}:daily:", slotID)
+}
+
+func totalsKey(slotID int) string {
+	return fmt.Sprintf("rotation:{%d}:totals", slotID)
+}
+
+func groupsKey(slotID int) string {
+	return fmt.Sprintf("rotation:{%d}:groups", slotID)
+}
+
+func targetingKey(slotID int) string {
+	return fmt.Sprintf("rotation:{%d}:targeting", slotID)
+}
+
+// recordShowScript атомарно проверяет, что баннер в ротации и не исчерпал
+// лимит показов, и увеличивает счетчики. Возвращает -1, если баннера нет
+// в слоте, 0 при достижении лимита и 1 при успехе.
+var recordShowScript = goredis.NewScript(`
+if redis.call('SISMEMBER', KEYS[1], ARGV[1]) == 0 then
+  return -1
+end
+local cap = tonumber(ARGV[3])
+if cap > 0 then
+  local total = tonumber(redis.call('HGET', KEYS[3], ARGV[1]) or '0')
+  if total >= cap then
+    return 0
+  end
+end
+redis.call('HINCRBY', KEYS[3], ARGV[1], 1)
+redis.call('HINCRBY', KEYS[2], ARGV[1] .. ':shows', 1)
+redis.call('HINCRBY', KEYS[5], ARGV[1] .. ':' .. ARGV[4] .. ':shows', 1)
+redis.call('SADD', KEYS[4], ARGV[2])
+return 1
+`)
+
+// recordClickScript увеличивает счетчик кликов, если баннер в ротации
+var recordClickScript = goredis.NewScript(`
+if redis.call('SISMEMBER', KEYS[1], ARGV[1]) == 0 then
+  return -1
+end
+redis.call('HINCRBY', KEYS[2], ARGV[1] .. ':clicks', 1)
+redis.call('HINCRBY', KEYS[4], ARGV[1] .. ':' .. ARGV[3] .. ':clicks', 1)
+redis.call('SADD', KEYS[3], ARGV[2])
+return 1
+`)
+
+// recordBatchScript атомарно прибавляет приращения одного слота. KEYS[1..3] -
+// banners, totals и groups слота, далее по два ключа stats и hourly на приращение.
+// ARGV[1] - лимит показов, далее по пять значений на приращение: banner, group,
+// bucket, shows, clicks. Сначала проверяются все приращения: если баннера нет
+// в ротации, возвращается {-1, banner}, если показы превысят лимит - {0, banner},
+// и ничего не записывается. При успехе возвращается {1}.
+var recordBatchScript = goredis.NewScript(`
+local cap = tonumber(ARGV[1])
+local totals = {}
+for i = 2, #ARGV, 5 do
+  local banner, shows = ARGV[i], tonumber(ARGV[i + 3])
+  if redis.call('SISMEMBER', KEYS[1], banner) == 0 then
+    return {-1, tonumber(banner)}
+  end
+  if cap > 0 and shows > 0 then
+    totals[banner] = (totals[banner] or tonumber(redis.call('HGET', KEYS[2], banner) or '0')) + shows
+    if totals[banner] > cap then
+      return {0, tonumber(banner)}
+    end
+  end
+end
+for i = 2, #ARGV, 5 do
+  local banner, group, bucket = ARGV[i], ARGV[i + 1], ARGV[i + 2]
+  local shows, clicks = tonumber(ARGV[i + 3]), tonumber(ARGV[i + 4])
+  local stats, hourly = KEYS[4 + (i - 2) / 5 * 2], KEYS[5 + (i - 2) / 5 * 2]
+  if shows > 0 then
+    redis.call('HINCRBY', KEYS[2], banner, shows)
+    redis.call('HINCRBY', stats, banner .. ':shows', shows)
+    redis.call('HINCRBY', hourly, banner .. ':' .. bucket .. ':shows', shows)
+  end
+  if clicks > 0 then
+    redis.call('HINCRBY', stats, banner .. ':clicks', clicks)
+    redis.call('HINCRBY', hourly, banner .. ':' .. bucket .. ':clicks', clicks)
+  end
+  redis.call('SADD', KEYS[3], group)
+end
+return {1}
+`)
+
+// removeBannerLua объявляет функцию удаления баннера из ротации вместе
+// со статистикой во всех группах и ограничениями показа. KEYS[1..4] - banners,
+// groups, totals и targeting слота, далее по три ключа stats, hourly и daily
+// на группу. Скрипт, начатый с groups_declared, возвращает 0, если в слоте
+// появилась группа, ключи которой не переданы.
+const removeBannerLua = `
+local group_keys = {}
+for i = 5, #KEYS, 3 do
+  group_keys[string.match(KEYS[i], '[^:]+$')] = {KEYS[i], KEYS[i + 1], KEYS[i + 2]}
+end
+local function groups_declared()
+  for _, group in ipairs(redis.call('SMEMBERS', KEYS[2])) do
+    if not group_keys[group] then
+      return false
+    end
+  end
+  return true
+end
+local function remove_banner(banner)
+  redis.call('SREM', KEYS[1], banner)
+  redis.call('HDEL', KEYS[3], banner)
+  redis.call('HDEL', KEYS[4], banner)
+  local prefix = banner .. ':'
+  for _, group in ipairs(redis.call('SMEMBERS', KEYS[2])) do
+    local keys = group_keys[group]
+    redis.call('HDEL', keys[1], banner .. ':shows', banner .. ':clicks')
+    if redis.call('HLEN', keys[1]) == 0 then
+      redis.call('SREM', KEYS[2], group)
+    end
+    for _, key in ipairs({keys[2], keys[3]}) do
+      for _, field in ipairs(redis.call('HKEYS', key)) do
+        if string.sub(field, 1, #prefix) == prefix then
+          redis.call('HDEL', key, field)
+        end
+      end
+    end
+  end
+end
+`
+
+// removeBannerScript удаляет баннер ARGV[1] из ротации
+var removeBannerScript = goredis.NewScript(removeBannerLua + `
+if not groups_declared() then
+  return 0
+end
+remove_banner(ARGV[1])
+return 1
+`)
+
+// updateRotationScript атомарно применяет изменение ротации. ARGV[1] - "1"
+// для замены ротации, ARGV[2] - число добавляемых баннеров, за ними идут
+// добавляемые, а затем удаляемые баннеры.
+var updateRotationScript = goredis.NewScript(removeBannerLua + `
+if not groups_declared() then
+  return 0
+end
+local added = {}
+local count = tonumber(ARGV[2])
+for i = 3, 2 + count do
+  added[ARGV[i]] = true
+end
+if ARGV[1] == '1' then
+  for _, banner in ipairs(redis.call('SMEMBERS', KEYS[1])) do
+    if not added[banner] then
+      remove_banner(banner)
+    end
+  end
+else
+  for i = 3 + count, #ARGV do
+    remove_banner(ARGV[i])
+  end
+end
+for banner in pairs(added) do
+  redis.call('SADD', KEYS[1], banner)
+end
+return 1
+`)
+
+// setTargetingScript сохраняет ограничения показа баннера ARGV[1], если он
+// в ротации; пустой ARGV[2] удаляет ограничения. Возвращает 0, если баннера нет.
+var setTargetingScript = goredis.NewScript(`
+if redis.call('SISMEMBER', KEYS[1], ARGV[1]) == 0 then
+  return 0
+end
+if ARGV[2] == '' then
+  redis.call('HDEL', KEYS[2], ARGV[1])
+else
+  redis.call('HSET', KEYS[2], ARGV[1], ARGV[2])
+end
+return 1
+`)
+
+// rollupScript переносит часовые корзины одной группы, начавшиеся раньше
+// ARGV[1], в дневные. Сутки в unix-времени выровнены по полуночи UTC.
+var rollupScript = goredis.NewScript(`
+local before = tonumber(ARGV[1])
+local fields = redis.call('HGETALL', KEYS[1])
+for i = 1, #fields, 2 do
+  local banner, bucket, counter = string.match(fields[i], '^(%d+):(%d+):(%a+)$')
+  if bucket and tonumber(bucket) < before then
+    local day = tonumber(bucket) - tonumber(bucket) % 86400
+    redis.call('HINCRBY', KEYS[2], banner .. ':' .. day .. ':' .. counter, tonumber(fields[i + 1]))
+    redis.call('HDEL', KEYS[1], fields[i])
+  end
+end
+return 1
+`)
+
+// RedisStorage - хранилище на Redis для слотов с высокой нагрузкой
+type RedisStorage struct {
+	client  goredis.UniversalClient
+	showCap int
+}
+
+var (
+	_ storage.Storage       = (*RedisStorage)(nil)
+	_ storage.BatchRecorder = (*RedisStorage)(nil)
+)
+
+// New подключается к Redis по адресу host:port или URL redis://.
+// showCap ограничивает число показов баннера в слоте, 0 - без ограничения.
+func New(addr string, showCap int) (*RedisStorage, error) {
+	opts := &goredis.Options{Addr: addr}
+	if strings.Contains(addr, "://") {
+		var err error
+		opts, err = goredis.ParseURL(addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse redis url: %w", err)
+		}
+	}
+
+	return NewWithClient(goredis.NewClient(opts), showCap), nil
+}
+
+// NewWithClient создает хранилище поверх готового клиента
+func NewWithClient(client goredis.UniversalClient, showCap int) *RedisStorage {
+	return &RedisStorage{client: client, showCap: showCap}
+}
+
+func (s *RedisStorage) AddBannerToSlot(ctx context.Context, slotID, bannerID int) error {
+	return s.client.SAdd(ctx, bannersKey(slotID), bannerID).Err()
+}
+
+func (s *RedisStorage) RemoveBannerFromSlot(ctx context.Context, slotID, bannerID int) error {
+	return s.runRotationScript(ctx, removeBannerScript, slotID, bannerID)
+}
+
+// UpdateSlotRotation применяет изменение одним Lua-скриптом. Как и
+// AddBannerToSlot, не проверяет, что баннеры заведены в справочнике.
+func (s *RedisStorage) UpdateSlotRotation(ctx context.Context, slotID int, change storage.RotationChange) error {
+	replace := "0"
+	if change.Replace {
+		replace = "1"
+	}
+
+	args := make([]any, 0, 2+len(change.Add)+len(change.Remove))
+	args = append(args, replace, len(change.Add))
+	for _, id := range change.Add {
+		args = append(args, id)
+	}
+	if !change.Replace {
+		for _, id := range change.Remove {
+			args = append(args, id)
+		}
+	}
+
+	return s.runRotationScript(ctx, updateRotationScript, slotID, args...)
+}
+
+// maxRotationAttempts - сколько раз runRotationScript перечитывает группы слота
+const maxRotationAttempts = 5
+
+// runRotationScript запускает скрипт на основе removeBannerLua, передавая
+// ключи статистики всех групп слота через KEYS, как требует Redis Cluster.
+// Если между чтением групп и запуском скрипта появилась новая группа,
+// скрипт ничего не меняет, и группы перечитываются.
+func (s *RedisStorage) runRotationScript(ctx context.Context, script *goredis.Script, slotID int, args ...any) error {
+	for range maxRotationAttempts {
+		groups, err := s.client.SMembers(ctx, groupsKey(slotID)).Result()
+		if err != nil {
+			return err
+		}
+
+		keys := make([]string, 0, 4+3*len(groups))
+		keys = append(keys, bannersKey(slotID), groupsKey(slotID), totalsKey(slotID), targetingKey(slotID))
+		for _, group := range groups {
+			keys = append(keys, statsPrefix(slotID)+group, hourlyPrefix(slotID)+group, dailyPrefix(slotID)+group)
+		}
+
+		done, err := script.Run(ctx, s.client, keys, args...).Int()
+		if err != nil {
+			return err
+		}
+		if done == 1 {
+			return nil
+		}
+	}
+	return fmt.Errorf("groups of slot %d keep changing: %w", slotID, storage.ErrConflict)
+}
+
+func (s *RedisStorage) SetBannerTargeting(ctx context.Context, slotID, bannerID int, targeting storage.Targeting) error {
+	var value string
+	if !targeting.IsZero() {
+		data, err := json.Marshal(targeting)
+		if err != nil {
+			return err
+		}
+		value = string(data)
+	}
+
+	res, err := setTargetingScript.Run(ctx, s.client,
+		[]string{bannersKey(slotID), targetingKey(slotID)},
+		bannerID, value,
+	).Int()
+	if err != nil {
+		return err
+	}
+	if res == 0 {
+		return fmt.Errorf("banner %d is not in slot %d: %w", bannerID, slotID, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *RedisStorage) GetSlotTargeting(ctx context.Context, slotID int) (map[int]storage.Targeting, error) {
+	fields, err := s.client.HGetAll(ctx, targetingKey(slotID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	targeting := make(map[int]storage.Targeting, len(fields))
+	for field, value := range fields {
+		bannerID, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid banner id in targeting: %q", field)
+		}
+		var t storage.Targeting
+		if err := json.Unmarshal([]byte(value), &t); err != nil {
+			return nil, fmt.Errorf("failed to decode targeting of banner %d: %w", bannerID, err)
+		}
+		targeting[bannerID] = t
+	}
+	return targeting, nil
+}
+
+func (s *RedisStorage) RecordShow(ctx context.Context, slotID, bannerID, groupID int) error {
+	res, err := recordShowScript.Run(ctx, s.client,
+		[]string{
+			bannersKey(slotID), statsKey(slotID, groupID), totalsKey(slotID), groupsKey(slotID),
+			hourlyPrefix(slotID) + strconv.Itoa(groupID),
+		},
+		bannerID, groupID, s.showCap, storage.HourBucket(time.Now()).Unix(),
+	).Int()
+	if err != nil {
+		return err
+	}
+
+	switch res {
+	case -1:
+		return fmt.Errorf("banner %d is not in slot %d: %w", bannerID, slotID, storage.ErrNotFound)
+	case 0:
+		return fmt.Errorf("%w: banner %d slot %d", storage.ErrShowCapReached, bannerID, slotID)
+	}
+	return nil
+}
+
+func (s *RedisStorage) RecordClick(ctx context.Context, slotID, bannerID, groupID int) error {
+	res, err := recordClickScript.Run(ctx, s.client,
+		[]string{
+			bannersKey(slotID), statsKey(slotID, groupID), groupsKey(slotID),
+			hourlyPrefix(slotID) + strconv.Itoa(groupID),
+		},
+		bannerID, groupID, storage.HourBucket(time.Now()).Unix(),
+	).Int()
+	if err != nil {
+		return err
+	}
+
+	if res == -1 {
+		return fmt.Errorf("banner %d is not in slot %d: %w", bannerID, slotID, storage.ErrNotFound)
+	}
+	return nil
+}
+
+// RecordBatch записывает приращения, каждое в свою часовую корзину d.Hour.
+// Приращения одного слота проверяются и применяются атомарно скриптом
+// recordBatchScript: если баннера нет в ротации или показы превысят лимит,
+// слот не записывается и возвращается storage.ErrNotFound или
+// storage.ErrShowCapReached. Ключи разных слотов в Redis Cluster лежат
+// в разных hash slot, поэтому слоты пишутся по очереди до первой ошибки;
+// если к этому моменту часть слотов записана, ошибка оборачивается
+// в *storage.PartialBatchError.
+func (s *RedisStorage) RecordBatch(ctx context.Context, deltas []storage.StatDelta) error {
+	now := time.Now()
+	bySlot := make(map[int][]int)
+	var slots []int
+	for i, d := range deltas {
+		if _, ok := bySlot[d.SlotID]; !ok {
+			slots = append(slots, d.SlotID)
+		}
+		bySlot[d.SlotID] = append(bySlot[d.SlotID], i)
+	}
+
+	var applied []int
+	for _, slotID := range slots {
+		keys := []string{bannersKey(slotID), totalsKey(slotID), groupsKey(slotID)}
+		args := []any{s.showCap}
+		for _, i := range bySlot[slotID] {
+			d := deltas[i]
+			hour := d.Hour
+			if hour.IsZero() {
+				hour = now
+			}
+			group := strconv.Itoa(d.GroupID)
+			keys = append(keys, statsPrefix(slotID)+group, hourlyPrefix(slotID)+group)
+			args = append(args, d.BannerID, d.GroupID, storage.HourBucket(hour).Unix(), d.Shows, d.Clicks)
+		}
+
+		err := s.recordSlotBatch(ctx, slotID, keys, args)
+		if err != nil {
+			if len(applied) > 0 {
+				return &storage.PartialBatchError{Applied: applied, Err: err}
+			}
+			return err
+		}
+		applied = append(applied, bySlot[slotID]...)
+	}
+	return nil
+}
+
+// recordSlotBatch запускает recordBatchScript для слота и переводит его ответ в ошибку
+func (s *RedisStorage) recordSlotBatch(ctx context.Context, slotID int, keys []string, args []any) error {
+	res, err := recordBatchScript.Run(ctx, s.client, keys, args...).Int64Slice()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(res) == 2 && res[0] == -1:
+		return fmt.Errorf("banner %d is not in slot %d: %w", res[1], slotID, storage.ErrNotFound)
+	case len(res) == 2 && res[0] == 0:
+		return fmt.Errorf("%w: banner %d slot %d", storage.ErrShowCapReached, res[1], slotID)
+	}
+	return nil
+}
+
+func (s *RedisStorage) GetBannerStats(ctx context.Context, slotID, groupID int) ([]storage.BannerStat, error) {
+	fields, err := s.client.HGetAll(ctx, statsKey(slotID, groupID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	byBanner := make(map[int]*storage.BannerStat)
+	for field, value := range fields {
+		idPart, counter, ok := strings.Cut(field, ":")
+		if !ok {
+			continue
+		}
+		bannerID, err := strconv.Atoi(idPart)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stats field %q: %w", field, err)
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stats value %q: %w", value, err)
+		}
+
+		stat, ok := byBanner[bannerID]
+		if !ok {
+			stat = &storage.BannerStat{BannerID: bannerID}
+			byBanner[bannerID] = stat
+		}
+		switch counter {
+		case "shows":
+			stat.Shows = n
+		case "clicks":
+			stat.Clicks = n
+		}
+	}
+
+	stats := make([]storage.BannerStat, 0, len(byBanner))
+	for _, stat := range byBanner {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].BannerID < stats[j].BannerID })
+	return stats, nil
+}
+
+func (s *RedisStorage) GetBannerStatsRange(ctx context.Context, slotID, groupID int, from, to time.Time) ([]storage.BannerStat, error) {
+	fromUnix, toUnix := from.Unix(), to.Unix()
+	byBanner := make(map[int]*storage.BannerStat)
+
+	for _, key := range []string{hourlyPrefix(slotID) + strconv.Itoa(groupID), dailyPrefix(slotID) + strconv.Itoa(groupID)} {
+		fields, err := s.client.HGetAll(ctx, key).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		for field, value := range fields {
+			parts := strings.Split(field, ":")
+			if len(parts) != 3 {
+				continue
+			}
+			bannerID, err := strconv.Atoi(parts[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid stats field %q: %w", field, err)
+			}
+			bucket, err := strconv.ParseInt(parts[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid stats field %q: %w", field, err)
+			}
+			if bucket < fromUnix || bucket >= toUnix {
+				continue
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid stats value %q: %w", value, err)
+			}
+
+			stat, ok := byBanner[bannerID]
+			if !ok {
+				stat = &storage.BannerStat{BannerID: bannerID}
+				byBanner[bannerID] = stat
+			}
+			switch parts[2] {
+			case "shows":
+				stat.Shows += n
+			case "clicks":
+				stat.Clicks += n
+			}
+		}
+	}
+
+	stats := make([]storage.BannerStat, 0, len(byBanner))
+	for _, stat := range byBanner {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].BannerID < stats[j].BannerID })
+	return stats, nil
+}
+
+// RollupHourlyStats обходит часовые хэши всех слотов через SCAN и
+// атомарно сворачивает каждый скриптом rollupScript
+func (s *RedisStorage) RollupHourlyStats(ctx context.Context, before time.Time) error {
+	iter := s.client.Scan(ctx, 0, "rotation:{*}:hourly:*", 100).Iterator()
+	for iter.Next(ctx) {
+		hourlyKey := iter.Val()
+		dailyKey := strings.Replace(hourlyKey, ":hourly:", ":daily:", 1)
+		if err := rollupScript.Run(ctx, s.client, []string{hourlyKey, dailyKey}, before.Unix()).Err(); err != nil {
+			return fmt.Errorf("failed to roll up %s: %w", hourlyKey, err)
+		}
+	}
+
+	return iter.Err()
+}
+
+func (s *RedisStorage) GetBannersForSlot(ctx context.Context, slotID int) ([]int, error) {
+	members, err := s.client.SMembers(ctx, bannersKey(slotID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query banners for slot: %w", err)
+	}
+
+	bannerIDs := make([]int, 0, len(members))
+	for _, member := range members {
+		bannerID, err := strconv.Atoi(member)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse banner ID: %w", err)
+		}
+		bannerIDs = append(bannerIDs, bannerID)
+	}
+	sort.Ints(bannerIDs)
+	return bannerIDs, nil
+}
+
+// Ping проверяет соединение с Redis
+func (s *RedisStorage) Ping(ctx context.Context) error {
+	if err := s.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("failed to ping redis: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStorage) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,482 @@
+package redis
+
+import (
+	"banner-rotation/internal/storage"
+	"banner-rotation/internal/storage/buffered"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStorage(t *testing.T, showCap int) *RedisStorage {
+	mr := miniredis.RunT(t)
+	store, err := New(mr.Addr(), showCap)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestRedisStorage(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t, 0)
+
+	t.Run("AddBannerToSlot", func(t *testing.T) {
+		require.NoError(t, store.AddBannerToSlot(ctx, 1, 2))
+		require.NoError(t, store.AddBannerToSlot(ctx, 1, 1))
+		require.NoError(t, store.AddBannerToSlot(ctx, 1, 1))
+
+		banners, err := store.GetBannersForSlot(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2}, banners)
+	})
+
+	t.Run("RecordShow and RecordClick", func(t *testing.T) {
+		require.NoError(t, store.RecordShow(ctx, 1, 1, 1))
+		require.NoError(t, store.RecordShow(ctx, 1, 1, 1))
+		require.NoError(t, store.RecordClick(ctx, 1, 1, 1))
+		require.NoError(t, store.RecordShow(ctx, 1, 2, 2))
+
+		stats, err := store.GetBannerStats(ctx, 1, 1)
+		require.NoError(t, err)
+		require.Len(t, stats, 1)
+		assert.Equal(t, 1, stats[0].BannerID)
+		assert.Equal(t, 2, stats[0].Shows)
+		assert.Equal(t, 1, stats[0].Clicks)
+	})
+
+	t.Run("RecordShow for banner not in slot", func(t *testing.T) {
+		assert.Error(t, store.RecordShow(ctx, 1, 3, 1))
+		assert.Error(t, store.RecordClick(ctx, 2, 1, 1))
+	})
+
+	t.Run("RemoveBannerFromSlot drops stats in all groups", func(t *testing.T) {
+		require.NoError(t, store.RecordShow(ctx, 1, 1, 2))
+		require.NoError(t, store.RemoveBannerFromSlot(ctx, 1, 1))
+
+		banners, err := store.GetBannersForSlot(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []int{2}, banners)
+
+		for _, groupID := range []int{1, 2} {
+			stats, err := store.GetBannerStats(ctx, 1, groupID)
+			require.NoError(t, err)
+			for _, stat := range stats {
+				assert.NotEqual(t, 1, stat.BannerID)
+			}
+		}
+	})
+}
+
+func TestRedisStorage_ShowCap(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t, 100)
+	require.NoError(t, store.AddBannerToSlot(ctx, 1, 1))
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		capped int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(groupID int) {
+			defer wg.Done()
+			for j := 0; j < 15; j++ {
+				err := store.RecordShow(ctx, 1, 1, groupID)
+				if err != nil {
+					assert.ErrorIs(t, err, storage.ErrShowCapReached)
+					mu.Lock()
+					capped++
+					mu.Unlock()
+				}
+			}
+		}(i % 3)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 50, capped)
+
+	total := 0
+	for groupID := 0; groupID < 3; groupID++ {
+		stats, err := store.GetBannerStats(ctx, 1, groupID)
+		require.NoError(t, err)
+		for _, stat := range stats {
+			total += stat.Shows
+		}
+	}
+	assert.Equal(t, 100, total)
+}
+
+func TestRedisStorage_TimeBuckets(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t, 0)
+	require.NoError(t, store.AddBannerToSlot(ctx, 1, 1))
+	require.NoError(t, store.AddBannerToSlot(ctx, 1, 2))
+
+	require.NoError(t, store.RecordShow(ctx, 1, 1, 1))
+	require.NoError(t, store.RecordShow(ctx, 1, 1, 1))
+	require.NoError(t, store.RecordClick(ctx, 1, 1, 1))
+	require.NoError(t, store.RecordShow(ctx, 1, 2, 1))
+
+	now := time.Now()
+	from, to := now.Add(-48*time.Hour), now.Add(48*time.Hour)
+
+	stats, err := store.GetBannerStatsRange(ctx, 1, 1, now.Add(-time.Hour), to)
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.Equal(t, 2, stats[0].Shows)
+	assert.Equal(t, 1, stats[0].Clicks)
+
+	require.NoError(t, store.RollupHourlyStats(ctx, to))
+
+	stats, err = store.GetBannerStatsRange(ctx, 1, 1, from, to)
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.Equal(t, 2, stats[0].Shows)
+	assert.Equal(t, 1, stats[0].Clicks)
+
+	require.NoError(t, store.RemoveBannerFromSlot(ctx, 1, 1))
+
+	stats, err = store.GetBannerStatsRange(ctx, 1, 1, from, to)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 2, stats[0].BannerID)
+}
+
+func TestRedisStorage_RecordBatch(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t, 10)
+	require.NoError(t, store.AddBannerToSlot(ctx, 1, 1))
+	require.NoError(t, store.AddBannerToSlot(ctx, 2, 1))
+
+	// Приращение прошлого часа попадает в свою корзину, а не в текущую
+	lastHour := storage.HourBucket(time.Now()).Add(-time.Hour)
+	require.NoError(t, store.RecordBatch(ctx, []storage.StatDelta{
+		{SlotID: 1, BannerID: 1, GroupID: 1, Hour: lastHour, Shows: 3, Clicks: 1},
+		{SlotID: 2, BannerID: 1, GroupID: 1, Hour: lastHour, Shows: 8},
+	}))
+
+	stats, err := store.GetBannerStatsRange(ctx, 1, 1, lastHour, lastHour.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 3, stats[0].Shows)
+	assert.Equal(t, 1, stats[0].Clicks)
+
+	stats, err = store.GetBannerStatsRange(ctx, 1, 1, lastHour.Add(time.Hour), lastHour.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, stats)
+
+	// Слот, превышающий лимит показов, не применяется целиком, а уже
+	// записанные слоты перечисляются в ошибке
+	err = store.RecordBatch(ctx, []storage.StatDelta{
+		{SlotID: 1, BannerID: 1, GroupID: 1, Hour: lastHour, Shows: 1},
+		{SlotID: 2, BannerID: 1, GroupID: 1, Hour: lastHour, Shows: 2},
+		{SlotID: 2, BannerID: 1, GroupID: 2, Hour: lastHour, Shows: 1},
+	})
+	require.ErrorIs(t, err, storage.ErrShowCapReached)
+	var partial *storage.PartialBatchError
+	require.ErrorAs(t, err, &partial)
+	assert.Equal(t, []int{0}, partial.Applied)
+
+	stats, err = store.GetBannerStats(ctx, 2, 1)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 8, stats[0].Shows)
+
+	stats, err = store.GetBannerStats(ctx, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 4, stats[0].Shows)
+
+	// Пачка с баннером вне слота не применяется целиком
+	err = store.RecordBatch(ctx, []storage.StatDelta{
+		{SlotID: 1, BannerID: 1, GroupID: 1, Hour: lastHour, Shows: 1},
+		{SlotID: 1, BannerID: 2, GroupID: 1, Hour: lastHour, Shows: 1},
+	})
+	require.ErrorIs(t, err, storage.ErrNotFound)
+	assert.NotErrorAs(t, err, &partial, "nothing was written")
+
+	stats, err = store.GetBannerStats(ctx, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 4, stats[0].Shows)
+}
+
+func TestRedisStorage_RecordBatchPartial(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t, 0)
+	require.NoError(t, store.AddBannerToSlot(ctx, 1, 1))
+	require.NoError(t, store.AddBannerToSlot(ctx, 2, 1))
+
+	// Баннер 2 не в ротации слота 2: первый слот записывается,
+	// второй нет, и буфер не повторяет уже записанные приращения
+	buffer := buffered.New(store, time.Hour)
+	t.Cleanup(func() { _ = buffer.Close() })
+	require.NoError(t, buffer.RecordShow(ctx, 1, 1, 1))
+	require.NoError(t, buffer.RecordClick(ctx, 1, 1, 1))
+	require.NoError(t, buffer.RecordShow(ctx, 2, 1, 1))
+	require.NoError(t, buffer.RecordShow(ctx, 2, 2, 1))
+	require.NoError(t, buffer.Flush(ctx))
+
+	stats, err := store.GetBannerStats(ctx, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.BannerStat{{BannerID: 1, Shows: 1, Clicks: 1}}, stats)
+
+	stats, err = store.GetBannerStats(ctx, 2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.BannerStat{{BannerID: 1, Shows: 1}}, stats)
+	assert.Equal(t, 1, buffer.Stats().Dropped)
+}
+
+func TestRedisStorage_Catalog(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t, 0)
+
+	banner, err := store.CreateBanner(ctx, storage.Banner{Description: "first"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, banner.ID)
+
+	_, err = store.CreateBanner(ctx, storage.Banner{ID: 10, Description: "explicit"})
+	require.NoError(t, err)
+	_, err = store.CreateBanner(ctx, storage.Banner{ID: 10, Description: "duplicate"})
+	assert.ErrorIs(t, err, storage.ErrConflict)
+
+	// Автоматический ID не пересекается с явно заданным
+	next, err := store.CreateBanner(ctx, storage.Banner{Description: "next"})
+	require.NoError(t, err)
+	assert.Equal(t, 11, next.ID)
+
+	require.NoError(t, store.UpdateBanner(ctx, storage.Banner{ID: 1, Description: "renamed"}))
+	banner, err = store.GetBanner(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", banner.Description)
+
+	banners, err := store.ListBanners(ctx)
+	require.NoError(t, err)
+	require.Len(t, banners, 3)
+	assert.Equal(t, []int{1, 10, 11}, []int{banners[0].ID, banners[1].ID, banners[2].ID})
+
+	_, err = store.GetBanner(ctx, 100)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	assert.ErrorIs(t, store.UpdateBanner(ctx, storage.Banner{ID: 100}), storage.ErrNotFound)
+	assert.ErrorIs(t, store.DeleteBanner(ctx, 100), storage.ErrNotFound)
+
+	sizes := []storage.Size{{Width: 300, Height: 250}, {Width: 336, Height: 280}}
+	slot, err := store.CreateSlot(ctx, storage.Slot{Description: "slot", Sizes: sizes})
+	require.NoError(t, err)
+	got, err := store.GetSlot(ctx, slot.ID)
+	require.NoError(t, err)
+	assert.Equal(t, sizes, got.Sizes)
+
+	group, err := store.CreateGroup(ctx, storage.Group{Description: "group"})
+	require.NoError(t, err)
+
+	require.NoError(t, store.AddBannerToSlot(ctx, slot.ID, banner.ID))
+	require.NoError(t, store.RecordShow(ctx, slot.ID, banner.ID, group.ID))
+
+	// Сущности, на которые ссылаются ротация и статистика, не удаляются
+	assert.ErrorIs(t, store.DeleteBanner(ctx, banner.ID), storage.ErrConflict)
+	assert.ErrorIs(t, store.DeleteSlot(ctx, slot.ID), storage.ErrConflict)
+	assert.ErrorIs(t, store.DeleteGroup(ctx, group.ID), storage.ErrConflict)
+
+	require.NoError(t, store.RemoveBannerFromSlot(ctx, slot.ID, banner.ID))
+	require.NoError(t, store.DeleteBanner(ctx, banner.ID))
+	require.NoError(t, store.DeleteSlot(ctx, slot.ID))
+	require.NoError(t, store.DeleteGroup(ctx, group.ID))
+
+	_, err = store.GetSlot(ctx, slot.ID)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	groups, err := store.ListGroups(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+}
+
+func TestRedisStorage_Creative(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t, 0)
+
+	creative := storage.Creative{
+		ImageURL: "https://cdn.example.com/728x90.png",
+		ClickURL: "https://example.com/landing",
+		Width:    728,
+		Height:   90,
+		AltText:  "sale",
+	}
+	banner, err := store.CreateBanner(ctx, storage.Banner{Description: "leaderboard", Creative: creative})
+	require.NoError(t, err)
+
+	got, err := store.GetBanner(ctx, banner.ID)
+	require.NoError(t, err)
+	assert.Equal(t, creative, got.Creative)
+
+	// Обновление заменяет креатив целиком
+	update := storage.Creative{HTML: "<b>sale</b>"}
+	require.NoError(t, store.UpdateBanner(ctx, storage.Banner{ID: banner.ID, Description: "html", Creative: update}))
+	banners, err := store.ListBanners(ctx)
+	require.NoError(t, err)
+	require.Len(t, banners, 1)
+	assert.Equal(t, update, banners[0].Creative)
+
+	require.NoError(t, store.DeleteBanner(ctx, banner.ID))
+	_, err = store.CreateBanner(ctx, storage.Banner{ID: banner.ID, Description: "plain"})
+	require.NoError(t, err)
+	got, err = store.GetBanner(ctx, banner.ID)
+	require.NoError(t, err)
+	assert.Zero(t, got.Creative)
+}
+
+func TestRedisStorage_Campaigns(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t, 0)
+
+	_, err := store.CreateCampaign(ctx, storage.Campaign{AdvertiserID: 1, Name: "orphan"})
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	advertiser, err := store.CreateAdvertiser(ctx, storage.Advertiser{Name: "acme"})
+	require.NoError(t, err)
+
+	campaign := storage.Campaign{
+		AdvertiserID: advertiser.ID,
+		Name:         "winter",
+		Status:       storage.CampaignPaused,
+		Budget:       100000,
+		StartAt:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	campaign, err = store.CreateCampaign(ctx, campaign)
+	require.NoError(t, err)
+
+	got, err := store.GetCampaign(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, campaign, got)
+
+	campaign.Status = storage.CampaignActive
+	campaign.EndAt = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, store.UpdateCampaign(ctx, campaign))
+	campaigns, err := store.ListCampaigns(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.Campaign{campaign}, campaigns)
+
+	_, err = store.CreateBanner(ctx, storage.Banner{Description: "orphan", CampaignID: 100})
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	banner, err := store.CreateBanner(ctx, storage.Banner{Description: "snow", CampaignID: campaign.ID})
+	require.NoError(t, err)
+	gotBanner, err := store.GetBanner(ctx, banner.ID)
+	require.NoError(t, err)
+	assert.Equal(t, campaign.ID, gotBanner.CampaignID)
+
+	// Пакетное чтение пропускает отсутствующие ID и повторы
+	banners, err := store.GetBanners(ctx, []int{banner.ID, 100, banner.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []storage.Banner{gotBanner}, banners)
+	campaigns, err = store.GetCampaigns(ctx, []int{100, campaign.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []storage.Campaign{campaign}, campaigns)
+
+	// Кампания с баннерами и рекламодатель с кампаниями не удаляются
+	assert.ErrorIs(t, store.DeleteCampaign(ctx, campaign.ID), storage.ErrConflict)
+	assert.ErrorIs(t, store.DeleteAdvertiser(ctx, advertiser.ID), storage.ErrConflict)
+
+	require.NoError(t, store.UpdateBanner(ctx, storage.Banner{ID: banner.ID, Description: "snow"}))
+	require.NoError(t, store.DeleteCampaign(ctx, campaign.ID))
+	require.NoError(t, store.DeleteAdvertiser(ctx, advertiser.ID))
+
+	_, err = store.GetCampaign(ctx, campaign.ID)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	advertisers, err := store.ListAdvertisers(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, advertisers)
+}
+
+func TestRedisStorage_UpdateSlotRotation(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t, 0)
+
+	require.NoError(t, store.UpdateSlotRotation(ctx, 1, storage.RotationChange{Add: []int{1, 2, 3}}))
+	require.NoError(t, store.RecordShow(ctx, 1, 1, 1))
+	require.NoError(t, store.RecordShow(ctx, 1, 2, 2))
+
+	require.NoError(t, store.UpdateSlotRotation(ctx, 1, storage.RotationChange{Add: []int{4}, Remove: []int{1}}))
+	banners, err := store.GetBannersForSlot(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 3, 4}, banners)
+
+	stats, err := store.GetBannerStats(ctx, 1, 1)
+	require.NoError(t, err)
+	assert.Empty(t, stats)
+
+	require.NoError(t, store.UpdateSlotRotation(ctx, 1, storage.RotationChange{Add: []int{3, 5}, Replace: true}))
+	banners, err = store.GetBannersForSlot(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 5}, banners)
+
+	stats, err = store.GetBannerStats(ctx, 1, 2)
+	require.NoError(t, err)
+	assert.Empty(t, stats)
+
+	// Скрипт без ключей новой группы ничего не меняет
+	require.NoError(t, store.RecordShow(ctx, 1, 3, 3))
+	done, err := removeBannerScript.Run(ctx, store.client,
+		[]string{bannersKey(1), groupsKey(1), totalsKey(1), targetingKey(1)}, 3,
+	).Int()
+	require.NoError(t, err)
+	assert.Zero(t, done)
+	banners, err = store.GetBannersForSlot(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 5}, banners)
+}
+
+func TestRedisStorage_GroupRules(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t, 0)
+
+	rule := &storage.GroupRule{Priority: 1, MinAge: 18, MaxAge: 25, Interests: []string{"sport"}}
+	group, err := store.CreateGroup(ctx, storage.Group{Description: "students", Rule: rule})
+	require.NoError(t, err)
+
+	groups, err := store.ListGroups(ctx)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, rule, groups[0].Rule)
+
+	require.NoError(t, store.UpdateGroup(ctx, storage.Group{ID: group.ID, Description: "everyone"}))
+	got, err := store.GetGroup(ctx, group.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got.Rule)
+}
+
+func TestRedisStorage_Targeting(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t, 0)
+
+	require.NoError(t, store.AddBannerToSlot(ctx, 1, 1))
+	assert.ErrorIs(t, store.SetBannerTargeting(ctx, 1, 2, storage.Targeting{OS: []string{"ios"}}), storage.ErrNotFound)
+
+	targeting := storage.Targeting{Countries: []string{"RU"}, Devices: []string{"mobile"}}
+	require.NoError(t, store.SetBannerTargeting(ctx, 1, 1, targeting))
+	got, err := store.GetSlotTargeting(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]storage.Targeting{1: targeting}, got)
+
+	require.NoError(t, store.RemoveBannerFromSlot(ctx, 1, 1))
+	got, err = store.GetSlotTargeting(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, got, "targeting is removed with the banner")
+}
+
+func TestRedisStorage_Ping(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store, err := New(mr.Addr(), 0)
+	require.NoError(t, err)
+	defer store.Close()
+
+	require.NoError(t, store.Ping(context.Background()))
+
+	mr.Close()
+	assert.Error(t, store.Ping(context.Background()))
+}
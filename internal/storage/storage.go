@@ -2,16 +2,65 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
 )
 
+var (
+	// ErrNotFound - запрошенная сущность не существует
+	ErrNotFound = errors.New("not found")
+	// ErrConflict - операция противоречит текущему состоянию: сущность
+	// с таким ID уже есть или на нее ссылаются другие данные
+	ErrConflict = errors.New("conflict")
+	// ErrInvalid - запрос корректен по формату, но недопустим по смыслу
+	ErrInvalid = errors.New("invalid")
+	// ErrUnavailable - хранилище временно недоступно, запрос можно повторить
+	ErrUnavailable = errors.New("unavailable")
+)
+
+// ErrShowCapReached - баннер исчерпал лимит показов в слоте,
+// следующий показ нужно отдать другому баннеру
+var ErrShowCapReached = fmt.Errorf("show cap reached for banner: %w", ErrConflict)
+
+// IsUnavailable сообщает, что ошибка временная: хранилище помечено
+// недоступным, истек таймаут или не удалось соединиться по сети
+func IsUnavailable(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, ErrUnavailable) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.As(err, &netErr)
+}
+
 // Storage - интерфейс для работы с хранилищем
 type Storage interface {
+	BannerStorage
+	SlotStorage
+	GroupStorage
+	AdvertiserStorage
+	CampaignStorage
+
 	// Добавляет баннер в ротацию слота
 	AddBannerToSlot(ctx context.Context, slotID, bannerID int) error
 
 	// Удаляет баннер из ротации слота
 	RemoveBannerFromSlot(ctx context.Context, slotID, bannerID int) error
 
+	// Применяет пакетное изменение ротации слота целиком или не применяет
+	// ничего. Статистика удаленных баннеров удаляется, как в RemoveBannerFromSlot.
+	UpdateSlotRotation(ctx context.Context, slotID int, change RotationChange) error
+
+	// Задает ограничения показа баннера в слоте, пустые ограничения снимаются.
+	// Возвращает ErrNotFound, если баннера нет в ротации слота.
+	SetBannerTargeting(ctx context.Context, slotID, bannerID int, targeting Targeting) error
+
+	// Возвращает ограничения показа баннеров слота: bannerID -> ограничения.
+	// Баннеры без ограничений не возвращаются.
+	GetSlotTargeting(ctx context.Context, slotID int) (map[int]Targeting, error)
+
 	// Регистрирует показ баннера
 	RecordShow(ctx context.Context, slotID, bannerID, groupID int) error
 
@@ -21,15 +70,345 @@ type Storage interface {
 	// Возвращает статистику для баннеров в слоте и группе
 	GetBannerStats(ctx context.Context, slotID, groupID int) ([]BannerStat, error)
 
+	// Возвращает статистику для баннеров в слоте и группе за период [from, to).
+	// Учитываются часовые корзины, начинающиеся в периоде, и дневные корзины
+	// (после свертки), день которых начинается в периоде.
+	GetBannerStatsRange(ctx context.Context, slotID, groupID int, from, to time.Time) ([]BannerStat, error)
+
+	// Сворачивает часовые корзины статистики, начавшиеся раньше before, в дневные
+	RollupHourlyStats(ctx context.Context, before time.Time) error
+
 	// Получить все баннеры в слоте
 	GetBannersForSlot(ctx context.Context, slotID int) ([]int, error)
 
 	Close() error
 }
 
+// Banner - баннер. CampaignID = 0 - баннер не входит в кампанию
+type Banner struct {
+	ID          int
+	Description string
+	CampaignID  int
+	Creative
+}
+
+// Creative - содержимое баннера для отрисовки на странице
+type Creative struct {
+	ImageURL string
+	HTML     string
+	ClickURL string
+	Width    int
+	Height   int
+	AltText  string
+}
+
+// Size возвращает размер креатива; у креатива без размеров он нулевой
+func (c Creative) Size() Size {
+	return Size{Width: c.Width, Height: c.Height}
+}
+
+// Slot - место на сайте, в котором показываются баннеры.
+// Sizes - допустимые размеры креативов; пустой список - любой размер.
+type Slot struct {
+	ID          int
+	Description string
+	Sizes       []Size
+}
+
+// Size - размер креатива в пикселях
+type Size struct {
+	Width  int
+	Height int
+}
+
+func (s Size) String() string {
+	return fmt.Sprintf("%dx%d", s.Width, s.Height)
+}
+
+// Accepts проверяет, подходит ли креатив указанного размера слоту
+func (s Slot) Accepts(size Size) bool {
+	if len(s.Sizes) == 0 {
+		return true
+	}
+	return slices.Contains(s.Sizes, size)
+}
+
+// Group - социально-демографическая группа пользователей.
+// Rule = nil - группа не подбирается по атрибутам пользователя.
+type Group struct {
+	ID          int
+	Description string
+	Rule        *GroupRule
+}
+
+// GroupRule - правило попадания пользователя в группу. Пустое условие
+// не ограничивает, правило без условий подходит любому пользователю.
+// Из нескольких подходящих правил выбирается правило с большим Priority.
+type GroupRule struct {
+	Priority int
+	// MinAge и MaxAge - границы возраста включительно, 0 - без границы
+	MinAge int
+	MaxAge int
+	// Genders - допустимые значения пола
+	Genders []string
+	// Interests - пользователь должен иметь хотя бы один из интересов
+	Interests []string
+}
+
+// UserAttributes - атрибуты пользователя для подбора группы.
+// Age = 0 - возраст неизвестен.
+type UserAttributes struct {
+	Age       int
+	Gender    string
+	Interests []string
+}
+
+// Matches проверяет, подходит ли пользователь под правило. Пользователь
+// с неизвестным возрастом не подходит под правило с границами возраста.
+func (r GroupRule) Matches(user UserAttributes) bool {
+	if (r.MinAge > 0 || r.MaxAge > 0) && user.Age == 0 {
+		return false
+	}
+	if r.MinAge > 0 && user.Age < r.MinAge {
+		return false
+	}
+	if r.MaxAge > 0 && user.Age > r.MaxAge {
+		return false
+	}
+	if len(r.Genders) > 0 && !slices.Contains(r.Genders, user.Gender) {
+		return false
+	}
+	if len(r.Interests) > 0 && !slices.ContainsFunc(r.Interests, func(interest string) bool {
+		return slices.Contains(user.Interests, interest)
+	}) {
+		return false
+	}
+	return true
+}
+
+// Advertiser - рекламодатель, владелец кампаний
+type Advertiser struct {
+	ID   int
+	Name string
+}
+
+// CampaignStatus - состояние рекламной кампании
+type CampaignStatus string
+
+const (
+	CampaignActive  CampaignStatus = "active"
+	CampaignPaused  CampaignStatus = "paused"
+	CampaignStopped CampaignStatus = "stopped"
+)
+
+// Campaign - рекламная кампания, объединяющая баннеры рекламодателя.
+// Budget задается в минимальных единицах валюты и пока не расходуется
+// автоматически. StartAt и EndAt - период показа [StartAt, EndAt),
+// нулевое значение - без ограничения с этой стороны.
+type Campaign struct {
+	ID           int
+	AdvertiserID int
+	Name         string
+	Status       CampaignStatus
+	Budget       int64
+	StartAt      time.Time
+	EndAt        time.Time
+}
+
+// Running проверяет, показываются ли баннеры кампании в момент now
+func (c Campaign) Running(now time.Time) bool {
+	return c.Status == CampaignActive &&
+		(c.StartAt.IsZero() || !now.Before(c.StartAt)) &&
+		(c.EndAt.IsZero() || now.Before(c.EndAt))
+}
+
+// BannerStorage - справочник баннеров. Create с нулевым ID выдает новый
+// идентификатор, с ненулевым - использует его или возвращает ErrConflict.
+// Create и Update возвращают ErrNotFound, если кампания баннера не существует.
+// Delete возвращает ErrConflict, если баннер находится в ротации.
+type BannerStorage interface {
+	CreateBanner(ctx context.Context, banner Banner) (Banner, error)
+	GetBanner(ctx context.Context, id int) (Banner, error)
+	// GetBanners возвращает баннеры с перечисленными ID по порядку ID,
+	// отсутствующие в справочнике пропускаются
+	GetBanners(ctx context.Context, ids []int) ([]Banner, error)
+	ListBanners(ctx context.Context) ([]Banner, error)
+	UpdateBanner(ctx context.Context, banner Banner) error
+	DeleteBanner(ctx context.Context, id int) error
+}
+
+// SlotStorage - справочник слотов. Delete возвращает ErrConflict,
+// если в ротации слота есть баннеры.
+type SlotStorage interface {
+	CreateSlot(ctx context.Context, slot Slot) (Slot, error)
+	GetSlot(ctx context.Context, id int) (Slot, error)
+	ListSlots(ctx context.Context) ([]Slot, error)
+	UpdateSlot(ctx context.Context, slot Slot) error
+	DeleteSlot(ctx context.Context, id int) error
+}
+
+// GroupStorage - справочник групп. Delete возвращает ErrConflict,
+// если по группе накоплена статистика.
+type GroupStorage interface {
+	CreateGroup(ctx context.Context, group Group) (Group, error)
+	GetGroup(ctx context.Context, id int) (Group, error)
+	ListGroups(ctx context.Context) ([]Group, error)
+	UpdateGroup(ctx context.Context, group Group) error
+	DeleteGroup(ctx context.Context, id int) error
+}
+
+// AdvertiserStorage - справочник рекламодателей. Delete возвращает
+// ErrConflict, если у рекламодателя есть кампании.
+type AdvertiserStorage interface {
+	CreateAdvertiser(ctx context.Context, advertiser Advertiser) (Advertiser, error)
+	GetAdvertiser(ctx context.Context, id int) (Advertiser, error)
+	ListAdvertisers(ctx context.Context) ([]Advertiser, error)
+	UpdateAdvertiser(ctx context.Context, advertiser Advertiser) error
+	DeleteAdvertiser(ctx context.Context, id int) error
+}
+
+// CampaignStorage - справочник кампаний. Create и Update возвращают
+// ErrNotFound, если рекламодатель не существует; Delete возвращает
+// ErrConflict, если в кампании есть баннеры.
+type CampaignStorage interface {
+	CreateCampaign(ctx context.Context, campaign Campaign) (Campaign, error)
+	GetCampaign(ctx context.Context, id int) (Campaign, error)
+	// GetCampaigns возвращает кампании с перечисленными ID по порядку ID,
+	// отсутствующие в справочнике пропускаются
+	GetCampaigns(ctx context.Context, ids []int) ([]Campaign, error)
+	ListCampaigns(ctx context.Context) ([]Campaign, error)
+	UpdateCampaign(ctx context.Context, campaign Campaign) error
+	DeleteCampaign(ctx context.Context, id int) error
+}
+
+// RotationChange - пакетное изменение ротации слота. Без Replace баннеры
+// из Add добавляются, из Remove - удаляются; с Replace ротация заменяется
+// баннерами из Add, а Remove не используется.
+type RotationChange struct {
+	Add     []int
+	Remove  []int
+	Replace bool
+}
+
+// Removes возвращает баннеры из current, которые изменение убирает из ротации
+func (c RotationChange) Removes(current []int) []int {
+	if !c.Replace {
+		return c.Remove
+	}
+	var removed []int
+	for _, id := range current {
+		if !slices.Contains(c.Add, id) {
+			removed = append(removed, id)
+		}
+	}
+	return removed
+}
+
+// Targeting - ограничения показа баннера в слоте. Пустой список не
+// ограничивает; значения сравниваются без учета регистра.
+type Targeting struct {
+	// Countries - коды стран ISO 3166-1 alpha-2
+	Countries []string
+	// Devices - типы устройств: desktop, mobile, tablet
+	Devices []string
+	OS      []string
+}
+
+// IsZero проверяет, что ограничений нет
+func (t Targeting) IsZero() bool {
+	return len(t.Countries) == 0 && len(t.Devices) == 0 && len(t.OS) == 0
+}
+
+// Visitor - контекст запроса показа. Пустое значение - неизвестно.
+type Visitor struct {
+	Country string
+	Device  string
+	OS      string
+}
+
+// Allows проверяет, можно ли показать баннер посетителю. Если значение
+// ограничено, а у посетителя оно неизвестно, баннер не показывается.
+func (t Targeting) Allows(visitor Visitor) bool {
+	return allows(t.Countries, visitor.Country) &&
+		allows(t.Devices, visitor.Device) &&
+		allows(t.OS, visitor.OS)
+}
+
+func allows(allowed []string, value string) bool {
+	return len(allowed) == 0 || slices.ContainsFunc(allowed, func(v string) bool {
+		return strings.EqualFold(v, value)
+	})
+}
+
 // BannerStat - статистика баннера
 type BannerStat struct {
 	BannerID int
 	Shows    int
 	Clicks   int
 }
+
+// StatDelta - приращение счетчиков для комбинации слот+баннер+группа
+type StatDelta struct {
+	SlotID   int
+	BannerID int
+	GroupID  int
+	// Hour - начало часовой корзины, в которую попадает приращение
+	Hour   time.Time
+	Shows  int
+	Clicks int
+}
+
+// HourBucket возвращает начало часовой корзины (UTC) для момента t
+func HourBucket(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Hour)
+}
+
+// DayBucket возвращает начало дневной корзины (UTC) для момента t
+func DayBucket(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// BatchRecorder - опциональный интерфейс хранилища для пакетной
+// записи счетчиков: все приращения применяются целиком или не применяются.
+// Хранилище, которое не может записать пачку атомарно, при ошибке возвращает
+// *PartialBatchError со списком уже записанных приращений.
+type BatchRecorder interface {
+	RecordBatch(ctx context.Context, deltas []StatDelta) error
+}
+
+// PartialBatchError - пачка записана частично: приращения с номерами
+// Applied записаны, остальные нет
+type PartialBatchError struct {
+	Applied []int
+	Err     error
+}
+
+func (e *PartialBatchError) Error() string {
+	return fmt.Sprintf("batch applied partially, %d deltas written: %v", len(e.Applied), e.Err)
+}
+
+func (e *PartialBatchError) Unwrap() error {
+	return e.Err
+}
+
+// AppliedDeltas возвращает номера приращений пачки, записанных
+// несмотря на ошибку RecordBatch
+func AppliedDeltas(err error) map[int]bool {
+	var partial *PartialBatchError
+	if !errors.As(err, &partial) {
+		return nil
+	}
+
+	applied := make(map[int]bool, len(partial.Applied))
+	for _, i := range partial.Applied {
+		applied[i] = true
+	}
+	return applied
+}
+
+// Pinger - опциональный интерфейс хранилища для проверки готовности:
+// Ping возвращает ошибку, если хранилище сейчас недоступно
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
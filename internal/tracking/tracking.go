@@ -0,0 +1,128 @@
+// Package tracking подписывает ссылки на клики и пиксели показов баннеров,
+// чтобы их можно было вставлять в письма и статический HTML без JavaScript.
+package tracking
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultClickTTL - срок действия ссылки перехода по умолчанию
+	DefaultClickTTL = 7 * 24 * time.Hour
+	// DefaultImpressionTTL - сколько по умолчанию ждать подтверждения показа
+	DefaultImpressionTTL = time.Hour
+)
+
+// signatureSize - длина подписи токена в байтах, усеченный HMAC-SHA256
+const signatureSize = 16
+
+// nonceSize - длина случайной части токена в байтах
+const nonceSize = 6
+
+var (
+	// ErrInvalidToken - токен поврежден, подписан другим ключом или другого вида
+	ErrInvalidToken = errors.New("invalid tracking token")
+	// ErrTokenExpired - подпись верна, но срок действия токена истек
+	ErrTokenExpired = errors.New("tracking token expired")
+)
+
+// Kind - вид токена; токен одного вида не принимается вместо другого
+type Kind string
+
+const (
+	KindClick      Kind = "c"
+	KindImpression Kind = "i"
+)
+
+// Claims - показ баннера, к которому относится токен. Nonce отличает
+// токены одного показа, выпущенные в одну секунду.
+type Claims struct {
+	Kind      Kind
+	SlotID    int
+	BannerID  int
+	GroupID   int
+	ExpiresAt time.Time
+	Nonce     string
+}
+
+// Signer выпускает и проверяет токены, подписанные общим секретом.
+// Все экземпляры сервиса должны использовать один секрет.
+type Signer struct {
+	secret []byte
+	now    func() time.Time
+}
+
+func NewSigner(secret []byte) *Signer {
+	return &Signer{secret: secret, now: time.Now}
+}
+
+// Sign выпускает токен вида kind со сроком действия ttl для показа баннера
+// bannerID в слоте slotID группе groupID
+func (s *Signer) Sign(kind Kind, ttl time.Duration, slotID, bannerID, groupID int) string {
+	nonce := make([]byte, nonceSize)
+	_, _ = rand.Read(nonce)
+
+	payload := fmt.Sprintf("%s.%d.%d.%d.%d.%s", kind, slotID, bannerID, groupID,
+		s.now().Add(ttl).Unix(), base64.RawURLEncoding.EncodeToString(nonce))
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(s.sign(payload))
+}
+
+// Parse проверяет токен вида kind. Если токен удалось разобрать, Claims
+// заполнены даже при ErrInvalidToken и ErrTokenExpired, но доверять им
+// можно только при nil-ошибке.
+func (s *Signer) Parse(kind Kind, token string) (Claims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return Claims{}, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+
+	fields := strings.Split(string(payload), ".")
+	if len(fields) != 6 {
+		return Claims{}, ErrInvalidToken
+	}
+	values := make([]int64, 4)
+	for i := range values {
+		if values[i], err = strconv.ParseInt(fields[i+1], 10, 64); err != nil {
+			return Claims{}, ErrInvalidToken
+		}
+	}
+	claims := Claims{
+		Kind:      Kind(fields[0]),
+		SlotID:    int(values[0]),
+		BannerID:  int(values[1]),
+		GroupID:   int(values[2]),
+		ExpiresAt: time.Unix(values[3], 0).UTC(),
+		Nonce:     fields[5],
+	}
+
+	if claims.Kind != kind || !hmac.Equal(mac, s.sign(string(payload))) {
+		return claims, ErrInvalidToken
+	}
+	if !s.now().Before(claims.ExpiresAt) {
+		return claims, ErrTokenExpired
+	}
+	return claims, nil
+}
+
+func (s *Signer) sign(payload string) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)[:signatureSize]
+}
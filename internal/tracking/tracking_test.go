@@ -0,0 +1,67 @@
+package tracking
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	signer := NewSigner([]byte("secret"))
+	signer.now = func() time.Time { return now }
+
+	token := signer.Sign(KindClick, time.Hour, 1, 100, 2)
+
+	t.Run("valid", func(t *testing.T) {
+		claims, err := signer.Parse(KindClick, token)
+		require.NoError(t, err)
+		assert.NotEmpty(t, claims.Nonce)
+		claims.Nonce = ""
+		assert.Equal(t, Claims{Kind: KindClick, SlotID: 1, BannerID: 100, GroupID: 2, ExpiresAt: now.Add(time.Hour)}, claims)
+	})
+
+	t.Run("tokens of one show differ", func(t *testing.T) {
+		assert.NotEqual(t, token, signer.Sign(KindClick, time.Hour, 1, 100, 2))
+	})
+
+	t.Run("other kind", func(t *testing.T) {
+		_, err := signer.Parse(KindImpression, token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("other secret", func(t *testing.T) {
+		other := NewSigner([]byte("other"))
+		other.now = signer.now
+
+		claims, err := other.Parse(KindClick, token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+		assert.Equal(t, 100, claims.BannerID, "claims are decoded for the redirect")
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		forged := NewSigner([]byte("other")).Sign(KindClick, time.Hour, 1, 200, 2)
+		payload, _, _ := strings.Cut(forged, ".")
+		_, signature, _ := strings.Cut(token, ".")
+
+		_, err := signer.Parse(KindClick, payload+"."+signature)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		for _, token := range []string{"", "abc", "abc.def", "!!.!!", "MS4y.AAAA"} {
+			_, err := signer.Parse(KindClick, token)
+			assert.ErrorIs(t, err, ErrInvalidToken, token)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		now = now.Add(time.Hour)
+		claims, err := signer.Parse(KindClick, token)
+		assert.ErrorIs(t, err, ErrTokenExpired)
+		assert.Equal(t, 100, claims.BannerID)
+	})
+}